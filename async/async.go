@@ -28,6 +28,7 @@ import (
 	"errors"
 	"fmt"
 	"path"
+	"reflect"
 	"runtime"
 	"time"
 
@@ -80,12 +81,15 @@ func Execute(fn func(), poolName ...string) {
 // and provides all error stack aiming to facilitate fail causes discovery.
 func ExecuteWithContext(fn Task, c echo.Context, poolName ...string) {
 	functionName := "unknown function"
-	if config.Bean.Sentry.On && config.Bean.Sentry.TracesSampleRate > 0.0 {
+	if (config.Bean.Sentry.On && config.Bean.Sentry.TracesSampleRate > 0.0) || trace.IsOtel() {
 		if pc, file, line, ok := runtime.Caller(1); ok {
 			functionName = fmt.Sprintf("%s:%d\n\t\r %s\n", path.Base(file), line, runtime.FuncForPC(pc).Name())
 		}
 	}
 
+	// Keep the request context to continue the OpenTelemetry trace, the echo context may be reused once the handler returns.
+	parentCtx := c.Request().Context()
+
 	// Acquire a context from echo.
 	ec := c.Echo().AcquireContext()
 
@@ -105,7 +109,7 @@ func ExecuteWithContext(fn Task, c echo.Context, poolName ...string) {
 			hub.Scope().SetRequest(ec.Request())
 			ctx = sentry.SetHubOnContext(ctx, hub)
 
-			if config.Bean.Sentry.TracesSampleRate > 0.0 {
+			if config.Bean.Sentry.TracesSampleRate > 0.0 && !trace.IsOtel() {
 				urlPath := ec.Request().URL.Path
 
 				span := sentry.StartSpan(ctx, "async",
@@ -124,6 +128,12 @@ func ExecuteWithContext(fn Task, c echo.Context, poolName ...string) {
 			}
 		}
 
+		if trace.IsOtel() {
+			var finish func()
+			ctx, finish = startOtelSpan(trace.ContextWithOtelSpan(ctx, parentCtx), functionName)
+			defer finish()
+		}
+
 		// Release the acquired context. This defer will be executed second.
 		defer c.Echo().ReleaseContext(ec)
 
@@ -136,7 +146,7 @@ func ExecuteWithContext(fn Task, c echo.Context, poolName ...string) {
 
 func ExecuteWithTimeout(ctx context.Context, duration time.Duration, fn TaskWithCtx, poolName ...string) {
 	functionName := "unknown function"
-	if config.Bean.Sentry.On && config.Bean.Sentry.TracesSampleRate > 0.0 {
+	if (config.Bean.Sentry.On && config.Bean.Sentry.TracesSampleRate > 0.0) || trace.IsOtel() {
 		if pc, file, line, ok := runtime.Caller(1); ok {
 			functionName = fmt.Sprintf("%s:%d\n\t\r %s\n", path.Base(file), line, runtime.FuncForPC(pc).Name())
 		}
@@ -165,7 +175,7 @@ func ExecuteWithTimeout(ctx context.Context, duration time.Duration, fn TaskWith
 		}
 
 		// can pull the right hub and send the exception message to sentry.
-		if config.Bean.Sentry.On && config.Bean.Sentry.TracesSampleRate > 0.0 {
+		if config.Bean.Sentry.On && config.Bean.Sentry.TracesSampleRate > 0.0 && !trace.IsOtel() {
 			var transactionName string
 			if parentSpan != nil {
 				transactionName = parentSpan.Name
@@ -180,6 +190,12 @@ func ExecuteWithTimeout(ctx context.Context, duration time.Duration, fn TaskWith
 			c = span.Context()
 		}
 
+		if trace.IsOtel() {
+			var finish func()
+			c, finish = startOtelSpan(trace.ContextWithOtelSpan(c, ctx), functionName)
+			defer finish()
+		}
+
 		// This defer will be executed first.
 		defer recoverPanic(c)
 
@@ -231,7 +247,7 @@ func ExecuteContext(ctx context.Context, fn TaskWithCtx, asyncOpts ...AsyncOptio
 	newCtx, cancel := newContext(ctx, config.Bean.Sentry.On, opts.timeout)
 
 	var sentrySamplingOpts []sentry.SpanOption
-	if config.Bean.Sentry.On && config.Bean.Sentry.TracesSampleRate > 0.0 {
+	if config.Bean.Sentry.On && config.Bean.Sentry.TracesSampleRate > 0.0 && !trace.IsOtel() {
 		sentrySamplingOpts = setupSentrySampling(ctx)
 	}
 
@@ -277,6 +293,12 @@ func newContext(current context.Context, sentryOn bool, timeout time.Duration) (
 		new = bctx.SetRequestID(new, reqID)
 	}
 
+	// Continue the OpenTelemetry trace, if any, without sharing the parent's cancellation.
+	new = trace.ContextWithOtelSpan(new, current)
+
+	// Share the debug logs buffer of the request, if any, until the response is done.
+	new = log.WithTailBuffer(new, log.TailBufferFromContext(current))
+
 	// Set the timeout to the context.
 	var cancel context.CancelFunc = func() {} // do nothing
 	if timeout > 0 {
@@ -336,6 +358,10 @@ func extractTracing(ctx context.Context) (sentryTrace, baggage string) {
 
 	span := sentry.SpanFromContext(ctx)
 	if span == nil {
+		// Continue the caller's trace, sent as `sentry-trace` or W3C `traceparent`, if there is no span yet.
+		if req, ok := bctx.GetRequest(ctx); ok {
+			return trace.SentryTraceFromHeader(req.Header), req.Header.Get(sentry.SentryBaggageHeader)
+		}
 		return "", ""
 	}
 
@@ -360,6 +386,10 @@ func newTask(ctx context.Context, fn TaskWithCtx,
 			span := sentry.StartSpan(ctx, "async", sentryOpts...)
 			defer span.Finish()
 			ctx = span.Context()
+		} else if trace.IsOtel() {
+			var finish func()
+			ctx, finish = startOtelSpan(ctx, runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name())
+			defer finish()
 		}
 
 		defer recoverPanic(ctx)
@@ -383,6 +413,16 @@ func newTask(ctx context.Context, fn TaskWithCtx,
 	}
 }
 
+// startOtelSpan starts an `async` span on the OpenTelemetry tracer.
+func startOtelSpan(ctx context.Context, description string) (context.Context, func()) {
+	tracer := trace.CurrentTracer()
+	if !tracer.Enabled() {
+		return ctx, func() {}
+	}
+
+	return tracer.Start(ctx, "async", trace.SpanOptions{Description: description})
+}
+
 type execOptions struct {
 	poolName *string
 }
@@ -35,6 +35,7 @@ import (
 	"os/signal"
 	"slices"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -48,6 +49,7 @@ import (
 	elog "github.com/labstack/gommon/log"
 	pkgerrors "github.com/pkg/errors"
 	"github.com/retail-ai-inc/bean/v2/config"
+	bctx "github.com/retail-ai-inc/bean/v2/context"
 	"github.com/retail-ai-inc/bean/v2/echoview"
 	berror "github.com/retail-ai-inc/bean/v2/error"
 	"github.com/retail-ai-inc/bean/v2/goview"
@@ -61,9 +63,11 @@ import (
 	"github.com/retail-ai-inc/bean/v2/internal/validator"
 	blog "github.com/retail-ai-inc/bean/v2/log"
 	"github.com/retail-ai-inc/bean/v2/store/memory"
+	"github.com/retail-ai-inc/bean/v2/stream"
 	"github.com/retail-ai-inc/bean/v2/trace"
 	"github.com/rs/dnscache"
 	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc"
 	"gorm.io/gorm"
 )
 
@@ -86,9 +90,12 @@ type Bean struct {
 	DBConn            *DBDeps
 	Echo              *echo.Echo
 	BeforeServe       func()
+	GRPC              *grpc.Server // Set by `ServeAt` when `grpc.on` is true, before `BeforeServe` is called.
 	ShutdownSrv       []func() error
 	CleanupDBs        []func() error
 	errorHandlerFuncs []berror.ErrorHandlerFunc
+	health            healthChecks
+	grpc              grpcOptions
 	Validate          *validatorV10.Validate
 	Config            config.Config
 }
@@ -101,6 +108,20 @@ var TenantAlterDbHostParam string
 // Support a DNS cache version of the net/http Transport.
 var NetHttpFastTransporter *http.Transport
 
+// `AccessLogTenantID` and `AccessLogUserID` extract the tenant and user ids written to the access log entry
+// when `accessLog.mode` is `combined`. By default they read the ids set on the request context with
+// `context.SetTenantID` and `context.SetUserID`; replace them to read the ids from somewhere else, like JWT claims.
+var (
+	AccessLogTenantID = func(c echo.Context) string {
+		id, _ := bctx.GetTenantID(c.Request().Context())
+		return id
+	}
+	AccessLogUserID = func(c echo.Context) string {
+		id, _ := bctx.GetUserID(c.Request().Context())
+		return id
+	}
+)
+
 func New() *Bean {
 	if config.Bean == nil {
 		log.Fatal("config is not loaded")
@@ -225,16 +246,42 @@ func NewEcho() (*echo.Echo, func() error) {
 
 	// IMPORTANT: Configure debug log.
 	if config.Bean.DebugLogPath != "" {
-		if file, err := helpers.OpenFile(config.Bean.DebugLogPath); err != nil {
+		if file, err := blog.NewRotatingFile(config.Bean.DebugLogPath, blog.RotateConfigFrom(config.Bean.DebugLogRotation)); err != nil {
 			e.Logger.Fatalf("Unable to open log file: %v Server 🚀  crash landed. Exiting...\n", err)
 		} else {
 			e.Logger.SetOutput(file)
 		}
 	}
+	// DEBUG unless `log.level` is set in `env.json`, applied by `blog.Init`.
 	e.Logger.SetLevel(elog.DEBUG)
 
-	// Initialize `BeanLogger` global variable using `e.Logger`.
-	_ = blog.Init(e.Logger)
+	// Initialize `BeanLogger` global variable using `e.Logger`, with the trace ids of the tracing provider.
+	var extractor blog.TraceExtractor = blog.NewSentryExtractor()
+	if trace.IsOtel() {
+		extractor = blog.NewOtelExtractor()
+	}
+	_ = blog.Init(e.Logger, blog.WithTraceExtractor(extractor))
+
+	// Toggle the log level between DEBUG and `log.level` on SIGUSR1.
+	if config.Bean.Log.SignalToggle {
+		stopSignal := blog.Levels().WatchSignal()
+		closes = append(closes, func() error {
+			stopSignal()
+			return nil
+		})
+	}
+
+	// Throttle the same errors logged by the error handlers and the application, and sent to Sentry below.
+	dedupConfig := berror.DedupConfig{
+		Window: config.Bean.ErrorDedup.Window,
+		Burst:  config.Bean.ErrorDedup.Burst,
+		Frames: config.Bean.ErrorDedup.Frames,
+	}
+	if config.Bean.ErrorDedup.On {
+		berror.LogDedup = berror.NewDedup(dedupConfig, errorsSuppressed("log"))
+		blog.ErrorDedup = berror.LogDedup.Allow
+		closes = append(closes, berror.LogDedup.Close)
+	}
 
 	// Adds a `Server` header to the response.
 	e.Use(middleware.ServerHeader(config.Bean.ProjectName, helpers.CurrVersion()))
@@ -269,6 +316,9 @@ func NewEcho() (*echo.Echo, func() error) {
 		e.Use(ContextTimeout(timeoutDur))
 	}
 
+	// Continue the caller's trace from `sentry-trace` or W3C `traceparent` headers depending on `tracing.propagation`.
+	e.Use(middleware.IncomingTraceContext)
+
 	flushSentry := func() error { return nil }
 	// IMPORTANT: Capturing error and send to sentry if needed.
 	// Sentry `panic` error handler and APM initialization if activated from `env.json`
@@ -281,10 +331,18 @@ func NewEcho() (*echo.Echo, func() error) {
 			if clientOption.TracesSampleRate > 0 {
 				clientOption.EnableTracing = true
 			}
-			if err := sentry.Init(*clientOption); err != nil {
+			options := *clientOption
+			var sentryDedup *berror.Dedup
+			if config.Bean.ErrorDedup.On {
+				sentryDedup = berror.NewDedup(dedupConfig, errorsSuppressed("sentry"))
+				options.BeforeSend = trace.DedupBeforeSend(sentryDedup, options.BeforeSend)
+			}
+			if err := sentry.Init(options); err != nil {
 				e.Logger.Fatal("Sentry initialization failed: ", err, ". Server 🚀  crash landed. Exiting...")
 			}
 			flushSentry = func() error {
+				// Send the summary of the errors suppressed before flushing.
+				_ = sentryDedup.Close()
 				notTimeout := sentry.Flush(config.Bean.Sentry.Timeout)
 				if !notTimeout {
 					return errors.New("sentry flush timeout")
@@ -312,6 +370,30 @@ func NewEcho() (*echo.Echo, func() error) {
 	}
 	closes = append(closes, flushSentry)
 
+	// OpenTelemetry tracing if `tracing.provider` is `otel` in env.json. Spans are exported to an OTLP/HTTP collector.
+	var otelTracing bool
+	if trace.IsOtel() {
+		otelCfg := config.Bean.Tracing.Otel
+		shutdownOtel, err := trace.InitOtel(context.Background(), otelCfg, config.Bean.ProjectName, config.Bean.Environment)
+		if err != nil {
+			e.Logger.Fatal("OpenTelemetry initialization failed: ", err, ". Server 🚀  crash landed. Exiting...")
+		}
+		closes = append(closes, func() error {
+			timeout := otelCfg.Timeout
+			if timeout <= 0 {
+				timeout = 10 * time.Second
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			return shutdownOtel(ctx)
+		})
+
+		if helpers.FloatInRange(otelCfg.TracesSampleRate, 0.0, 1.0) > 0.0 {
+			regex.SetSamplingPathSkipper(otelCfg.SkipTracesEndpoints)
+			otelTracing = true
+		}
+	}
+
 	// IMPORTANT: Request related middleware.
 	// Set the `X-Request-ID` header field if it doesn't exist.
 	e.Use(echomiddleware.RequestIDWithConfig(echomiddleware.RequestIDConfig{
@@ -324,6 +406,18 @@ func NewEcho() (*echo.Echo, func() error) {
 		TargetHeader:     echo.HeaderXRequestID,
 	}))
 
+	// After the request id, so the server spans get it.
+	if otelTracing {
+		e.Use(middleware.OtelTracing)
+	}
+
+	// Keep the debug logs of each request, written only if it fails with a 5xx or is marked.
+	if config.Bean.Log.TailBuffer.On {
+		e.Use(middleware.TailBufferWithConfig(middleware.TailBufferConfig{
+			MaxEntries: config.Bean.Log.TailBuffer.MaxEntries,
+		}))
+	}
+
 	// IMPORTANT: Configure access log and body dumper. (can be turn off)
 	if config.Bean.AccessLog.On {
 		accessLogConfig := middleware.LoggerConfig{
@@ -332,6 +426,29 @@ func NewEcho() (*echo.Echo, func() error) {
 			RequestHeader:  config.Bean.AccessLog.ReqHeaderParam,
 			ResponseHeader: config.Bean.AccessLog.ResHeaderParam,
 			Logger:         blog.Logger(),
+
+			BodyDumpMaxSize:      config.Bean.AccessLog.BodyDumpMaxSize,
+			BodyDumpContentTypes: config.Bean.AccessLog.BodyDumpContentTypes,
+			BodyDumpSampleRate:   1,
+			BodyDumpAlwaysStatus: config.Bean.AccessLog.BodyDumpAlwaysStatus,
+
+			Combined: strings.EqualFold(config.Bean.AccessLog.Mode, "combined"),
+			// Resolved on every request, so the extractors can be replaced after `New`.
+			TenantID: func(c echo.Context) string {
+				if AccessLogTenantID == nil {
+					return ""
+				}
+				return AccessLogTenantID(c)
+			},
+			UserID: func(c echo.Context) string {
+				if AccessLogUserID == nil {
+					return ""
+				}
+				return AccessLogUserID(c)
+			},
+		}
+		if rate := config.Bean.AccessLog.BodyDumpSampleRate; rate != nil {
+			accessLogConfig.BodyDumpSampleRate = *rate
 		}
 
 		accessLogger := middleware.AccessLoggerWithConfig(accessLogConfig)
@@ -363,6 +480,18 @@ func NewEcho() (*echo.Echo, func() error) {
 		e.GET(metricsPath, echoprometheus.NewHandler())
 	}
 
+	// Serve the runtime control of the log levels, only to the requests with the token.
+	if config.Bean.Log.Admin.On {
+		if config.Bean.Log.Admin.Token == "" {
+			e.Logger.Fatal("The log level admin endpoint needs `log.admin.token`. Server 🚀  crash landed. Exiting...")
+		}
+		levelPath := config.Bean.Log.Admin.Path
+		if levelPath == "" {
+			levelPath = blog.DefaultLevelPath
+		}
+		e.Match([]string{http.MethodGet, http.MethodPut, http.MethodDelete}, levelPath, blog.LevelHandler(blog.Levels(), config.Bean.Log.Admin.Token))
+	}
+
 	// Register goroutine pool
 	if len(config.Bean.AsyncPool) > 0 {
 		for _, asyncPool := range config.Bean.AsyncPool {
@@ -402,6 +531,25 @@ func (b *Bean) ServeAt(host, port string) error {
 		Handler: b.Echo,
 	}
 
+	var grpcLis net.Listener
+	if b.Config.GRPC.On {
+		b.GRPC = b.newGRPCServer()
+
+		if b.Config.GRPC.Multiplex {
+			// gRPC needs HTTP/2, which `net/http` only speaks over TLS unless h2c is enabled.
+			s.Handler = b.multiplexGRPC(b.Echo)
+			s.Protocols = new(http.Protocols)
+			s.Protocols.SetHTTP1(true)
+			s.Protocols.SetHTTP2(true)
+			s.Protocols.SetUnencryptedHTTP2(!b.Config.HTTP.SSL.On)
+		} else {
+			grpcLis, err = net.Listen("tcp", b.Config.GRPC.Host+":"+b.Config.GRPC.Port)
+			if err != nil {
+				return pkgerrors.Wrapf(err, "failed to listen for grpc server")
+			}
+		}
+	}
+
 	// IMPORTANT: Keep-alive is default true but I kept this here to let you guys no that there is a settings
 	// for it :)
 	s.SetKeepAlivesEnabled(b.Config.HTTP.KeepAlive)
@@ -411,6 +559,12 @@ func (b *Bean) ServeAt(host, port string) error {
 		b.BeforeServe()
 	}
 
+	// Register the health service after `BeforeServe`, so it reports the services registered there too.
+	var stopHealth func() error
+	if b.GRPC != nil {
+		stopHealth = b.serveGRPCHealth(b.GRPC)
+	}
+
 	// Keep all the route information in route.Routes
 	broute.Init(b.Echo)
 
@@ -418,8 +572,11 @@ func (b *Bean) ServeAt(host, port string) error {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	errCh := make(chan error, 1)
+	errCh := make(chan error, 2)
+	var serveWG sync.WaitGroup
+	serveWG.Add(1)
 	go func() {
+		defer serveWG.Done()
 		var err error
 		if b.Config.HTTP.SSL.On {
 			s.TLSConfig = &tls.Config{
@@ -433,9 +590,31 @@ func (b *Bean) ServeAt(host, port string) error {
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			errCh <- err
 		}
+	}()
+
+	if grpcLis != nil {
+		b.Echo.Logger.Info("Starting gRPC server at " + grpcLis.Addr().String() + "...🚀")
+		serveWG.Add(1)
+		go func() {
+			defer serveWG.Done()
+			// `Serve` returns nil once `Stop` or `GracefulStop` is called.
+			if err := b.GRPC.Serve(grpcLis); err != nil {
+				errCh <- pkgerrors.Wrapf(err, "grpc server")
+			}
+		}()
+	}
+
+	go func() {
+		serveWG.Wait()
 		close(errCh)
 	}()
 
+	// Closers run in the reverse order: the health service goes `NOT_SERVING` first, then
+	// a standalone gRPC server stops before the HTTP server, a multiplexed one after it.
+	if b.GRPC != nil && b.Config.GRPC.Multiplex {
+		b.ShutdownSrv = append(b.ShutdownSrv, b.stopGRPC)
+	}
+
 	b.ShutdownSrv = append(b.ShutdownSrv, func() error {
 		var timeout time.Duration
 		if b.Config.HTTP.ShutdownTimeout > 0 {
@@ -456,6 +635,28 @@ func (b *Bean) ServeAt(host, port string) error {
 		return nil
 	})
 
+	// Close the SSE and WebSocket streams first: `http.Server.Shutdown` would wait for the SSE
+	// handlers until the timeout, and doesn't track the hijacked WebSocket connections at all.
+	b.ShutdownSrv = append(b.ShutdownSrv, func() error {
+		timeout := b.Config.HTTP.ShutdownTimeout
+		if timeout <= 0 {
+			timeout = 30 * time.Second
+		}
+		sdnCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := stream.Shutdown(sdnCtx); err != nil {
+			return pkgerrors.Wrapf(err, "failed to close the open streams")
+		}
+		return nil
+	})
+
+	if b.GRPC != nil {
+		if !b.Config.GRPC.Multiplex {
+			b.ShutdownSrv = append(b.ShutdownSrv, b.stopGRPC)
+		}
+		b.ShutdownSrv = append(b.ShutdownSrv, stopHealth)
+	}
+
 	select {
 	case srvErr := <-errCh:
 		if srvErr != nil {
@@ -506,6 +707,9 @@ func (b *Bean) DefaultHTTPErrorHandler() echo.HTTPErrorHandler {
 			return
 		}
 
+		// E.g. `gorm.ErrRecordNotFound` becomes a 404 `berror.APIError`.
+		err = berror.MapError(err)
+
 		for _, handle := range b.errorHandlerFuncs {
 			handled, err := handle(err, c)
 			if err != nil {
@@ -598,6 +802,8 @@ func (b *Bean) InitDB() error {
 
 	b.CleanupDBs = append(b.CleanupDBs, cleanups...)
 
+	b.addDBHealthChecks(b.DBConn)
+
 	return nil
 }
 
@@ -668,6 +874,22 @@ func (b *Bean) CleanupDB() error {
 	return nil
 }
 
+// errorsSuppressed writes the summary of the same errors a deduplicator suppressed, as a `ERROR_DEDUP`
+// log, and sends it to Sentry too for the Sentry events.
+func errorsSuppressed(target string) func(berror.Suppressed) {
+	return func(s berror.Suppressed) {
+		blog.FromContext(context.Background()).WithLevel("ERROR_DEDUP").Warn(s.String(),
+			"target", target,
+			"fingerprint", s.Fingerprint,
+			"suppressed", s.Count,
+			"since", s.Since,
+		)
+		if target == "sentry" {
+			trace.CaptureSuppressed(s)
+		}
+	}
+}
+
 func closer(closers []func() error) func() error {
 	return func() error {
 		if len(closers) == 0 {
@@ -709,6 +931,10 @@ func ContextTimeout(timeout time.Duration) echo.MiddlewareFunc {
 	}
 
 	return echomiddleware.ContextTimeoutWithConfig(echomiddleware.ContextTimeoutConfig{
+		// Long-lived SSE and WebSocket streams are closed by the client or by `ShutdownAll` instead.
+		Skipper: func(c echo.Context) bool {
+			return stream.IsStreamingRoute(c)
+		},
 		Timeout:      timeout,
 		ErrorHandler: timeoutErrorHandler,
 	})
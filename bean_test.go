@@ -4,6 +4,9 @@ package bean
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"net"
 	"net/http"
@@ -18,9 +21,14 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"github.com/retail-ai-inc/bean/v2/config"
+	berror "github.com/retail-ai-inc/bean/v2/error"
 	"github.com/retail-ai-inc/bean/v2/internal/route"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"gorm.io/gorm"
 )
 
 func TestBean_UseErrorHandlerFuncs(t *testing.T) {
@@ -77,6 +85,21 @@ func TestDefaultHTTPErrorHandler(t *testing.T) {
 	assert.Equal(t, `{"errorCode":"default code","errors":"default catched!"}`+"\n", body)
 }
 
+func TestDefaultHTTPErrorHandler_MapError(t *testing.T) {
+	b := &Bean{}
+	b.Echo = echo.New()
+	b.UseErrorHandlerFuncs(berror.APIErrorHandlerFunc, berror.DefaultErrorHandlerFunc)
+	b.Echo.HTTPErrorHandler = b.DefaultHTTPErrorHandler()
+
+	b.Echo.GET("/users/:id", func(c echo.Context) error {
+		return fmt.Errorf("find user %s: %w", c.Param("id"), gorm.ErrRecordNotFound)
+	})
+
+	code, body := request(http.MethodGet, "/users/42", b.Echo)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.JSONEq(t, `{"errorCode":"100003","errorMsg":"Not Found","errors":null}`, body)
+}
+
 func request(method, path string, e *echo.Echo) (int, string) {
 	req := httptest.NewRequest(method, path, nil)
 	rec := httptest.NewRecorder()
@@ -269,6 +292,69 @@ func TestBean_ServeAt(t *testing.T) {
 	}
 }
 
+func TestBean_ServeGRPC(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping TestBean_ServeGRPC on Windows: process signaling is not supported")
+	}
+	tests := []struct {
+		name      string
+		multiplex bool
+		healthErr error
+		want      healthpb.HealthCheckResponse_ServingStatus
+	}{
+		{name: "own port", want: healthpb.HealthCheckResponse_SERVING},
+		{name: "multiplexed on the http port", multiplex: true, want: healthpb.HealthCheckResponse_SERVING},
+		{name: "failing health check", healthErr: errors.New("db is down"), want: healthpb.HealthCheckResponse_NOT_SERVING},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Bean{
+				Echo:     echo.New(),
+				Config:   config.Config{},
+				Validate: validator.New(),
+			}
+			host := "localhost"
+			port := strconv.Itoa(getFreePort(t))
+			b.Config.GRPC.On = true
+			b.Config.GRPC.Multiplex = tt.multiplex
+			b.Config.GRPC.Host = host
+			b.Config.GRPC.Port = strconv.Itoa(getFreePort(t))
+			b.AddHealthCheck("fake", func(ctx context.Context) error { return tt.healthErr })
+
+			srvErr := make(chan error, 1)
+			go func() {
+				srvErr <- b.ServeAt(host, port)
+				close(srvErr)
+			}()
+
+			target := host + ":" + b.Config.GRPC.Port
+			if tt.multiplex {
+				target = host + ":" + port
+			}
+			conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+			if err != nil {
+				t.Fatalf("failed to create grpc client: %v", err)
+			}
+			defer func() {
+				_ = conn.Close()
+			}()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.WaitForReady(true))
+			if err != nil {
+				t.Fatalf("health check failed: %v", err)
+			}
+			assert.Equal(t, tt.want, resp.Status)
+
+			signalTERM(t)
+			if err := <-srvErr; err != nil {
+				t.Errorf("Bean.ServeAt() error = %v", err)
+			}
+		})
+	}
+}
+
 func getFreePort(t *testing.T) int {
 	t.Helper()
 
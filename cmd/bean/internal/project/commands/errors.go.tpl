@@ -0,0 +1,79 @@
+{{ .Copyright }}
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	// Register the error codes of the API.
+	_ "{{ .PkgPath }}/packages/errcode"
+
+	berror "github.com/retail-ai-inc/bean/v2/error"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// errorsCmd represents the `errors` command.
+	errorsCmd = &cobra.Command{
+		Use:   "errors [command]",
+		Short: "This command requires a sub command parameter.",
+		Long:  "",
+	}
+)
+
+var (
+	// errorsExportCmd represents the `errors export` command.
+	errorsExportCmd = &cobra.Command{
+		Use:   "export",
+		Short: "Export the error code catalog.",
+		Long:  `This command writes all the registered error codes, with their HTTP status, message, severity and documentation, as JSON or Markdown for the clients of the API.`,
+		Args:  cobra.ExactArgs(0),
+		Run:   errorsExport,
+	}
+)
+
+var (
+	errorsFormat string
+	errorsOutput string
+)
+
+func init() {
+	errorsExportCmd.Flags().StringVarP(&errorsFormat, "format", "f", "json", "output format, json or md")
+	errorsExportCmd.Flags().StringVarP(&errorsOutput, "output", "o", "", "output file, stdout if empty")
+	errorsCmd.AddCommand(errorsExportCmd)
+	rootCmd.AddCommand(errorsCmd)
+}
+
+func errorsExport(cmd *cobra.Command, args []string) {
+	var export func(w io.Writer) error
+	switch errorsFormat {
+	case "json":
+		export = berror.ExportCodesJSON
+	case "md", "markdown":
+		export = berror.ExportCodesMarkdown
+	default:
+		fmt.Printf("unknown format %q, json or md\n", errorsFormat)
+		os.Exit(1)
+	}
+
+	out := os.Stdout
+	if errorsOutput != "" {
+		file, err := os.Create(errorsOutput)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		out = file
+	}
+
+	err := export(out)
+	if out != os.Stdout {
+		err = errors.Join(err, out.Close())
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,28 @@
+{{ .Copyright }}
+// Package errcode declares the error codes of the API, so the handlers return `berror.NewAPIErrorFromCode(code, args...)`
+// without mapping them to a status. A code declared twice, here or by bean, panics at startup.
+// Export the catalog with `go run main.go errors export --format md`.
+package errcode
+
+import (
+	"net/http"
+
+	berror "github.com/retail-ai-inc/bean/v2/error"
+)
+
+const (
+	// Example:
+	EXAMPLE_NOT_FOUND berror.ErrorCode = "300001"
+)
+
+func init() {
+	berror.MustRegisterCodes(
+		berror.CodeDef{
+			Code:     EXAMPLE_NOT_FOUND,
+			Status:   http.StatusNotFound,
+			Message:  "example %s not found",
+			Severity: "INFO",
+			Doc:      "No example has the id.",
+		},
+	)
+}
@@ -17,8 +17,10 @@ type Config struct {
 	ProjectName  string
 	Environment  string
 	DebugLogPath string
-	Secret       string
-	AccessLog    struct {
+	// DebugLogRotation rotates the file at `DebugLogPath`.
+	DebugLogRotation LogRotation
+	Secret           string
+	AccessLog        struct {
 		On                bool
 		Async             bool 
 		AsyncQueueSize    int 
@@ -29,6 +31,51 @@ type Config struct {
 		ReqHeaderParam    []string
 		ResHeaderParam    []string
 		SkipEndpoints     []string
+		// Mode is `split` (default), an `ACCESS` entry before the handler and a `DUMP` entry after,
+		// or `combined`, a single `ACCESS` entry once the request is done.
+		Mode string
+		// BodyDumpMaxSize is the number of bytes dumped per body, 64KB if 0.
+		BodyDumpMaxSize int64
+		// BodyDumpContentTypes lists the dumped content types (`application/json`, `text/*`, `+json`).
+		BodyDumpContentTypes []string
+		// BodyDumpSampleRate is the fraction of the responses below `BodyDumpAlwaysStatus` dumped, 1 if nil.
+		BodyDumpSampleRate *float64
+		// BodyDumpAlwaysStatus is the status from which every response is dumped, 400 if 0.
+		BodyDumpAlwaysStatus int
+		// Rotation rotates the file at `Path`.
+		Rotation LogRotation
+		// Sinks replaces the single output of `Path` with several, each with its own filters.
+		Sinks []LogSink
+		// AsyncOverflow is what `Async` does when the queue is full.
+		AsyncOverflow LogOverflow
+		// Encoding is how the entries are written, JSON by default.
+		Encoding LogEncoding
+		// Redact redacts personal data found in the values of the fields.
+		Redact []LogRedactRule
+		// MaskRules mask the values at some paths, where `BodyDumpMaskParam` masks keys anywhere.
+		MaskRules []LogMaskRule
+		// AppMinSeverity drops the application logs of `log.FromContext` and `slog` below it, e.g. `INFO`.
+		AppMinSeverity string
+	}
+	// Log controls the log levels, at start and at runtime.
+	Log struct {
+		// Level is the level of the echo logger: `DEBUG` (default), `INFO`, `WARN`, `ERROR` or `OFF`.
+		Level string
+		// Levels are the lowest severities of the structured entries per level name, e.g. `{"DUMP": "OFF"}`.
+		Levels map[string]string
+		// Admin serves the levels on `Path` (`/admin/log-level` if empty) to the requests with the bearer `Token`.
+		Admin struct {
+			On    bool
+			Path  string
+			Token string
+		}
+		// SignalToggle switches the echo logger between DEBUG and `Level` on SIGUSR1.
+		SignalToggle bool
+		// TailBuffer keeps up to `MaxEntries` debug entries per request (1000 if 0), written only if it fails.
+		TailBuffer struct {
+			On         bool
+			MaxEntries int
+		}
 	}
 	Prometheus struct {
 		On            bool
@@ -41,52 +88,23 @@ type Config struct {
 		BodyLimit       string
 		IsHttpsRedirect bool
 		Timeout         time.Duration
-		ErrorMessage    struct {
-			E404 struct {
-				Json []struct {
-					Key   string
-					Value string
-				}
-				Html struct {
-					File string
-				}
-			}
-			E405 struct {
-				Json []struct {
-					Key   string
-					Value string
-				}
-				Html struct {
-					File string
-				}
+		// ErrorMessage is keyed by the status code, e.g. `429`, or its class, e.g. `5xx`, and `default` for
+		// the others. `Sentry` turns off (or on) sending the errors of the status to sentry.
+		ErrorMessage map[string]struct {
+			Json []struct {
+				Key   string
+				Value string
 			}
-			E500 struct {
-				Json []struct {
-					Key   string
-					Value string
-				}
-				Html struct {
-					File string
-				}
-			}
-			E504 struct {
-				Json []struct {
-					Key   string
-					Value string
-				}
-				Html struct {
-					File string
-				}
-			}
-			Default struct {
-				Json []struct {
-					Key   string
-					Value string
-				}
-				Html struct {
-					File string
-				}
+			Html struct {
+				File string
 			}
+			Sentry *bool
+		}
+		// ProblemDetails writes the JSON error responses as RFC 7807 `application/problem+json`, their
+		// `type` being `TypeBaseURL` followed by the error code if it is set.
+		ProblemDetails struct {
+			On          bool
+			TypeBaseURL string
 		}
 		KeepAlive     bool
 		AllowedMethod []string
@@ -98,6 +116,17 @@ type Config struct {
 		}
 		ShutdownTimeout time.Duration
 	}
+	GRPC struct {
+		On                  bool
+		Host                string
+		Port                string
+		Multiplex           bool // Serve gRPC on the HTTP port instead of `Port`.
+		Timeout             time.Duration
+		MaxRecvMsgSize      int
+		MaxSendMsgSize      int
+		HealthCheckInterval time.Duration
+		ShutdownTimeout     time.Duration
+	}
 	NetHttpFastTransporter struct {
 		On                  bool
 		MaxIdleConns        *int
@@ -118,8 +147,10 @@ type Config struct {
 		Redis  dbdrivers.RedisConfig
 		Memory dbdrivers.MemoryConfig
 	}
-	Sentry   Sentry
-	Security struct {
+	Sentry     Sentry
+	Tracing    Tracing
+	ErrorDedup ErrorDedup
+	Security   struct {
 		HTTP struct {
 			Header struct {
 				XssProtection         string
@@ -150,6 +181,99 @@ type Sentry struct {
 	ConfigureScope      func(scope *sentry.Scope)
 }
 
+// ErrorDedup lets through `Burst` occurrences of the same error per `Window` (1 per 1m by default), the same
+// errors having the same type, code and top `Frames` stack frames (3 by default). The suppressed ones
+// are summarized at the end of each window.
+type ErrorDedup struct {
+	On     bool
+	Window time.Duration
+	Burst  int
+	Frames int
+}
+
+// Tracing selects the backend used by `trace.StartSpan` and friends.
+// `Provider` is either `sentry` (default when empty) or `otel`.
+// `Propagation` lists the header formats used to continue and propagate traces: `sentry`, `w3c` and `baggage`.
+type Tracing struct {
+	Provider    string
+	Propagation []string
+	Otel        Otel
+}
+
+// Otel holds the OpenTelemetry SDK and OTLP/HTTP exporter settings.
+type Otel struct {
+	Endpoint            string // host:port of the OTLP/HTTP collector, e.g. `localhost:4318`.
+	URLPath             string // Defaults to `/v1/traces`.
+	Insecure            bool
+	Headers             map[string]string
+	ServiceName         string // Defaults to `projectName`.
+	TracesSampleRate    float64
+	Timeout             time.Duration
+	SkipTracesEndpoints []string
+}
+
+// LogRotation rotates a log file by size and/or time. The zero value never rotates; the file is
+// still reopened on SIGHUP, for an external logrotate.
+type LogRotation struct {
+	MaxSize  int           // In megabytes.
+	Interval time.Duration // E.g. `24h` rotates every day at 00:00 UTC.
+	Compress bool          // Gzip the rotated files.
+	MaxFiles int           // Number of rotated files kept, all if 0.
+	MaxAge   time.Duration // Rotated files older than this are removed, none if 0.
+}
+
+// LogOverflow is the overflow policy of an async log queue.
+type LogOverflow struct {
+	Policy         string        // `drop_newest` (default), `drop_oldest`, `block` or `spill`.
+	BlockTimeout   time.Duration // Longest wait of `block`, 100ms if 0.
+	SpillPath      string        // File of `spill`.
+	SpillMaxSize   int           // In megabytes, 256 if 0.
+	ReportInterval time.Duration // Interval of the WARNING reports of dropped entries, 1 minute if 0.
+}
+
+// LogMaskRule masks the values at some paths of the structured logs.
+type LogMaskRule struct {
+	Scope  string   // `request_body`, `response_body`, `header`, or the whole entry if empty.
+	Levels []string // E.g. `OUTBOUND_API` for the `transport/http` entries. All if empty.
+	Paths  []string // E.g. `$.items[*].card.number`, `request_header.Authorization`, or header names for `header`.
+}
+
+// LogRedactRule redacts the text found by its detectors and patterns in some fields of the logs.
+type LogRedactRule struct {
+	Fields    []string // Dot paths, `*` for any segment, e.g. `request_header.Authorization`. All if empty.
+	Detectors []string // `pan`, `email`, `phone`, `jwt` or `bearer`.
+	Patterns  []string // Custom regular expressions, the first group is redacted if there's one.
+	Action    string   // `mask` (default), `last4`, or `hash` with the `Secret`.
+}
+
+// LogEncoding selects the format of the structured logs.
+type LogEncoding struct {
+	Format       string // `json` (default), `logfmt`, `ecs`, `gcp` or `emf`.
+	GCPProjectID string // Prefixes the `gcp` trace with `projects/<id>/traces/`.
+	EMF          struct {
+		Namespace  string            // `bean` if empty.
+		Dimensions [][]string        // `[["level"]]` if empty.
+		Metrics    map[string]string // Field to unit, `latency_ms` in `Milliseconds` if empty.
+	}
+}
+
+// LogSink is an output of the structured logger, receiving the entries which pass its filters.
+type LogSink struct {
+	Type        string // `stdout`, `file`, `syslog`, `jsonlines` or `fluent`.
+	Path        string // File path, for `file`.
+	Rotation    LogRotation
+	Network     string   // `tcp` (default), `tls`, or `udp` for `syslog`.
+	Address     string   // `host:port` of the network sinks.
+	Tag         string   // Fluent tag, or syslog APP-NAME.
+	Facility    int      // Syslog facility, 1 (user-level) by default.
+	MinSeverity string   // E.g. `ERROR` keeps ERROR and CRITICAL entries. All if empty.
+	Levels      []string // E.g. `ACCESS`, `DUMP`, `OUTBOUND_API`. All if empty.
+	SampleRate  *float64 // Fraction of the entries kept, all if nil.
+	Async       bool
+	QueueSize   int
+	Encoding    LogEncoding // The encoding of `accessLog` if the format is empty.
+}
+
 // LoadConfig parses a given config file into global Bean variable.
 func LoadConfig(filename string) (*Config, error) {
 	ext := filepath.Ext(filename)
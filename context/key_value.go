@@ -10,6 +10,10 @@ type key struct{ string }
 var (
 	requestID   = key{"request_id"}
 	httpRequest = key{"http_request"}
+	traceID     = key{"trace_id"}
+	traceState  = key{"trace_state"}
+	tenantID    = key{"tenant_id"}
+	userID      = key{"user_id"}
 	// TODO: Add more keys here as needed.
 )
 
@@ -21,6 +25,41 @@ func SetRequestID(ctx context.Context, id string) context.Context {
 	return context.WithValue(ctx, requestID, id)
 }
 
+// GetTraceID returns the trace id of the incoming request, as sent by the caller.
+func GetTraceID(ctx context.Context) (string, bool) {
+	return getNotEmptyStr(ctx, traceID)
+}
+
+func SetTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceID, id)
+}
+
+// GetTraceState returns the W3C `tracestate` of the incoming request, as sent by the caller.
+func GetTraceState(ctx context.Context) (string, bool) {
+	return getNotEmptyStr(ctx, traceState)
+}
+
+func SetTraceState(ctx context.Context, state string) context.Context {
+	return context.WithValue(ctx, traceState, state)
+}
+
+func GetTenantID(ctx context.Context) (string, bool) {
+	return getNotEmptyStr(ctx, tenantID)
+}
+
+func SetTenantID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantID, id)
+}
+
+// GetUserID returns the id of the authenticated user, as set by the application.
+func GetUserID(ctx context.Context) (string, bool) {
+	return getNotEmptyStr(ctx, userID)
+}
+
+func SetUserID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, userID, id)
+}
+
 func GetRequest(ctx context.Context) (*http.Request, bool) {
 	return getNonNilPtr(ctx, httpRequest)
 }
@@ -0,0 +1,244 @@
+// MIT License
+
+// Copyright (c) The RAI Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package error
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/retail-ai-inc/bean/v2/stacktrace"
+)
+
+// LogDedup throttles the errors the error handlers log, all are logged if nil. Set by `bean` from
+// `errorDedup` in `env.json`.
+var LogDedup *Dedup
+
+// DedupConfig configures a `Dedup`.
+type DedupConfig struct {
+	// Window is the period the occurrences are counted over, the summaries are emitted at its end. Default 1m.
+	Window time.Duration
+	// Burst is the number of occurrences of an error let through per window. Default 1.
+	Burst int
+	// Frames is the number of stack frames, from the top, in the fingerprint. Default 3.
+	Frames int
+}
+
+// Suppressed summarizes the occurrences of an error suppressed during a window.
+type Suppressed struct {
+	Fingerprint string
+	// Error is the message of the first occurrence.
+	Error string
+	Count int
+	Since time.Time
+}
+
+func (s Suppressed) String() string {
+	return fmt.Sprintf("%d similar errors suppressed since %s: %s", s.Count, s.Since.Format(time.RFC3339), s.Error)
+}
+
+// Dedup lets through the first `Burst` occurrences of an error per window and suppresses the others,
+// the errors being the same if their fingerprints are, see `Fingerprint`. At the end of each window the
+// suppressed occurrences are passed to the summary function, once per fingerprint.
+type Dedup struct {
+	mu      sync.Mutex
+	cfg     DedupConfig
+	seen    map[string]*dedupState
+	since   time.Time
+	summary func(Suppressed)
+	done    chan struct{}
+	stopped sync.Once
+}
+
+type dedupState struct {
+	msg        string
+	count      int
+	suppressed int
+}
+
+// NewDedup returns a deduplicator calling summary with the errors suppressed at the end of every window,
+// until `Close`.
+func NewDedup(cfg DedupConfig, summary func(Suppressed)) *Dedup {
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = 1
+	}
+	if cfg.Frames <= 0 {
+		cfg.Frames = 3
+	}
+
+	d := &Dedup{
+		cfg:     cfg,
+		seen:    map[string]*dedupState{},
+		since:   time.Now(),
+		summary: summary,
+		done:    make(chan struct{}),
+	}
+
+	go func() {
+		ticker := time.NewTicker(cfg.Window)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				d.Flush()
+			case <-d.done:
+				return
+			}
+		}
+	}()
+
+	return d
+}
+
+// Allow reports whether the error is let through, counting it. It is always true for a nil deduplicator.
+func (d *Dedup) Allow(err error) bool {
+	if d == nil || err == nil {
+		return true
+	}
+	return d.AllowFingerprint(d.Fingerprint(err), err.Error())
+}
+
+// AllowFingerprint is `Allow` for an error already fingerprinted, msg is its message for the summary.
+func (d *Dedup) AllowFingerprint(fingerprint, msg string) bool {
+	if d == nil {
+		return true
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	st, ok := d.seen[fingerprint]
+	if !ok {
+		st = &dedupState{msg: msg}
+		d.seen[fingerprint] = st
+	}
+	st.count++
+	if st.count <= d.cfg.Burst {
+		return true
+	}
+	st.suppressed++
+	return false
+}
+
+// Flush ends the window: the suppressed errors are summarized and the counts reset.
+func (d *Dedup) Flush() {
+	d.mu.Lock()
+	since := d.since
+	seen := d.seen
+	d.seen = map[string]*dedupState{}
+	d.since = time.Now()
+	d.mu.Unlock()
+
+	if d.summary == nil {
+		return
+	}
+	for fingerprint, st := range seen {
+		if st.suppressed > 0 {
+			d.summary(Suppressed{Fingerprint: fingerprint, Error: st.msg, Count: st.suppressed, Since: since})
+		}
+	}
+}
+
+// Close stops the windows and summarizes the errors suppressed in the current one.
+func (d *Dedup) Close() error {
+	if d == nil {
+		return nil
+	}
+	d.stopped.Do(func() {
+		close(d.done)
+		d.Flush()
+	})
+	return nil
+}
+
+// Frames returns the number of stack frames in the fingerprints.
+func (d *Dedup) Frames() int {
+	return d.cfg.Frames
+}
+
+// Fingerprint identifies the error by its type, the type of its cause, its code and the top frames of
+// its stack. Its message is used instead of the stack if it has none.
+func (d *Dedup) Fingerprint(err error) string {
+	return Fingerprint(err, d.cfg.Frames)
+}
+
+// Fingerprint is `Dedup.Fingerprint` with the number of stack frames.
+func Fingerprint(err error, frames int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%T", err)
+
+	cause := err
+	for next := errors.Unwrap(cause); next != nil; next = errors.Unwrap(cause) {
+		cause = next
+	}
+	if cause != err {
+		fmt.Fprintf(&b, "|%T", cause)
+	}
+
+	var ae *APIError
+	var he *echo.HTTPError
+	switch {
+	case errors.As(err, &ae):
+		b.WriteString("|" + strconv.Itoa(ae.HTTPStatusCode) + ":" + string(ae.GlobalErrCode))
+	case errors.As(err, &he):
+		b.WriteString("|" + strconv.Itoa(he.Code))
+	}
+
+	var st interface{ StackTrace() stacktrace.StackTrace }
+	if errors.As(err, &st) && stackFrames(&b, st.StackTrace(), frames) {
+		return b.String()
+	}
+
+	b.WriteString("|" + err.Error())
+	return b.String()
+}
+
+// stackFrames writes the top frames of the stack, it reports false if there is none.
+func stackFrames(b *strings.Builder, stack stacktrace.StackTrace, frames int) bool {
+	if len(stack) == 0 || frames <= 0 {
+		return false
+	}
+
+	pcs := make([]uintptr, 0, frames)
+	for _, f := range stack[:min(frames, len(stack))] {
+		// The frames are the return addresses of `runtime.Callers`.
+		pcs = append(pcs, uintptr(f))
+	}
+	callers := runtime.CallersFrames(pcs)
+	for {
+		frame, more := callers.Next()
+		b.WriteString("|" + frame.Function + ":" + strconv.Itoa(frame.Line))
+		if !more {
+			break
+		}
+	}
+	return true
+}
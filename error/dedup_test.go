@@ -0,0 +1,111 @@
+// MIT License
+
+// Copyright (c) The RAI Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package error
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDedup_Allow(t *testing.T) {
+	var mu sync.Mutex
+	var summaries []Suppressed
+	d := NewDedup(DedupConfig{Window: time.Hour, Burst: 2}, func(s Suppressed) {
+		mu.Lock()
+		defer mu.Unlock()
+		summaries = append(summaries, s)
+	})
+
+	newErr := func() error {
+		return NewAPIError(http.StatusBadGateway, INTERNAL_SERVER_ERROR, errors.New("upstream down"))
+	}
+
+	var allowed []bool
+	for range 6 {
+		// Same type, code and stack.
+		allowed = append(allowed, d.Allow(newErr()))
+	}
+	assert.Equal(t, []bool{true, true, false, false, false, false}, allowed)
+	assert.True(t, d.Allow(errors.New("another error")))
+
+	require.NoError(t, d.Close())
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, summaries, 1)
+	assert.Equal(t, 4, summaries[0].Count)
+	assert.Equal(t, "upstream down", summaries[0].Error)
+	assert.Contains(t, summaries[0].String(), "4 similar errors suppressed since ")
+
+	// A new window lets them through again.
+	assert.True(t, d.Allow(errors.New("another error")))
+}
+
+func TestDedup_Window(t *testing.T) {
+	done := make(chan Suppressed, 1)
+	d := NewDedup(DedupConfig{Window: 20 * time.Millisecond}, func(s Suppressed) { done <- s })
+	defer d.Close()
+
+	err := errors.New("boom")
+	assert.True(t, d.Allow(err))
+	assert.False(t, d.Allow(err))
+
+	select {
+	case s := <-done:
+		assert.Equal(t, 1, s.Count)
+	case <-time.After(time.Second):
+		t.Fatal("no summary at the end of the window")
+	}
+	assert.True(t, d.Allow(err))
+}
+
+func TestDedup_Nil(t *testing.T) {
+	var d *Dedup
+	assert.True(t, d.Allow(errors.New("boom")))
+	assert.NoError(t, d.Close())
+}
+
+func TestFingerprint(t *testing.T) {
+	apiErr := func(code ErrorCode) error {
+		return NewAPIError(http.StatusInternalServerError, code, errors.New("db down"))
+	}
+	a, b := apiErr(INTERNAL_SERVER_ERROR), apiErr(INTERNAL_SERVER_ERROR)
+	assert.Equal(t, Fingerprint(a, 3), Fingerprint(b, 3))
+	assert.NotEqual(t, Fingerprint(a, 3), Fingerprint(apiErr(TIMEOUT), 3))
+	assert.NotEqual(t, Fingerprint(a, 3), Fingerprint(NewAPIError(http.StatusInternalServerError, INTERNAL_SERVER_ERROR, errors.New("db down")), 3),
+		"created elsewhere")
+	assert.Contains(t, Fingerprint(a, 3), "*error.APIError|*errors.errorString|500:100004|")
+
+	// Without a stack the message tells the errors apart.
+	assert.Equal(t, Fingerprint(errors.New("x"), 3), Fingerprint(errors.New("x"), 3))
+	assert.NotEqual(t, Fingerprint(errors.New("x"), 3), Fingerprint(errors.New("y"), 3))
+	assert.Equal(t, "*fmt.wrapError|*echo.HTTPError|502|wrapped: code=502, message=Bad Gateway",
+		Fingerprint(fmt.Errorf("wrapped: %w", echo.NewHTTPError(http.StatusBadGateway)), 3))
+}
@@ -0,0 +1,237 @@
+// MIT License
+
+// Copyright (c) The RAI Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package error
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/retail-ai-inc/bean/v2/stacktrace"
+	"github.com/spf13/viper"
+)
+
+// devSourceLines is the number of source lines shown before and after the line of a frame.
+const devSourceLines = 5
+
+// devEnvironments are the values of `environment` in `env.json` where the stack traces are shown.
+var devEnvironments = []string{"local", "dev", "development"}
+
+// maskedHeaders are the parts of the names of the headers masked on the developer error page.
+var maskedHeaders = []string{"auth", "cookie", "token", "secret", "key", "password", "session"}
+
+// DevEnvironment reports whether the `environment` of `env.json` is a development one: `local`, `dev` or
+// `development`. The HTML error responses show the developer error page there, and no stack trace anywhere else.
+func DevEnvironment() bool {
+	return slices.Contains(devEnvironments, strings.ToLower(viper.GetString("environment")))
+}
+
+type devPage struct {
+	Status     int
+	StatusText string
+	Code       ErrorCode
+	Detail     string
+	Error      string
+	Frames     []devFrame
+	// Trace is the `%+v` of the error, shown if it has no `stacktrace.StackTrace`.
+	Trace   string
+	Method  string
+	URL     string
+	Route   string
+	Handler string
+	Params  [][2]string
+	Headers [][2]string
+	Curl    string
+}
+
+type devFrame struct {
+	Function string
+	File     string
+	Line     int
+	Source   []devLine
+}
+
+type devLine struct {
+	Number  int
+	Text    string
+	Current bool
+}
+
+// renderDevPage writes the developer error page: the frames of the error with the source around them, and the
+// request with its sensitive headers masked.
+func renderDevPage(c echo.Context, res *ErrorResponse) error {
+	req := c.Request()
+
+	page := devPage{
+		Status:     res.Status,
+		StatusText: http.StatusText(res.Status),
+		Code:       res.Code,
+		Detail:     res.Detail,
+		Error:      res.Err.Error(),
+		Method:     req.Method,
+		URL:        c.Scheme() + "://" + req.Host + req.RequestURI,
+		Route:      c.Path(),
+	}
+
+	var st interface{ StackTrace() stacktrace.StackTrace }
+	if errors.As(res.Err, &st) {
+		for _, f := range st.StackTrace() {
+			function, file, line := f.Source()
+			page.Frames = append(page.Frames, devFrame{Function: function, File: file, Line: line, Source: sourceLines(file, line)})
+		}
+	} else {
+		page.Trace = fmt.Sprintf("%+v", res.Err)
+	}
+
+	for _, r := range c.Echo().Routes() {
+		if r.Method == req.Method && r.Path == page.Route {
+			page.Handler = r.Name
+			break
+		}
+	}
+
+	values := c.ParamValues()
+	for i, name := range c.ParamNames() {
+		if i < len(values) {
+			page.Params = append(page.Params, [2]string{name, values[i]})
+		}
+	}
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	curl := []string{"curl", "-X", req.Method, shellQuote(page.URL)}
+	for _, name := range names {
+		for _, value := range req.Header[name] {
+			if headerMasked(name) {
+				value = "****"
+			}
+			page.Headers = append(page.Headers, [2]string{name, value})
+			curl = append(curl, "-H", shellQuote(name+": "+value))
+		}
+	}
+	page.Curl = strings.Join(curl, " ")
+
+	var b strings.Builder
+	if err := devPageTemplate.Execute(&b, page); err != nil {
+		return err
+	}
+	return c.HTML(res.Status, b.String())
+}
+
+// sourceLines returns the lines of the file around the line, nil if it can't be read.
+func sourceLines(file string, line int) []devLine {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var lines []devLine
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan() && n <= line+devSourceLines; n++ {
+		if n >= line-devSourceLines {
+			lines = append(lines, devLine{Number: n, Text: scanner.Text(), Current: n == line})
+		}
+	}
+	return lines
+}
+
+func headerMasked(name string) bool {
+	name = strings.ToLower(name)
+	for _, part := range maskedHeaders {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// shellQuote quotes the string for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+var devPageTemplate = template.Must(template.New("dev").Parse(`<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Status}} {{.StatusText}}</title>
+<style>
+body { font-family: -apple-system, sans-serif; margin: 2em; color: #222; }
+h1 { color: #c0392b; margin-bottom: 0; }
+pre, code, td { font-family: Menlo, Consolas, monospace; font-size: 13px; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+td { border-bottom: 1px solid #eee; padding: 2px 8px; vertical-align: top; word-break: break-all; }
+details { margin: 4px 0; }
+summary { cursor: pointer; }
+.file { color: #888; }
+.source { background: #f6f8fa; padding: 4px 0; margin: 4px 0 8px; }
+.source div { white-space: pre; padding: 0 8px; }
+.source .current { background: #fde2e1; }
+.source span { display: inline-block; width: 4em; color: #999; }
+</style>
+</head>
+<body>
+<h1>{{.Status}} {{.StatusText}}{{if .Code}} ({{.Code}}){{end}}</h1>
+<p><strong>{{.Error}}</strong></p>
+{{if ne .Detail .Error}}<p>{{.Detail}}</p>{{end}}
+
+<h2>Stack trace</h2>
+{{range $i, $f := .Frames}}
+<details{{if eq $i 0}} open{{end}}>
+<summary><code>{{$f.Function}}</code> <span class="file">{{$f.File}}:{{$f.Line}}</span></summary>
+{{if $f.Source}}<div class="source">{{range $f.Source}}<div{{if .Current}} class="current"{{end}}><span>{{.Number}}</span>{{.Text}}</div>{{end}}</div>{{end}}
+</details>
+{{else}}
+<pre>{{.Trace}}</pre>
+{{end}}
+
+<h2>Request</h2>
+<table>
+<tr><td>Method</td><td>{{.Method}}</td></tr>
+<tr><td>URL</td><td>{{.URL}}</td></tr>
+<tr><td>Route</td><td>{{.Route}}</td></tr>
+{{if .Handler}}<tr><td>Handler</td><td>{{.Handler}}</td></tr>{{end}}
+{{range .Params}}<tr><td>:{{index . 0}}</td><td>{{index . 1}}</td></tr>{{end}}
+</table>
+
+<h2>Headers</h2>
+<table>
+{{range .Headers}}<tr><td>{{index . 0}}</td><td>{{index . 1}}</td></tr>{{end}}
+</table>
+
+<h2>curl <button onclick="navigator.clipboard.writeText(document.getElementById('curl').textContent)">Copy as curl</button></h2>
+<pre id="curl">{{.Curl}}</pre>
+</body>
+</html>
+`))
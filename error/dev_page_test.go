@@ -0,0 +1,117 @@
+// MIT License
+
+// Copyright (c) The RAI Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package error
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/retail-ai-inc/bean/v2/echoview"
+	"github.com/retail-ai-inc/bean/v2/goview"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveDevPage(environment string) *httptest.ResponseRecorder {
+	viper.Set("environment", environment)
+	defer viper.Set("environment", "")
+
+	e := echo.New()
+	e.Renderer = echoview.New(goview.Config{
+		Root:         TEST_VIEWS_ROOT,
+		Extension:    ".html",
+		Master:       "templates/master",
+		Partials:     []string{},
+		Funcs:        make(template.FuncMap),
+		DisableCache: true,
+		Delims:       goview.Delims{Left: "{{", Right: "}}"},
+	})
+	e.HTTPErrorHandler = func(err error, c echo.Context) {
+		for _, handle := range []ErrorHandlerFunc{APIErrorHandlerFunc, DefaultErrorHandlerFunc} {
+			if handled, _ := handle(err, c); handled {
+				return
+			}
+		}
+	}
+	e.GET("/users/:id", func(c echo.Context) error {
+		return NewAPIError(http.StatusConflict, "T00201", errors.New("user <b>already</b> exists"))
+	}).Name = "users.show"
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42?debug=1", nil)
+	req.Header.Set(echo.HeaderAccept, echo.MIMETextHTML)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer s3cret")
+	req.Header.Set("X-Api-Key", "k3y")
+	req.Header.Set("X-Tenant", "o'brien")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestDevPage(t *testing.T) {
+	rec := serveDevPage("local")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, echo.MIMETextHTMLCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+
+	body := rec.Body.String()
+	assert.Contains(t, body, "409 Conflict (T00201)")
+	assert.Contains(t, body, "user &lt;b&gt;already&lt;/b&gt; exists")
+	assert.Contains(t, body, "error/dev_page_test.go:")
+	assert.Contains(t, body, `class="current"`)
+	assert.Contains(t, body, "return NewAPIError(http.StatusConflict")
+	assert.Contains(t, body, "<td>/users/:id</td>")
+	assert.Contains(t, body, "<td>users.show</td>")
+	assert.Contains(t, body, "<td>:id</td><td>42</td>")
+	assert.Contains(t, body, "<td>Authorization</td><td>****</td>")
+	assert.Contains(t, body, "<td>X-Api-Key</td><td>****</td>")
+	assert.Contains(t, body, "-H &#39;Authorization: ****&#39;")
+	assert.Contains(t, body, "curl -X GET &#39;http://example.com/users/42?debug=1&#39;")
+	assert.Contains(t, body, "&#39;X-Tenant: o&#39;\\&#39;&#39;brien&#39;")
+	assert.Contains(t, body, "Copy as curl")
+}
+
+func TestDevPage_Production(t *testing.T) {
+	rec := serveDevPage("production")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, echo.MIMEApplicationJSON, rec.Header().Get(echo.HeaderContentType), "the API errors have no HTML page")
+	assert.NotContains(t, rec.Body.String(), "dev_page_test.go")
+
+	viper.Set("environment", "production")
+	defer viper.Set("environment", "")
+	res := serveNegotiated(DefaultErrorHandlerFunc, NewAPIError(http.StatusInternalServerError, INTERNAL_SERVER_ERROR, errors.New("boom")), echo.MIMETextHTML, "")
+	assert.Equal(t, echo.MIMETextHTMLCharsetUTF8, res.Header().Get(echo.HeaderContentType))
+	assert.Contains(t, res.Body.String(), "500 Internal Server Error")
+	assert.NotContains(t, res.Body.String(), "dev_page_test.go")
+}
+
+func TestDevEnvironment(t *testing.T) {
+	defer viper.Set("environment", "")
+
+	for env, want := range map[string]bool{"local": true, "DEV": true, "development": true, "staging": false, "production": false, "": false} {
+		viper.Set("environment", env)
+		assert.Equal(t, want, DevEnvironment(), env)
+	}
+}
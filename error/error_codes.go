@@ -38,6 +38,7 @@ const (
 	METHOD_NOT_ALLOWED           ErrorCode = "100006"
 	SERVICE_DOWN_FOR_MAINTENANCE ErrorCode = "100009"
 	TOO_MANY_REQUESTS            ErrorCode = "100010"
+	RESOURCE_CONFLICT            ErrorCode = "100011"
 	UNKNOWN_ERROR_CODE           ErrorCode = "100098"
 	TIMEOUT                      ErrorCode = "100099"
 
@@ -26,7 +26,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"strings"
+	"strconv"
 
 	sentryecho "github.com/getsentry/sentry-go/echo"
 	"github.com/labstack/echo/v4"
@@ -49,11 +49,20 @@ func ValidationErrorHandlerFunc(e error, c echo.Context) (bool, error) {
 		return false, nil
 	}
 
-	c.Logger().Error(ve)
+	if LogDedup.Allow(ve) {
+		c.Logger().Error(ve)
+	}
 
-	err := c.JSON(http.StatusBadRequest, ErrorResp{
-		ErrorCode: API_DATA_VALIDATION_FAILED,
-		Errors:    ve.ErrCollection(),
+	err := WriteErrorResponse(c, &ErrorResponse{
+		Status: http.StatusBadRequest,
+		Code:   API_DATA_VALIDATION_FAILED,
+		Detail: "request validation failed",
+		Errors: ve.ErrCollection(),
+		Body: ErrorResp{
+			ErrorCode: API_DATA_VALIDATION_FAILED,
+			Errors:    ve.ErrCollection(),
+		},
+		Err: ve,
 	})
 
 	return true, err
@@ -75,141 +84,80 @@ func APIErrorHandlerFunc(e error, c echo.Context) (bool, error) {
 		if hub := sentryecho.GetHubFromContext(c); hub != nil {
 			hub.CaptureException(ae)
 		}
-	} else {
+	} else if LogDedup.Allow(ae) {
 		// logs the stack trace for all errors when sentry is disabled.
 		c.Logger().Errorf("%+v", ae)
 	}
 
-	err := c.JSON(ae.HTTPStatusCode, ErrorResp{
-		ErrorCode: ae.GlobalErrCode,
-		ErrorMsg:  ae.Error(),
+	err := WriteErrorResponse(c, &ErrorResponse{
+		Status: ae.HTTPStatusCode,
+		Code:   ae.GlobalErrCode,
+		Detail: ae.Error(),
+		Body: ErrorResp{
+			ErrorCode: ae.GlobalErrCode,
+			ErrorMsg:  ae.Error(),
+		},
+		Err: ae,
 	})
 
 	return true, err
 }
 
+// httpErrorCodes are the error codes of the statuses of `echo.HTTPError`, `INTERNAL_SERVER_ERROR` for the others.
+var httpErrorCodes = map[int]ErrorCode{
+	http.StatusUnauthorized:          UNAUTHORIZED_ACCESS,
+	http.StatusNotFound:              RESOURCE_NOT_FOUND,
+	http.StatusMethodNotAllowed:      METHOD_NOT_ALLOWED,
+	http.StatusRequestEntityTooLarge: REQUEST_ENTITY_TOO_LARGE,
+	http.StatusTooManyRequests:       TOO_MANY_REQUESTS,
+	http.StatusServiceUnavailable:    SERVICE_DOWN_FOR_MAINTENANCE,
+	http.StatusGatewayTimeout:        TIMEOUT,
+}
+
+// httpErrorTemplates are the HTML templates of the statuses, `errors/html/500` for the others.
+var httpErrorTemplates = map[int]string{
+	http.StatusNotFound:           "errors/html/404",
+	http.StatusMethodNotAllowed:   "errors/html/405",
+	http.StatusServiceUnavailable: "errors/html/503",
+	http.StatusGatewayTimeout:     "errors/html/504",
+}
+
 func HTTPErrorHandlerFunc(e error, c echo.Context) (bool, error) {
 	var he *echo.HTTPError
 	if !errors.As(e, &he) {
 		return false, nil
 	}
 
-	c.Logger().Error(he)
-
-	// Return different response based on some defined error.
-	var err error
-	switch he.Code {
-	case http.StatusNotFound:
-		if !strings.Contains(c.Request().Header.Get("Content-Type"), "application/json") {
-			// Get from env.json file.
-			html404File := viper.GetString("http.errorMessage.e404.html.file")
-			if html404File != "" {
-				err = c.Render(he.Code, html404File, echo.Map{"stacktrace": fmt.Sprintf("%+v", e)})
-			} else {
-				err = c.Render(he.Code, "errors/html/404", echo.Map{"stacktrace": fmt.Sprintf("%+v", e)})
-			}
-		} else {
-			// Get from env.json file.
-			e404 := viper.GetStringMap("http.errorMessage.e404")
-			if val, ok := e404["json"]; ok {
-				err = c.JSON(he.Code, converter(val))
-			} else {
-				err = c.JSON(he.Code, ErrorResp{ErrorCode: RESOURCE_NOT_FOUND, ErrorMsg: he.Message})
-			}
-		}
-
-	case http.StatusMethodNotAllowed:
-		if !strings.Contains(c.Request().Header.Get("Content-Type"), "application/json") {
-			err = c.Render(he.Code, "errors/html/405", echo.Map{"stacktrace": fmt.Sprintf("%+v", e)})
-		} else {
-			// Get from env.json file.
-			e405 := viper.GetStringMap("http.errorMessage.e405")
-			if val, ok := e405["json"]; ok {
-				err = c.JSON(he.Code, converter(val))
-			} else {
-				err = c.JSON(he.Code, ErrorResp{ErrorCode: METHOD_NOT_ALLOWED, ErrorMsg: he.Message})
-			}
-		}
-
-	case http.StatusInternalServerError:
-		// Send error event to sentry if configured.
-		if viper.GetBool("sentry.on") {
-			if hub := sentryecho.GetHubFromContext(c); hub != nil {
-				hub.CaptureException(he)
-			}
-		}
+	if LogDedup.Allow(he) {
+		c.Logger().Error(he)
+	}
 
-		if !strings.Contains(c.Request().Header.Get("Content-Type"), "application/json") {
-			// Get from env.json file.
-			html500File := viper.GetString("http.errorMessage.e500.html.file")
-			if html500File != "" {
-				err = c.Render(he.Code, html500File, echo.Map{"stacktrace": fmt.Sprintf("%+v", e)})
-			} else {
-				err = c.Render(he.Code, "errors/html/500", echo.Map{"stacktrace": fmt.Sprintf("%+v", e)})
-			}
-		} else {
-			// Get from env.json file.
-			def := viper.GetStringMap("http.errorMessage.e500")
-			if val, ok := def["json"]; ok {
-				err = c.JSON(he.Code, converter(val))
-			} else {
-				err = c.JSON(he.Code, ErrorResp{ErrorCode: INTERNAL_SERVER_ERROR, ErrorMsg: he.Message})
-			}
-		}
+	key := errorMessageKey(he.Code)
 
-	case http.StatusGatewayTimeout:
-		if viper.GetBool("sentry.on") {
-			if hub := sentryecho.GetHubFromContext(c); hub != nil {
+	// Send error event to sentry if configured.
+	if viper.GetBool("sentry.on") && sentryCaptured(he.Code, key) {
+		if hub := sentryecho.GetHubFromContext(c); hub != nil {
+			if he.Code == http.StatusGatewayTimeout && he.Internal != nil {
 				hub.CaptureException(he.Internal)
-			}
-		}
-
-		if !strings.Contains(c.Request().Header.Get("Content-Type"), "application/json") {
-			// Get from env.json file.
-			html504File := viper.GetString("http.errorMessage.e504.html.file")
-			if html504File != "" {
-				err = c.Render(he.Code, html504File, echo.Map{"stacktrace": fmt.Sprintf("%+v", e)})
-			} else {
-				err = c.Render(he.Code, "errors/html/504", echo.Map{"stacktrace": fmt.Sprintf("%+v", e)})
-			}
-		} else {
-			// Get from env.json file.
-			e504 := viper.GetStringMap("http.errorMessage.e504")
-			if val, ok := e504["json"]; ok {
-				err = c.JSON(he.Code, converter(val))
 			} else {
-				err = c.JSON(he.Code, ErrorResp{ErrorCode: TIMEOUT, ErrorMsg: he.Message})
-			}
-		}
-
-	default:
-		// Send error event to sentry if configured.
-		if viper.GetBool("sentry.on") {
-			if hub := sentryecho.GetHubFromContext(c); hub != nil {
 				hub.CaptureException(he)
 			}
 		}
+	}
 
-		if !strings.Contains(c.Request().Header.Get("Content-Type"), "application/json") {
-			// Get from env.json file.
-			html500File := viper.GetString("http.errorMessage.default.html.file")
-			if html500File != "" {
-				err = c.Render(he.Code, html500File, echo.Map{"stacktrace": fmt.Sprintf("%+v", e)})
-			} else {
-				err = c.Render(he.Code, "errors/html/500", echo.Map{"stacktrace": fmt.Sprintf("%+v", e)})
-			}
-		} else {
-			// Get from env.json file.
-			def := viper.GetStringMap("http.errorMessage.default")
-			if val, ok := def["json"]; ok {
-				err = c.JSON(he.Code, converter(val))
-			} else {
-				err = c.JSON(he.Code, ErrorResp{ErrorCode: INTERNAL_SERVER_ERROR, ErrorMsg: he.Message})
-			}
-		}
+	code, ok := httpErrorCodes[he.Code]
+	if !ok {
+		code = INTERNAL_SERVER_ERROR
+	}
+	template, ok := httpErrorTemplates[he.Code]
+	if !ok {
+		template = "errors/html/500"
 	}
 
-	return true, err
+	res := configuredErrorResponse(he.Code, code, he.Message, key, template)
+	res.Err = e
+
+	return true, WriteErrorResponse(c, res)
 }
 
 // If any other error handler doesn't catch the error then finally `DefaultErrorHandlerFunc` will
@@ -220,46 +168,81 @@ func DefaultErrorHandlerFunc(err error, c echo.Context) (bool, error) {
 		return false, nil
 	}
 
+	// The logs of the same errors are throttled by `LogDedup`, the events by the beforeSend function.
+	logged := LogDedup.Allow(err)
+
 	// Send error event to sentry if configured.
 	if viper.GetBool("sentry.on") {
-		c.Logger().Error(err)
+		if logged {
+			c.Logger().Error(err)
+		}
 
-		if hub := sentryecho.GetHubFromContext(c); hub != nil {
+		if hub := sentryecho.GetHubFromContext(c); hub != nil && sentryCaptured(http.StatusInternalServerError, errorMessageKey(http.StatusInternalServerError)) {
 			hub.CaptureException(err)
 		}
-	} else {
+	} else if logged {
 		c.Logger().Errorf("%+v", err)
 	}
 
-	// Get Content-Type parameter from request header to identify the request content type. If the request is for
-	// html then we should display the error in html.
-	if !strings.Contains(c.Request().Header.Get("Content-Type"), "application/json") {
-		// Get from env.json file.
-		html500File := viper.GetString("http.errorMessage.default.html.file")
-		if html500File != "" {
-			return true, c.Render(http.StatusInternalServerError, html500File, echo.Map{
-				"stacktrace": fmt.Sprintf("%+v", err),
-			})
-		} else {
-			return true, c.Render(http.StatusInternalServerError, "errors/html/500", echo.Map{
-				"stacktrace": fmt.Sprintf("%+v", err),
-			})
+	// The responses get the configured message, or the status text, never the internal error kept for the logs.
+	res := configuredErrorResponse(http.StatusInternalServerError, INTERNAL_SERVER_ERROR, http.StatusText(http.StatusInternalServerError), errorMessageKey(http.StatusInternalServerError), "errors/html/500")
+	res.Detail = configuredMessage(res.Body, http.StatusText(http.StatusInternalServerError))
+	res.Err = err
+
+	return true, WriteErrorResponse(c, res)
+}
+
+// errorMessageKey returns the key of the status in `http.errorMessage` of `env.json`: the status, e.g. `429`, the
+// former `e429`, its class, e.g. `4xx`, or `default`.
+func errorMessageKey(status int) string {
+	for _, key := range []string{strconv.Itoa(status), "e" + strconv.Itoa(status), strconv.Itoa(status/100) + "xx"} {
+		if viper.IsSet("http.errorMessage." + key) {
+			return key
 		}
 	}
+	return "default"
+}
+
+// sentryCaptured reports whether the errors of the status are sent to sentry: `sentry` of the key in
+// `http.errorMessage` of `env.json` if set, else all but the 404 and 405 errors.
+func sentryCaptured(status int, key string) bool {
+	if viper.IsSet("http.errorMessage." + key + ".sentry") {
+		return viper.GetBool("http.errorMessage." + key + ".sentry")
+	}
+	return status != http.StatusNotFound && status != http.StatusMethodNotAllowed
+}
+
+// configuredErrorResponse returns an error response with the HTML file and the JSON of
+// `http.errorMessage.<key>` in `env.json`, if set.
+func configuredErrorResponse(status int, code ErrorCode, msg interface{}, key, template string) *ErrorResponse {
+	res := &ErrorResponse{
+		Status:   status,
+		Code:     code,
+		Detail:   fmt.Sprint(msg),
+		Body:     ErrorResp{ErrorCode: code, ErrorMsg: msg},
+		Template: template,
+	}
 
-	// If the Content-Type is `application/json` then return JSON response.
 	// Get from env.json file.
-	def := viper.GetStringMap("http.errorMessage.default")
-	if val, ok := def["json"]; ok {
-		err = c.JSON(http.StatusInternalServerError, converter(val))
-	} else {
-		err = c.JSON(http.StatusInternalServerError, ErrorResp{
-			ErrorCode: INTERNAL_SERVER_ERROR,
-			ErrorMsg:  err.Error(),
-		})
+	if file := viper.GetString("http.errorMessage." + key + ".html.file"); file != "" {
+		res.Template = file
+	}
+	if val, ok := viper.GetStringMap("http.errorMessage." + key)["json"]; ok {
+		res.Body = converter(val)
 	}
 
-	return true, err
+	return res
+}
+
+// configuredMessage returns the `errorMsg` of the JSON of `http.errorMessage` in `env.json`, or def if
+// it isn't set.
+func configuredMessage(body interface{}, def string) string {
+	if m, ok := body.(map[string]interface{}); ok {
+		if msg, ok := m["errorMsg"].(string); ok && msg != "" {
+			return msg
+		}
+	}
+	return def
 }
 
 func converter(data interface{}) interface{} {
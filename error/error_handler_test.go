@@ -35,6 +35,7 @@ import (
 	"github.com/retail-ai-inc/bean/v2/echoview"
 	"github.com/retail-ai-inc/bean/v2/goview"
 	"github.com/retail-ai-inc/bean/v2/internal/validator"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -142,3 +143,41 @@ func TestDefaultErrorHanderFunc(t *testing.T) {
 
 	e.ReleaseContext(c)
 }
+
+func TestHTTPErrorHandlerFunc_ErrorMessage(t *testing.T) {
+	viper.Set("http.errorMessage", map[string]interface{}{
+		"429": map[string]interface{}{
+			"json": []interface{}{
+				map[string]interface{}{"key": "errorCode", "value": "100010"},
+				map[string]interface{}{"key": "errorMsg", "value": "slow down"},
+			},
+			"sentry": false,
+		},
+		"e404": map[string]interface{}{"sentry": true},
+		"5xx":  map[string]interface{}{"sentry": false},
+	})
+	defer viper.Set("http.errorMessage", nil)
+
+	rec := serveNegotiated(HTTPErrorHandlerFunc, echo.NewHTTPError(http.StatusTooManyRequests), echo.MIMEApplicationJSON, "")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.JSONEq(t, `{"errorCode":"100010","errorMsg":"slow down"}`, rec.Body.String())
+
+	rec = serveNegotiated(HTTPErrorHandlerFunc, echo.NewHTTPError(http.StatusUnauthorized, "token expired"), echo.MIMEApplicationJSON, "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.JSONEq(t, `{"errorCode":"100002","errorMsg":"token expired","errors":null}`, rec.Body.String())
+
+	rec = serveNegotiated(HTTPErrorHandlerFunc, echo.NewHTTPError(http.StatusServiceUnavailable), echo.MIMETextHTML, "")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), "503 Service Unavailable")
+
+	assert.Equal(t, "429", errorMessageKey(http.StatusTooManyRequests))
+	assert.Equal(t, "e404", errorMessageKey(http.StatusNotFound))
+	assert.Equal(t, "5xx", errorMessageKey(http.StatusBadGateway))
+	assert.Equal(t, "default", errorMessageKey(http.StatusForbidden))
+
+	assert.False(t, sentryCaptured(http.StatusTooManyRequests, "429"))
+	assert.True(t, sentryCaptured(http.StatusNotFound, "e404"))
+	assert.False(t, sentryCaptured(http.StatusBadGateway, "5xx"))
+	assert.True(t, sentryCaptured(http.StatusForbidden, "default"))
+	assert.False(t, sentryCaptured(http.StatusMethodNotAllowed, "default"))
+}
@@ -0,0 +1,166 @@
+// MIT License
+
+// Copyright (c) The RAI Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package error
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/go-sql-driver/mysql"
+	"github.com/labstack/echo/v4"
+	"github.com/retail-ai-inc/bean/v2/stacktrace"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/gorm"
+)
+
+// mysqlDuplicateEntry is the MySQL error number of a duplicate key.
+const mysqlDuplicateEntry = 1062
+
+// ErrorMapper maps an error, e.g. of a database driver, to the status and the error code of an API error.
+// It reports false if it doesn't know the error.
+type ErrorMapper func(err error) (status int, code ErrorCode, ok bool)
+
+var mappers = struct {
+	sync.RWMutex
+	funcs []ErrorMapper
+}{}
+
+// driverMappers map the errors of the drivers bean connects, after the mappers of `RegisterErrorMapper`.
+var driverMappers = []ErrorMapper{
+	func(err error) (int, ErrorCode, bool) {
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, redis.Nil) {
+			return http.StatusNotFound, RESOURCE_NOT_FOUND, true
+		}
+		return 0, "", false
+	},
+	func(err error) (int, ErrorCode, bool) {
+		var me *mysql.MySQLError
+		if errors.Is(err, gorm.ErrDuplicatedKey) || errors.As(err, &me) && me.Number == mysqlDuplicateEntry || mongo.IsDuplicateKeyError(err) {
+			return http.StatusConflict, RESOURCE_CONFLICT, true
+		}
+		return 0, "", false
+	},
+	func(err error) (int, ErrorCode, bool) {
+		if errors.Is(err, context.DeadlineExceeded) || mongo.IsTimeout(err) {
+			return http.StatusGatewayTimeout, TIMEOUT, true
+		}
+		return 0, "", false
+	},
+}
+
+// RegisterErrorMapper adds mappers to the chain of `MapError`. They are tried in the order they are
+// registered, before the ones of the drivers, so they can override them.
+func RegisterErrorMapper(m ...ErrorMapper) {
+	mappers.Lock()
+	defer mappers.Unlock()
+	mappers.funcs = append(mappers.funcs, m...)
+}
+
+// MapError returns the `APIError` of the first mapper knowing the error, or the error as is if none does,
+// or if it's already an `APIError` or an `echo.HTTPError`. The default HTTP error handler maps the errors before
+// passing them to the error handler funcs.
+// The API error wraps the original error, keeping its stack if it has one, so the logs and sentry still get the
+// error of the driver; its message is the status text, not to send the one of the driver to the client. The
+// errors mapped to a 4xx status are ignorable.
+func MapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var ae *APIError
+	var he *echo.HTTPError
+	if errors.As(err, &ae) || errors.As(err, &he) {
+		return err
+	}
+
+	mappers.RLock()
+	funcs := slices.Concat(mappers.funcs, driverMappers)
+	mappers.RUnlock()
+
+	for _, m := range funcs {
+		status, code, ok := m(err)
+		if !ok {
+			continue
+		}
+		return &APIError{
+			HTTPStatusCode: status,
+			GlobalErrCode:  code,
+			Err:            &mappedError{status: status, err: err},
+			Ignorable:      status < http.StatusInternalServerError,
+			Stack:          stackOf(err),
+		}
+	}
+
+	return err
+}
+
+// stackOf returns the stack of the error, or the one of the caller of `MapError` if it has none.
+func stackOf(err error) *stacktrace.Stack {
+	var st interface{ StackTrace() stacktrace.StackTrace }
+	if !errors.As(err, &st) {
+		return stacktrace.Callers()
+	}
+
+	frames := st.StackTrace()
+	s := make(stacktrace.Stack, len(frames))
+	for i, f := range frames {
+		s[i] = uintptr(f)
+	}
+	return &s
+}
+
+// mappedError is the error of an `APIError` returned by `MapError`.
+type mappedError struct {
+	status int
+	err    error
+}
+
+func (e *mappedError) Error() string {
+	return http.StatusText(e.status)
+}
+
+// Unwrap returns the original error. It is used by errors.Is and errors.As.
+func (e *mappedError) Unwrap() error {
+	return e.err
+}
+
+func (e *mappedError) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			_, _ = fmt.Fprintf(s, "%s: %+v", e.Error(), e.err)
+			return
+		}
+		fallthrough
+	case 's':
+		_, _ = io.WriteString(s, e.Error())
+	case 'q':
+		_, _ = fmt.Fprintf(s, "%q", e.Error())
+	}
+}
@@ -0,0 +1,113 @@
+// MIT License
+
+// Copyright (c) The RAI Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package error
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/go-sql-driver/mysql"
+	"github.com/labstack/echo/v4"
+	"github.com/retail-ai-inc/bean/v2/stacktrace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/gorm"
+)
+
+type stackError struct {
+	*stacktrace.Stack
+}
+
+func (stackError) Error() string { return "with stack" }
+
+func TestMapError(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   ErrorCode
+	}{
+		{fmt.Errorf("find user: %w", gorm.ErrRecordNotFound), http.StatusNotFound, RESOURCE_NOT_FOUND},
+		{mongo.ErrNoDocuments, http.StatusNotFound, RESOURCE_NOT_FOUND},
+		{redis.Nil, http.StatusNotFound, RESOURCE_NOT_FOUND},
+		{&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a@example.com' for key 'users.email'"}, http.StatusConflict, RESOURCE_CONFLICT},
+		{gorm.ErrDuplicatedKey, http.StatusConflict, RESOURCE_CONFLICT},
+		{fmt.Errorf("call payment: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, TIMEOUT},
+	}
+	for _, tt := range tests {
+		var ae *APIError
+		require.ErrorAs(t, MapError(tt.err), &ae, tt.err.Error())
+		assert.Equal(t, tt.status, ae.HTTPStatusCode)
+		assert.Equal(t, tt.code, ae.GlobalErrCode)
+		assert.Equal(t, tt.status < http.StatusInternalServerError, ae.Ignorable)
+		assert.ErrorIs(t, ae, tt.err, "the original error is kept")
+		assert.Equal(t, http.StatusText(tt.status), ae.Error())
+		assert.Contains(t, fmt.Sprintf("%+v", ae), tt.err.Error())
+		assert.NotEmpty(t, ae.StackTrace())
+	}
+
+	for _, err := range []error{
+		nil,
+		errors.New("boom"),
+		&mysql.MySQLError{Number: 1146},
+		NewAPIError(http.StatusBadRequest, PROBLEM_PARSING_JSON, gorm.ErrRecordNotFound),
+		echo.NewHTTPError(http.StatusServiceUnavailable).SetInternal(context.DeadlineExceeded),
+	} {
+		assert.Equal(t, err, MapError(err))
+	}
+}
+
+func TestMapError_Stack(t *testing.T) {
+	st := stacktrace.Callers()
+	var ae *APIError
+	require.ErrorAs(t, MapError(fmt.Errorf("query: %w, %w", stackError{st}, gorm.ErrRecordNotFound)), &ae)
+	assert.Equal(t, st.StackTrace(), ae.StackTrace())
+}
+
+func TestRegisterErrorMapper(t *testing.T) {
+	defer func() { mappers.funcs = nil }()
+
+	errLocked := errors.New("account locked")
+	RegisterErrorMapper(
+		func(err error) (int, ErrorCode, bool) {
+			return http.StatusLocked, "300001", errors.Is(err, errLocked)
+		},
+		func(err error) (int, ErrorCode, bool) {
+			return http.StatusGone, "300002", errors.Is(err, gorm.ErrRecordNotFound)
+		},
+	)
+
+	var ae *APIError
+	require.ErrorAs(t, MapError(errLocked), &ae)
+	assert.Equal(t, http.StatusLocked, ae.HTTPStatusCode)
+
+	require.ErrorAs(t, MapError(gorm.ErrRecordNotFound), &ae)
+	assert.Equal(t, http.StatusGone, ae.HTTPStatusCode, "registered before the ones of the drivers")
+
+	require.ErrorAs(t, MapError(redis.Nil), &ae)
+	assert.Equal(t, http.StatusNotFound, ae.HTTPStatusCode)
+}
@@ -0,0 +1,212 @@
+// MIT License
+
+// Copyright (c) The RAI Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package error
+
+import (
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ErrorResponse is an error response to write in the media type negotiated with the request.
+type ErrorResponse struct {
+	Status int
+	Code   ErrorCode
+	// Detail is the message of the problem details and of the text response.
+	Detail string
+	// Errors are the validation errors of the problem details.
+	Errors interface{}
+	// Body is the JSON response: an `ErrorResp`, or the JSON of `http.errorMessage` in `env.json`.
+	Body interface{}
+	// Template is the HTML template, outside the development environments. JSON is written if empty.
+	Template string
+	// Err is the error handled, nil for the 404/405 middleware.
+	Err error
+}
+
+// ErrorRenderer writes an error response in a media type.
+type ErrorRenderer func(c echo.Context, res *ErrorResponse) error
+
+var renderers = struct {
+	sync.RWMutex
+	types []string
+	funcs map[string]ErrorRenderer
+}{funcs: map[string]ErrorRenderer{}}
+
+func init() {
+	RegisterErrorRenderer(echo.MIMEApplicationJSON, renderJSON)
+	RegisterErrorRenderer(MIMEApplicationProblemJSON, renderProblem)
+	RegisterErrorRenderer(echo.MIMETextHTML, renderHTML)
+	RegisterErrorRenderer(echo.MIMETextPlain, renderText)
+}
+
+// RegisterErrorRenderer adds, or replaces, the renderer of a media type, e.g. `application/xml`. The error
+// responses are written in the media type the `Accept` header of the request prefers, see `NegotiateErrorType`.
+func RegisterErrorRenderer(mediaType string, render ErrorRenderer) {
+	mediaType = strings.ToLower(mediaType)
+
+	renderers.Lock()
+	defer renderers.Unlock()
+	if _, ok := renderers.funcs[mediaType]; !ok {
+		renderers.types = append(renderers.types, mediaType)
+	}
+	renderers.funcs[mediaType] = render
+}
+
+// WriteErrorResponse writes the error response in the media type negotiated with the request.
+func WriteErrorResponse(c echo.Context, res *ErrorResponse) error {
+	mediaType := NegotiateErrorType(c)
+
+	renderers.RLock()
+	render := renderers.funcs[mediaType]
+	renderers.RUnlock()
+
+	return render(c, res)
+}
+
+// NegotiateErrorType returns the media type of the error responses to the request: the one its `Accept`
+// header prefers among the ones of the renderers. Without a preference, e.g. `*/*`, it's the `Content-Type`
+// of the request if there is a renderer of it, else JSON.
+func NegotiateErrorType(c echo.Context) string {
+	renderers.RLock()
+	types := renderers.types
+	renderers.RUnlock()
+
+	if mediaType := negotiate(c.Request().Header.Get(echo.HeaderAccept), types); mediaType != "" {
+		return mediaType
+	}
+
+	if contentType, _, err := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType)); err == nil && slices.Contains(types, contentType) {
+		return contentType
+	}
+
+	return echo.MIMEApplicationJSON
+}
+
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// negotiate returns the type the accept header prefers among the types, or "" if it prefers none.
+func negotiate(accept string, types []string) string {
+	if accept == "" {
+		return ""
+	}
+
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+		}
+	}
+
+	// The highest quality first, then the most specific range.
+	slices.SortStableFunc(ranges, func(a, b acceptRange) int {
+		if a.q != b.q {
+			if a.q > b.q {
+				return -1
+			}
+			return 1
+		}
+		return specificity(b.mediaType) - specificity(a.mediaType)
+	})
+
+	for _, r := range ranges {
+		if r.mediaType == "*/*" {
+			return ""
+		}
+		for _, t := range types {
+			if t == r.mediaType || strings.HasSuffix(r.mediaType, "/*") && strings.HasPrefix(t, strings.TrimSuffix(r.mediaType, "*")) {
+				return t
+			}
+		}
+	}
+
+	return ""
+}
+
+func specificity(mediaType string) int {
+	switch {
+	case mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(mediaType, "/*"):
+		return 1
+	default:
+		return 2
+	}
+}
+
+func renderJSON(c echo.Context, res *ErrorResponse) error {
+	if ProblemDetailsEnabled(c) {
+		return renderProblem(c, res)
+	}
+	if res.Body != nil {
+		return c.JSON(res.Status, res.Body)
+	}
+	return c.JSON(res.Status, ErrorResp{ErrorCode: res.Code, ErrorMsg: res.Detail})
+}
+
+func renderProblem(c echo.Context, res *ErrorResponse) error {
+	p := NewProblem(c, res.Status, res.Code, res.Detail)
+	p.Errors = res.Errors
+	return WriteProblem(c, p)
+}
+
+func renderHTML(c echo.Context, res *ErrorResponse) error {
+	if res.Err != nil && DevEnvironment() {
+		return renderDevPage(c, res)
+	}
+	if res.Template == "" {
+		return renderJSON(c, res)
+	}
+
+	// The stack traces are only shown by the developer error page, see `DevEnvironment`.
+	return c.Render(res.Status, res.Template, echo.Map{"stacktrace": nil})
+}
+
+func renderText(c echo.Context, res *ErrorResponse) error {
+	text := http.StatusText(res.Status)
+	if res.Code != "" {
+		text += " (" + string(res.Code) + ")"
+	}
+	if res.Detail != "" && res.Detail != http.StatusText(res.Status) {
+		text += ": " + res.Detail
+	}
+	return c.String(res.Status, text+"\n")
+}
@@ -0,0 +1,150 @@
+// MIT License
+
+// Copyright (c) The RAI Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package error
+
+import (
+	"encoding/xml"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/retail-ai-inc/bean/v2/echoview"
+	"github.com/retail-ai-inc/bean/v2/goview"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	types := []string{echo.MIMEApplicationJSON, MIMEApplicationProblemJSON, echo.MIMETextHTML, echo.MIMETextPlain}
+
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", ""},
+		{"*/*", ""},
+		{"application/json", echo.MIMEApplicationJSON},
+		{"application/problem+json, application/json;q=0.5", MIMEApplicationProblemJSON},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", echo.MIMETextHTML},
+		{"text/*", echo.MIMETextHTML},
+		{"text/plain;q=0.9, text/*;q=0.9", echo.MIMETextPlain},
+		{"application/xml, */*;q=0.1", ""},
+		{"application/json;q=0, text/plain", echo.MIMETextPlain},
+		{"image/png", ""},
+		{"not a type, application/json", echo.MIMEApplicationJSON},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, negotiate(tt.accept, types), tt.accept)
+	}
+}
+
+func serveNegotiated(handle ErrorHandlerFunc, err error, accept, contentType string) *httptest.ResponseRecorder {
+	e := echo.New()
+	e.Renderer = echoview.New(goview.Config{
+		Root:         TEST_VIEWS_ROOT,
+		Extension:    ".html",
+		Master:       "templates/master",
+		Partials:     []string{},
+		Funcs:        make(template.FuncMap),
+		DisableCache: true,
+		Delims:       goview.Delims{Left: "{{", Right: "}}"},
+	})
+	e.HTTPErrorHandler = func(err error, c echo.Context) { _, _ = handle(err, c) }
+	e.GET("/", func(c echo.Context) error { return err })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if accept != "" {
+		req.Header.Set(echo.HeaderAccept, accept)
+	}
+	if contentType != "" {
+		req.Header.Set(echo.HeaderContentType, contentType)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestErrorHandlers_Negotiation(t *testing.T) {
+	boom := errors.New("boom")
+
+	// A client without any preference gets JSON, not an HTML page.
+	rec := serveNegotiated(DefaultErrorHandlerFunc, boom, "", "")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, echo.MIMEApplicationJSON, rec.Header().Get(echo.HeaderContentType))
+	assert.JSONEq(t, `{"errorCode":"100004","errorMsg":"Internal Server Error","errors":null}`, rec.Body.String())
+
+	// The text responses don't show the internal error either.
+	rec = serveNegotiated(DefaultErrorHandlerFunc, boom, "text/plain", "")
+	assert.Equal(t, "Internal Server Error (100004)\n", rec.Body.String())
+
+	// Unless a message is configured.
+	viper.Set("http.errorMessage", map[string]interface{}{
+		"default": map[string]interface{}{
+			"json": []interface{}{map[string]interface{}{"key": "errorMsg", "value": "something went wrong"}},
+		},
+	})
+	rec = serveNegotiated(DefaultErrorHandlerFunc, boom, "text/plain", "")
+	viper.Set("http.errorMessage", nil)
+	assert.Equal(t, "Internal Server Error (100004): something went wrong\n", rec.Body.String())
+
+	rec = serveNegotiated(DefaultErrorHandlerFunc, boom, "text/html,*/*;q=0.8", "")
+	assert.Equal(t, echo.MIMETextHTMLCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+
+	// Falls back to the Content-Type.
+	rec = serveNegotiated(HTTPErrorHandlerFunc, echo.ErrNotFound, "*/*", echo.MIMETextHTML)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, echo.MIMETextHTMLCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+
+	rec = serveNegotiated(HTTPErrorHandlerFunc, echo.NewHTTPError(http.StatusGatewayTimeout, "upstream"), "text/plain", "")
+	assert.Equal(t, echo.MIMETextPlainCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, "Gateway Timeout (100099): upstream\n", rec.Body.String())
+
+	rec = serveNegotiated(APIErrorHandlerFunc, NewAPIError(http.StatusConflict, "T00201", errors.New("already paid")), "application/problem+json", "")
+	assert.Equal(t, MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+	assert.Contains(t, rec.Body.String(), `"detail":"already paid"`)
+
+	// The API errors have no HTML page.
+	rec = serveNegotiated(APIErrorHandlerFunc, NewAPIError(http.StatusConflict, "T00201", errors.New("already paid")), "text/html", "")
+	assert.Equal(t, echo.MIMEApplicationJSON, rec.Header().Get(echo.HeaderContentType))
+}
+
+func TestRegisterErrorRenderer(t *testing.T) {
+	type xmlError struct {
+		XMLName xml.Name  `xml:"error"`
+		Code    ErrorCode `xml:"code"`
+		Message string    `xml:"message"`
+	}
+	RegisterErrorRenderer(echo.MIMEApplicationXML, func(c echo.Context, res *ErrorResponse) error {
+		return c.XML(res.Status, xmlError{Code: res.Code, Message: res.Detail})
+	})
+
+	rec := serveNegotiated(DefaultErrorHandlerFunc, errors.New("boom"), "application/xml", "")
+	assert.Equal(t, echo.MIMEApplicationXMLCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+	assert.Contains(t, rec.Body.String(), "<error><code>100004</code><message>Internal Server Error</message></error>")
+
+	rec = serveNegotiated(DefaultErrorHandlerFunc, errors.New("boom"), "", echo.MIMEApplicationXML)
+	assert.Equal(t, echo.MIMEApplicationXMLCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+}
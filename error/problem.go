@@ -0,0 +1,94 @@
+// MIT License
+
+// Copyright (c) The RAI Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package error
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/viper"
+)
+
+// MIMEApplicationProblemJSON is the content type of the problem details of RFC 7807.
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// problemDetailsKey is the key of the echo context value set by `UseProblemDetails`.
+const problemDetailsKey = "bean.problemDetails"
+
+// Problem is a problem details object of RFC 7807. The `errorCode` and the validation `errors` of
+// `ErrorResp` are extension members.
+type Problem struct {
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Status    int         `json:"status"`
+	Detail    string      `json:"detail,omitempty"`
+	Instance  string      `json:"instance,omitempty"`
+	ErrorCode ErrorCode   `json:"errorCode,omitempty"`
+	Errors    interface{} `json:"errors,omitempty"`
+}
+
+// UseProblemDetails returns a middleware writing the errors of the routes it's used on, e.g. a route
+// group, as problem details if on is true, as `ErrorResp` if not; whatever `http.problemDetails.on` is.
+func UseProblemDetails(on bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(problemDetailsKey, on)
+			return next(c)
+		}
+	}
+}
+
+// ProblemDetailsEnabled reports whether the errors of the request are written as problem details, see
+// `UseProblemDetails`. Off by default.
+func ProblemDetailsEnabled(c echo.Context) bool {
+	if on, ok := c.Get(problemDetailsKey).(bool); ok {
+		return on
+	}
+	return viper.GetBool("http.problemDetails.on")
+}
+
+// NewProblem returns the problem details of an error response to the request. The type is
+// `http.problemDetails.typeBaseURL` followed by the error code if both are set, `about:blank` if not.
+func NewProblem(c echo.Context, status int, code ErrorCode, detail string) *Problem {
+	p := &Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Instance:  c.Request().URL.Path,
+		ErrorCode: code,
+	}
+	if detail != p.Title {
+		p.Detail = detail
+	}
+	if base := viper.GetString("http.problemDetails.typeBaseURL"); base != "" && code != "" {
+		p.Type = strings.TrimSuffix(base, "/") + "/" + string(code)
+	}
+	return p
+}
+
+// WriteProblem writes the problem details with the `application/problem+json` content type.
+func WriteProblem(c echo.Context, p *Problem) error {
+	c.Response().Header().Set(echo.HeaderContentType, MIMEApplicationProblemJSON)
+	return c.JSON(p.Status, p)
+}
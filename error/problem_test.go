@@ -0,0 +1,118 @@
+// MIT License
+
+// Copyright (c) The RAI Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package error
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	validatorV10 "github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/retail-ai-inc/bean/v2/internal/validator"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveProblem(t *testing.T, handle ErrorHandlerFunc, err error, middlewares ...echo.MiddlewareFunc) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+
+	e := echo.New()
+	e.HTTPErrorHandler = func(err error, c echo.Context) {
+		handled, herr := handle(err, c)
+		require.True(t, handled)
+		require.NoError(t, herr)
+	}
+	e.GET("/orders/:id", func(c echo.Context) error { return err }, middlewares...)
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/42", nil)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	return rec, body
+}
+
+func TestProblemDetails(t *testing.T) {
+	viper.Set("http.problemDetails.on", true)
+	viper.Set("http.problemDetails.typeBaseURL", "https://api.example.com/errors/")
+	t.Cleanup(func() {
+		viper.Set("http.problemDetails.on", false)
+		viper.Set("http.problemDetails.typeBaseURL", "")
+	})
+
+	rec, body := serveProblem(t, APIErrorHandlerFunc, NewAPIError(http.StatusConflict, ErrorCode("300001"), errors.New("order already paid")))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, map[string]any{
+		"type":      "https://api.example.com/errors/300001",
+		"title":     "Conflict",
+		"status":    float64(http.StatusConflict),
+		"detail":    "order already paid",
+		"instance":  "/orders/42",
+		"errorCode": "300001",
+	}, body)
+
+	_, body = serveProblem(t, ValidationErrorHandlerFunc, &validator.ValidationError{Err: validatorV10.ValidationErrors{}})
+	assert.Equal(t, "Bad Request", body["title"])
+	assert.Equal(t, string(API_DATA_VALIDATION_FAILED), body["errorCode"])
+
+	rec, body = serveProblem(t, HTTPErrorHandlerFunc, echo.NewHTTPError(http.StatusGatewayTimeout, "upstream timeout"))
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	assert.Equal(t, "upstream timeout", body["detail"])
+	assert.Equal(t, string(TIMEOUT), body["errorCode"])
+
+	rec, body = serveProblem(t, DefaultErrorHandlerFunc, errors.New("boom"))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "Internal Server Error", body["title"])
+	// The internal error is only logged.
+	assert.NotContains(t, body, "detail")
+
+	// Turned off for a route group.
+	rec, body = serveProblem(t, APIErrorHandlerFunc, NewAPIError(http.StatusConflict, ErrorCode("300001"), errors.New("order already paid")), UseProblemDetails(false))
+	assert.Equal(t, echo.MIMEApplicationJSON, rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, "300001", body["errorCode"])
+	assert.Equal(t, "order already paid", body["errorMsg"])
+}
+
+func TestUseProblemDetails(t *testing.T) {
+	rec, body := serveProblem(t, HTTPErrorHandlerFunc, echo.NewHTTPError(http.StatusNotFound), UseProblemDetails(true))
+	assert.Equal(t, MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, map[string]any{
+		"type":      "about:blank",
+		"title":     "Not Found",
+		"status":    float64(http.StatusNotFound),
+		"instance":  "/orders/42",
+		"errorCode": string(RESOURCE_NOT_FOUND),
+	}, body)
+
+	// The current format stays the default.
+	rec, body = serveProblem(t, HTTPErrorHandlerFunc, echo.NewHTTPError(http.StatusNotFound))
+	assert.Equal(t, echo.MIMEApplicationJSON, rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, string(RESOURCE_NOT_FOUND), body["errorCode"])
+}
@@ -0,0 +1,203 @@
+// MIT License
+
+// Copyright (c) The RAI Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package error
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/retail-ai-inc/bean/v2/stacktrace"
+)
+
+// CodeDef declares an error code: the response and the handling of its errors.
+type CodeDef struct {
+	Code ErrorCode `json:"code"`
+	// Status is the HTTP status of the responses. Default 500.
+	Status int `json:"status"`
+	// Message is the format of the message of the errors, with the arguments of `NewAPIErrorFromCode`.
+	// Default the status text.
+	Message string `json:"message"`
+	// Severity is the level of the Sentry events: `DEBUG`, `INFO`, `WARNING`, `ERROR` (default) or `CRITICAL`.
+	Severity string `json:"severity"`
+	// Ignorable errors aren't sent to Sentry.
+	Ignorable bool `json:"ignorable"`
+	// Doc documents the code for the clients of the API.
+	Doc string `json:"doc,omitempty"`
+}
+
+var registry = struct {
+	sync.RWMutex
+	defs map[ErrorCode]CodeDef
+}{defs: map[ErrorCode]CodeDef{}}
+
+func init() {
+	MustRegisterCodes(
+		CodeDef{Code: PROBLEM_PARSING_JSON, Status: http.StatusBadRequest, Severity: "WARNING", Doc: "The body of the request isn't valid JSON."},
+		CodeDef{Code: UNAUTHORIZED_ACCESS, Status: http.StatusUnauthorized, Severity: "WARNING", Doc: "The request lacks valid credentials."},
+		CodeDef{Code: RESOURCE_NOT_FOUND, Status: http.StatusNotFound, Severity: "INFO", Doc: "The route or the resource doesn't exist."},
+		CodeDef{Code: INTERNAL_SERVER_ERROR, Status: http.StatusInternalServerError, Doc: "An unexpected error happened."},
+		CodeDef{Code: REQUEST_ENTITY_TOO_LARGE, Status: http.StatusRequestEntityTooLarge, Severity: "WARNING", Doc: "The body of the request is over `http.bodyLimit`."},
+		CodeDef{Code: METHOD_NOT_ALLOWED, Status: http.StatusMethodNotAllowed, Severity: "INFO", Doc: "The route doesn't accept the method."},
+		CodeDef{Code: SERVICE_DOWN_FOR_MAINTENANCE, Status: http.StatusServiceUnavailable, Severity: "WARNING", Doc: "The service is down for maintenance."},
+		CodeDef{Code: TOO_MANY_REQUESTS, Status: http.StatusTooManyRequests, Severity: "WARNING", Doc: "The client sent too many requests."},
+		CodeDef{Code: RESOURCE_CONFLICT, Status: http.StatusConflict, Severity: "WARNING", Doc: "The resource already exists, e.g. a duplicate key."},
+		CodeDef{Code: UNKNOWN_ERROR_CODE, Status: http.StatusInternalServerError, Doc: "The error code isn't registered."},
+		CodeDef{Code: TIMEOUT, Status: http.StatusGatewayTimeout, Doc: "The request or an upstream server timed out."},
+		CodeDef{Code: API_DATA_VALIDATION_FAILED, Status: http.StatusBadRequest, Severity: "INFO", Doc: "The parameters of the request are invalid, see `errors`."},
+	)
+}
+
+// RegisterCodes adds the codes to the registry. No code is added if one is already registered, or
+// declared twice.
+func RegisterCodes(defs ...CodeDef) error {
+	registry.Lock()
+	defer registry.Unlock()
+
+	seen := make(map[ErrorCode]bool, len(defs))
+	for i, def := range defs {
+		if def.Code == "" {
+			return fmt.Errorf("error code %d: empty code", i)
+		}
+		if _, ok := registry.defs[def.Code]; ok || seen[def.Code] {
+			return fmt.Errorf("error code %s: duplicate code", def.Code)
+		}
+		if def.Status != 0 && (def.Status < 400 || def.Status > 599) {
+			return fmt.Errorf("error code %s: status %d is not an error status", def.Code, def.Status)
+		}
+		switch strings.ToUpper(def.Severity) {
+		case "", "DEBUG", "INFO", "WARNING", "ERROR", "CRITICAL":
+		default:
+			return fmt.Errorf("error code %s: unknown severity %q", def.Code, def.Severity)
+		}
+		seen[def.Code] = true
+	}
+
+	for _, def := range defs {
+		if def.Status == 0 {
+			def.Status = http.StatusInternalServerError
+		}
+		if def.Severity == "" {
+			def.Severity = "ERROR"
+		}
+		def.Severity = strings.ToUpper(def.Severity)
+		registry.defs[def.Code] = def
+	}
+
+	return nil
+}
+
+// MustRegisterCodes is `RegisterCodes` panicking on error, to fail at startup when called from `init`.
+func MustRegisterCodes(defs ...CodeDef) {
+	if err := RegisterCodes(defs...); err != nil {
+		panic(err)
+	}
+}
+
+// LookupCode returns the declaration of the code.
+func LookupCode(code ErrorCode) (CodeDef, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	def, ok := registry.defs[code]
+	return def, ok
+}
+
+// Codes returns the declarations of all the codes, sorted by code.
+func Codes() []CodeDef {
+	registry.RLock()
+	defs := make([]CodeDef, 0, len(registry.defs))
+	for _, def := range registry.defs {
+		defs = append(defs, def)
+	}
+	registry.RUnlock()
+
+	slices.SortFunc(defs, func(a, b CodeDef) int { return strings.Compare(string(a.Code), string(b.Code)) })
+	return defs
+}
+
+// NewAPIErrorFromCode returns the error of a registered code, with its status and Sentry ignorability.
+// The arguments format the message of the code; an error among them is wrapped with the `%w` verb.
+// The errors of an unknown code are 500 `UNKNOWN_ERROR_CODE` errors.
+func NewAPIErrorFromCode(code ErrorCode, args ...any) error {
+	def, ok := LookupCode(code)
+	if !ok {
+		return &APIError{
+			HTTPStatusCode: http.StatusInternalServerError,
+			GlobalErrCode:  UNKNOWN_ERROR_CODE,
+			Err:            fmt.Errorf("unknown error code %s", code),
+			Stack:          stacktrace.Callers(),
+		}
+	}
+
+	msg := def.Message
+	if msg == "" {
+		msg = http.StatusText(def.Status)
+	}
+
+	var err error
+	if len(args) > 0 {
+		err = fmt.Errorf(msg, args...)
+	} else {
+		err = fmt.Errorf("%s", msg)
+	}
+
+	return &APIError{
+		HTTPStatusCode: def.Status,
+		GlobalErrCode:  code,
+		Err:            err,
+		Ignorable:      def.Ignorable,
+		Stack:          stacktrace.Callers(),
+	}
+}
+
+// ExportCodesJSON writes the declarations of all the codes as a JSON array.
+func ExportCodesJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(Codes())
+}
+
+// ExportCodesMarkdown writes the declarations of all the codes as a Markdown table.
+func ExportCodesMarkdown(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("| Code | Status | Message | Severity | Ignorable | Description |\n")
+	b.WriteString("|---|---|---|---|---|---|\n")
+	for _, def := range Codes() {
+		msg := def.Message
+		if msg == "" {
+			msg = http.StatusText(def.Status)
+		}
+		fmt.Fprintf(&b, "| `%s` | %d %s | %s | %s | %t | %s |\n",
+			def.Code, def.Status, http.StatusText(def.Status), markdownCell(msg), def.Severity, def.Ignorable, markdownCell(def.Doc))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
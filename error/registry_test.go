@@ -0,0 +1,103 @@
+// MIT License
+
+// Copyright (c) The RAI Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package error
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterCodes(t *testing.T) {
+	require.NoError(t, RegisterCodes(
+		CodeDef{Code: "T00001", Status: http.StatusNotFound, Message: "order %s not found", Severity: "warning", Ignorable: true, Doc: "No order has the id."},
+		CodeDef{Code: "T00002"},
+	))
+
+	def, ok := LookupCode("T00001")
+	require.True(t, ok)
+	assert.Equal(t, "WARNING", def.Severity)
+
+	def, ok = LookupCode("T00002")
+	require.True(t, ok)
+	assert.Equal(t, http.StatusInternalServerError, def.Status)
+	assert.Equal(t, "ERROR", def.Severity)
+
+	assert.EqualError(t, RegisterCodes(CodeDef{Code: "T00003"}, CodeDef{Code: "T00001"}), "error code T00001: duplicate code")
+	_, ok = LookupCode("T00003")
+	assert.False(t, ok, "nothing is registered on error")
+
+	assert.EqualError(t, RegisterCodes(CodeDef{Code: "T00004"}, CodeDef{Code: "T00004"}), "error code T00004: duplicate code")
+	assert.EqualError(t, RegisterCodes(CodeDef{Code: RESOURCE_NOT_FOUND}), "error code 100003: duplicate code")
+	assert.EqualError(t, RegisterCodes(CodeDef{Code: "T00005", Status: http.StatusOK}), "error code T00005: status 200 is not an error status")
+	assert.EqualError(t, RegisterCodes(CodeDef{Code: "T00005", Severity: "LOUD"}), `error code T00005: unknown severity "LOUD"`)
+	assert.Panics(t, func() { MustRegisterCodes(CodeDef{Code: "T00001"}) })
+}
+
+func TestNewAPIErrorFromCode(t *testing.T) {
+	MustRegisterCodes(
+		CodeDef{Code: "T00101", Status: http.StatusConflict, Message: "order %s already paid: %w", Ignorable: true},
+		CodeDef{Code: "T00102", Status: http.StatusServiceUnavailable},
+	)
+
+	cause := errors.New("duplicate key")
+	err := NewAPIErrorFromCode("T00101", "42", cause)
+	var ae *APIError
+	require.ErrorAs(t, err, &ae)
+	assert.Equal(t, http.StatusConflict, ae.HTTPStatusCode)
+	assert.Equal(t, ErrorCode("T00101"), ae.GlobalErrCode)
+	assert.True(t, ae.Ignorable)
+	assert.Equal(t, "order 42 already paid: duplicate key", err.Error())
+	assert.ErrorIs(t, err, cause)
+	require.NotEmpty(t, *ae.Stack)
+	assert.Contains(t, Fingerprint(err, 1), "TestNewAPIErrorFromCode", "the stack starts at the caller")
+
+	err = NewAPIErrorFromCode("T00102")
+	assert.Equal(t, "Service Unavailable", err.Error())
+
+	err = NewAPIErrorFromCode("T00199")
+	require.ErrorAs(t, err, &ae)
+	assert.Equal(t, http.StatusInternalServerError, ae.HTTPStatusCode)
+	assert.Equal(t, UNKNOWN_ERROR_CODE, ae.GlobalErrCode)
+	assert.Equal(t, "unknown error code T00199", err.Error())
+}
+
+func TestExportCodes(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, ExportCodesJSON(&buf))
+	var defs []CodeDef
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &defs))
+	require.NotEmpty(t, defs)
+	assert.Equal(t, PROBLEM_PARSING_JSON, defs[0].Code, "sorted by code")
+	assert.Contains(t, defs, CodeDef{Code: TIMEOUT, Status: http.StatusGatewayTimeout, Severity: "ERROR", Doc: "The request or an upstream server timed out."})
+
+	buf.Reset()
+	require.NoError(t, ExportCodesMarkdown(&buf))
+	assert.Contains(t, buf.String(), "| Code | Status | Message | Severity | Ignorable | Description |\n|---|---|---|---|---|---|\n")
+	assert.Contains(t, buf.String(), "| `100003` | 404 Not Found | Not Found | INFO | false | The route or the resource doesn't exist. |\n")
+}
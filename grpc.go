@@ -0,0 +1,201 @@
+// MIT License
+
+// Copyright (c) The RAI Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bean
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/retail-ai-inc/bean/v2/internal/interceptor"
+	blog "github.com/retail-ai-inc/bean/v2/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type grpcOptions struct {
+	unary    []grpc.UnaryServerInterceptor
+	stream   []grpc.StreamServerInterceptor
+	options  []grpc.ServerOption
+	services []grpcService
+}
+
+type grpcService struct {
+	desc *grpc.ServiceDesc
+	impl any
+}
+
+// UseGRPCInterceptors appends unary interceptors after the default chain
+// (metadata, tracing, access log, recovery, deadline and error mapping).
+func (b *Bean) UseGRPCInterceptors(interceptors ...grpc.UnaryServerInterceptor) {
+	b.grpc.unary = append(b.grpc.unary, interceptors...)
+}
+
+// UseGRPCStreamInterceptors appends stream interceptors after the default chain.
+func (b *Bean) UseGRPCStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) {
+	b.grpc.stream = append(b.grpc.stream, interceptors...)
+}
+
+// UseGRPCServerOptions adds options to the gRPC server, e.g. credentials or keepalive parameters.
+func (b *Bean) UseGRPCServerOptions(opts ...grpc.ServerOption) {
+	b.grpc.options = append(b.grpc.options, opts...)
+}
+
+// RegisterService registers a gRPC service which is served once `ServeAt` is called with `grpc.on`.
+// `Bean` implements `grpc.ServiceRegistrar`, so the generated `RegisterXxxServer(b, impl)` functions can be used.
+func (b *Bean) RegisterService(desc *grpc.ServiceDesc, impl any) {
+	if b.GRPC != nil {
+		b.GRPC.RegisterService(desc, impl)
+		return
+	}
+	b.grpc.services = append(b.grpc.services, grpcService{desc: desc, impl: impl})
+}
+
+func (b *Bean) newGRPCServer() *grpc.Server {
+	cfg := interceptor.Config{
+		Skipper:       isGRPCHealthMethod,
+		BodyDump:      b.Config.AccessLog.BodyDump,
+		MetadataParam: b.Config.AccessLog.ReqHeaderParam,
+		Timeout:       b.Config.GRPC.Timeout,
+	}
+	if b.Config.AccessLog.On {
+		cfg.Logger = blog.Logger()
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(append(interceptor.DefaultUnary(cfg), b.grpc.unary...)...),
+		grpc.ChainStreamInterceptor(append(interceptor.DefaultStream(cfg), b.grpc.stream...)...),
+	}
+	if b.Config.GRPC.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(b.Config.GRPC.MaxRecvMsgSize))
+	}
+	if b.Config.GRPC.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(b.Config.GRPC.MaxSendMsgSize))
+	}
+	opts = append(opts, b.grpc.options...)
+
+	s := grpc.NewServer(opts...)
+	for _, svc := range b.grpc.services {
+		s.RegisterService(svc.desc, svc.impl)
+	}
+	b.grpc.services = nil
+
+	return s
+}
+
+// serveGRPCHealth registers the standard gRPC health service and keeps its statuses up to date
+// with the registered health checks. The returned function sets every service to `NOT_SERVING`.
+func (b *Bean) serveGRPCHealth(s *grpc.Server) func() error {
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(s, hs)
+
+	interval := b.Config.GRPC.HealthCheckInterval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	update := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		defer cancel()
+
+		status := healthpb.HealthCheckResponse_SERVING
+		if err := b.CheckHealth(ctx); err != nil {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+			b.Echo.Logger.Error("grpc health check failed: ", err)
+		}
+
+		hs.SetServingStatus("", status)
+		for name := range s.GetServiceInfo() {
+			if name != healthpb.Health_ServiceDesc.ServiceName {
+				hs.SetServingStatus(name, status)
+			}
+		}
+	}
+	update()
+
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				update()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() error {
+		close(done)
+		hs.Shutdown()
+		return nil
+	}
+}
+
+// stopGRPC stops the gRPC server. A multiplexed server is drained by `http.Server.Shutdown`
+// and stopped right away, as `GracefulStop` does not support connections served through `ServeHTTP`.
+func (b *Bean) stopGRPC() error {
+	if b.Config.GRPC.Multiplex {
+		b.GRPC.Stop()
+		return nil
+	}
+
+	timeout := b.Config.GRPC.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	done := make(chan struct{})
+	go func() {
+		b.GRPC.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		b.GRPC.Stop()
+		return errors.New("failed to gracefully shutdown grpc server: timeout")
+	}
+}
+
+// multiplexGRPC routes the HTTP/2 gRPC requests to the gRPC server and anything else to `next`.
+func (b *Bean) multiplexGRPC(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			b.GRPC.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isGRPCHealthMethod(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/"+healthpb.Health_ServiceDesc.ServiceName+"/")
+}
@@ -0,0 +1,100 @@
+// MIT License
+
+// Copyright (c) The RAI Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package bean
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// HealthCheck reports whether a dependency of the service is usable.
+type HealthCheck func(ctx context.Context) error
+
+type healthChecks struct {
+	sync.RWMutex
+	checks map[string]HealthCheck
+}
+
+// AddHealthCheck registers a named dependency check. `InitDB` registers the master databases,
+// and the gRPC health service reports `NOT_SERVING` as long as any registered check fails.
+// A check registered under an existing name replaces it.
+func (b *Bean) AddHealthCheck(name string, check HealthCheck) {
+	b.health.Lock()
+	defer b.health.Unlock()
+	if b.health.checks == nil {
+		b.health.checks = map[string]HealthCheck{}
+	}
+	b.health.checks[name] = check
+}
+
+// CheckHealth runs all the registered checks and joins their errors.
+// Use it in a HTTP health endpoint to report the same status as the gRPC health service.
+func (b *Bean) CheckHealth(ctx context.Context) error {
+	b.health.RLock()
+	names := make([]string, 0, len(b.health.checks))
+	checks := make(map[string]HealthCheck, len(b.health.checks))
+	for name, check := range b.health.checks {
+		names = append(names, name)
+		checks[name] = check
+	}
+	b.health.RUnlock()
+
+	sort.Strings(names)
+
+	var err error
+	for _, name := range names {
+		if cErr := checks[name](ctx); cErr != nil {
+			err = errors.Join(err, fmt.Errorf("%s: %w", name, cErr))
+		}
+	}
+
+	return err
+}
+
+// addDBHealthChecks registers a check for every master database connection.
+func (b *Bean) addDBHealthChecks(deps *DBDeps) {
+	if deps.MasterMySQLDB != nil {
+		b.AddHealthCheck("mysql", func(ctx context.Context) error {
+			db, err := deps.MasterMySQLDB.DB()
+			if err != nil {
+				return err
+			}
+			return db.PingContext(ctx)
+		})
+	}
+
+	if deps.MasterMongoDB != nil {
+		b.AddHealthCheck("mongo", func(ctx context.Context) error {
+			return deps.MasterMongoDB.Ping(ctx, nil)
+		})
+	}
+
+	if deps.MasterRedisDB != nil && deps.MasterRedisDB.Primary != nil {
+		b.AddHealthCheck("redis", func(ctx context.Context) error {
+			return deps.MasterRedisDB.Primary.Ping(ctx).Err()
+		})
+	}
+}
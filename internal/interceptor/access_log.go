@@ -0,0 +1,158 @@
+package interceptor
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	bctx "github.com/retail-ai-inc/bean/v2/context"
+)
+
+// UnaryAccessLog writes one `ACCESS` entry per call through `Config.Logger`, with the request and
+// response messages when `Config.BodyDump` is on. Server side failures are written as errors.
+func UnaryAccessLog(cfg Config) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if cfg.Logger == nil || (cfg.Skipper != nil && cfg.Skipper(info.FullMethod)) {
+			return handler(ctx, req)
+		}
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		fields := accessFields(cfg, ctx, info.FullMethod, start, err)
+		if cfg.BodyDump {
+			if body := marshalMessage(req); body != "" {
+				fields["request_body"] = body
+			}
+			if body := marshalMessage(resp); body != "" {
+				fields["response_body"] = body
+			}
+		}
+		writeAccessLog(cfg, ctx, fields, err)
+
+		return resp, err
+	}
+}
+
+// StreamAccessLog is the stream version of `UnaryAccessLog`. The messages are counted
+// instead of dumped, as a stream can carry any number of them.
+func StreamAccessLog(cfg Config) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if cfg.Logger == nil || (cfg.Skipper != nil && cfg.Skipper(info.FullMethod)) {
+			return handler(srv, ss)
+		}
+
+		start := time.Now()
+		cs := &countingStream{ServerStream: ss}
+		err := handler(srv, cs)
+
+		fields := accessFields(cfg, ss.Context(), info.FullMethod, start, err)
+		cs.mu.Lock()
+		fields["messages_in"] = cs.received
+		fields["messages_out"] = cs.sent
+		cs.mu.Unlock()
+		writeAccessLog(cfg, ss.Context(), fields, err)
+
+		return err
+	}
+}
+
+func accessFields(cfg Config, ctx context.Context, fullMethod string, start time.Time, err error) map[string]any {
+	stop := time.Now()
+	code := status.Code(err)
+
+	fields := map[string]any{
+		"protocol":      "grpc",
+		"method":        fullMethod,
+		"status":        code.String(),
+		"grpc_code":     int(code),
+		"latency":       int64(stop.Sub(start)),
+		"latency_human": stop.Sub(start).String(),
+	}
+
+	if id, ok := bctx.GetRequestID(ctx); ok {
+		fields["id"] = id
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		fields["remote_ip"] = p.Addr.String()
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	if ua := firstValue(md, "user-agent"); ua != "" {
+		fields["user_agent"] = ua
+	}
+
+	if len(cfg.MetadataParam) > 0 {
+		reqHeader := make(map[string]any)
+		for _, k := range cfg.MetadataParam {
+			if v := firstValue(md, strings.ToLower(k)); v != "" {
+				reqHeader[k] = v
+			}
+		}
+		fields["request_header"] = reqHeader
+	}
+
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+
+	return fields
+}
+
+func writeAccessLog(cfg Config, ctx context.Context, fields map[string]any, err error) {
+	if isServerError(status.Code(err)) {
+		cfg.Logger.TraceError(ctx, "ACCESS", fields)
+		return
+	}
+	cfg.Logger.TraceInfo(ctx, "ACCESS", fields)
+}
+
+func marshalMessage(m any) string {
+	msg, ok := m.(proto.Message)
+	if !ok || msg == nil {
+		return ""
+	}
+
+	b, err := protojson.Marshal(msg)
+	if err != nil {
+		return ""
+	}
+
+	return string(b)
+}
+
+// countingStream counts the messages going through a server stream.
+type countingStream struct {
+	grpc.ServerStream
+	mu       sync.Mutex
+	received int
+	sent     int
+}
+
+func (s *countingStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.mu.Lock()
+		s.received++
+		s.mu.Unlock()
+	}
+	return err
+}
+
+func (s *countingStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.mu.Lock()
+		s.sent++
+		s.mu.Unlock()
+	}
+	return err
+}
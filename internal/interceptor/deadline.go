@@ -0,0 +1,53 @@
+package interceptor
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryDeadline rejects calls whose deadline has already passed and applies `timeout`
+// to calls which don't carry a deadline, same as the HTTP `ContextTimeout` middleware.
+func UnaryDeadline(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, cancel, err := withDeadline(ctx, timeout)
+		if err != nil {
+			return nil, err
+		}
+		defer cancel()
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamDeadline is the stream version of `UnaryDeadline`.
+func StreamDeadline(timeout time.Duration) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, cancel, err := withDeadline(ss.Context(), timeout)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+
+		return handler(srv, withContext(ss, ctx))
+	}
+}
+
+func withDeadline(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		if time.Until(deadline) <= 0 {
+			return ctx, func() {}, status.Error(codes.DeadlineExceeded, "deadline exceeded before the call started")
+		}
+		return ctx, func() {}, nil
+	}
+
+	if timeout <= 0 {
+		return ctx, func() {}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, nil
+}
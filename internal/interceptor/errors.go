@@ -0,0 +1,99 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	berror "github.com/retail-ai-inc/bean/v2/error"
+	"github.com/retail-ai-inc/bean/v2/internal/validator"
+	"github.com/retail-ai-inc/bean/v2/log"
+	"github.com/retail-ai-inc/bean/v2/trace"
+)
+
+// UnaryErrorMapping converts the errors returned by handlers into gRPC statuses the same way
+// `DefaultHTTPErrorHandler` converts them into HTTP responses, including Sentry capture.
+func UnaryErrorMapping() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		return resp, toStatusError(ctx, err)
+	}
+}
+
+// StreamErrorMapping is the stream version of `UnaryErrorMapping`.
+func StreamErrorMapping() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return toStatusError(ss.Context(), handler(srv, ss))
+	}
+}
+
+func toStatusError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var ae *berror.APIError
+	if errors.As(err, &ae) {
+		// Same threshold as `APIErrorHandlerFunc`, to not bloat Sentry with minor errors.
+		if ae.HTTPStatusCode >= 404 && viper.GetBool("sentry.on") {
+			if !ae.Ignorable {
+				trace.SentryCaptureException(ctx, ae)
+			}
+		} else if l := log.Logger(); l != nil {
+			l.Errorf("%+v", ae)
+		}
+		return ae.GRPCStatus().Err()
+	}
+
+	// Already a gRPC status, built by the handler on purpose.
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var ve *validator.ValidationError
+	if errors.As(err, &ve) {
+		st := status.New(codes.InvalidArgument, string(berror.API_DATA_VALIDATION_FAILED))
+		if l := log.Logger(); l != nil {
+			l.Error(ve)
+		}
+		return st.Err()
+	}
+
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		code := berror.GRPCCode(he.Code)
+		if he.Code < http.StatusInternalServerError {
+			return status.Error(code, fmt.Sprint(he.Message))
+		}
+		// Like the other errors below, `he.Internal` only goes to Sentry or to the logs.
+		if viper.GetBool("sentry.on") {
+			trace.SentryCaptureException(ctx, he)
+		} else if l := log.Logger(); l != nil && berror.LogDedup.Allow(he) {
+			l.Errorf("%+v", he)
+		}
+		return status.Error(code, http.StatusText(he.Code))
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, context.DeadlineExceeded.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, context.Canceled.Error())
+	}
+
+	// The error goes to Sentry, or to the logs, never to the client: it may hold SQL, driver errors or paths.
+	if viper.GetBool("sentry.on") {
+		trace.SentryCaptureException(ctx, err)
+	} else if l := log.Logger(); l != nil && berror.LogDedup.Allow(err) {
+		l.Errorf("%+v", err)
+	}
+
+	return status.Error(codes.Internal, http.StatusText(http.StatusInternalServerError))
+}
@@ -0,0 +1,68 @@
+// Package interceptor provides the default gRPC server interceptor chain of bean.
+// The chain mirrors the Echo middleware stack: request-ID and tenant metadata, tracing,
+// access logging, panic recovery, deadline enforcement and error to status mapping.
+package interceptor
+
+import (
+	"context"
+	"time"
+
+	"github.com/retail-ai-inc/bean/v2/log"
+	"google.golang.org/grpc"
+)
+
+// Config configures the default interceptor chain.
+type Config struct {
+	// Logger receives one access log entry per call. Access logging is off if nil.
+	Logger log.AccessLogger
+	// Skipper skips the access log of the matching full method names, e.g. health checks.
+	Skipper func(fullMethod string) bool
+	// BodyDump logs request and response messages as protojson (masked by the log pipeline).
+	BodyDump bool
+	// MetadataParam lists the incoming metadata keys written to the access log.
+	MetadataParam []string
+	// Timeout is applied to calls which don't carry a deadline. No deadline is applied if it's 0.
+	Timeout time.Duration
+}
+
+// DefaultUnary returns the default unary server interceptor chain, outermost first.
+func DefaultUnary(cfg Config) []grpc.UnaryServerInterceptor {
+	return []grpc.UnaryServerInterceptor{
+		UnaryMetadata(),
+		UnaryTracing(),
+		UnaryAccessLog(cfg),
+		UnaryRecovery(),
+		UnaryDeadline(cfg.Timeout),
+		UnaryErrorMapping(),
+	}
+}
+
+// DefaultStream returns the default stream server interceptor chain, outermost first.
+func DefaultStream(cfg Config) []grpc.StreamServerInterceptor {
+	return []grpc.StreamServerInterceptor{
+		StreamMetadata(),
+		StreamTracing(),
+		StreamAccessLog(cfg),
+		StreamRecovery(),
+		StreamDeadline(cfg.Timeout),
+		StreamErrorMapping(),
+	}
+}
+
+// serverStream overrides the context of a `grpc.ServerStream`.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func withContext(ss grpc.ServerStream, ctx context.Context) grpc.ServerStream {
+	if s, ok := ss.(*serverStream); ok {
+		s.ctx = ctx
+		return s
+	}
+	return &serverStream{ServerStream: ss, ctx: ctx}
+}
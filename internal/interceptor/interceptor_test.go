@@ -0,0 +1,175 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	bctx "github.com/retail-ai-inc/bean/v2/context"
+	berror "github.com/retail-ai-inc/bean/v2/error"
+)
+
+type recordLogger struct {
+	mu     sync.Mutex
+	infos  []map[string]any
+	errors []map[string]any
+}
+
+func (l *recordLogger) TraceInfo(_ context.Context, _ string, fields map[string]any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.infos = append(l.infos, fields)
+}
+
+func (l *recordLogger) TraceError(_ context.Context, _ string, fields map[string]any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errors = append(l.errors, fields)
+}
+
+// healthServer stands in for a user service, the behaviour is picked by the requested service name.
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+	ctx context.Context
+}
+
+func (s *healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	s.ctx = ctx
+	switch req.Service {
+	case "panic":
+		panic("boom")
+	case "api-error":
+		return nil, berror.NewAPIError(http.StatusNotFound, berror.RESOURCE_NOT_FOUND, errors.New("no such item"))
+	case "plain-error":
+		return nil, errors.New("unexpected")
+	case "http-error":
+		return nil, echo.NewHTTPError(http.StatusConflict, "already paid").SetInternal(errors.New("pq: duplicate key"))
+	case "http-server-error":
+		return nil, echo.NewHTTPError(http.StatusServiceUnavailable, "down").SetInternal(errors.New("dial tcp 10.0.0.1:5432"))
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func newTestClient(t *testing.T, cfg Config) (healthpb.HealthClient, *healthServer) {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(DefaultUnary(cfg)...),
+		grpc.ChainStreamInterceptor(DefaultStream(cfg)...),
+	)
+	srv := &healthServer{}
+	healthpb.RegisterHealthServer(s, srv)
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return healthpb.NewHealthClient(conn), srv
+}
+
+func TestUnaryChain(t *testing.T) {
+	logger := &recordLogger{}
+	client, srv := newTestClient(t, Config{
+		Logger:        logger,
+		BodyDump:      true,
+		MetadataParam: []string{"X-Tenant-Id"},
+		Timeout:       time.Minute,
+	})
+
+	t.Run("request id, tenant and deadline", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "req-1", TenantIDMetadataKey, "42")
+		var header metadata.MD
+		_, err := client.Check(ctx, &healthpb.HealthCheckRequest{}, grpc.Header(&header))
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"req-1"}, header.Get("x-request-id"))
+		reqID, _ := bctx.GetRequestID(srv.ctx)
+		assert.Equal(t, "req-1", reqID)
+		tenantID, _ := bctx.GetTenantID(srv.ctx)
+		assert.Equal(t, "42", tenantID)
+		_, ok := srv.ctx.Deadline()
+		assert.True(t, ok)
+	})
+
+	t.Run("generated request id", func(t *testing.T) {
+		var header metadata.MD
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}, grpc.Header(&header))
+		require.NoError(t, err)
+		assert.Len(t, header.Get("x-request-id"), 1)
+		assert.NotEmpty(t, header.Get("x-request-id")[0])
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "panic"})
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.Equal(t, "Internal Server Error", status.Convert(err).Message())
+	})
+
+	t.Run("api error", func(t *testing.T) {
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "api-error"})
+		st := status.Convert(err)
+		assert.Equal(t, codes.NotFound, st.Code())
+		assert.Equal(t, "no such item", st.Message())
+		require.Len(t, st.Details(), 1)
+		info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+		require.True(t, ok)
+		assert.Equal(t, string(berror.RESOURCE_NOT_FOUND), info.Reason)
+	})
+
+	t.Run("http error", func(t *testing.T) {
+		// The internal error never reaches the client.
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "http-error"})
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+		assert.Equal(t, "already paid", status.Convert(err).Message())
+
+		_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "http-server-error"})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Equal(t, "Service Unavailable", status.Convert(err).Message())
+	})
+
+	t.Run("plain error", func(t *testing.T) {
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "plain-error"})
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.Equal(t, "Internal Server Error", status.Convert(err).Message())
+	})
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+
+	require.Len(t, logger.infos, 4)
+	first := logger.infos[0]
+	assert.Equal(t, "grpc", first["protocol"])
+	assert.Equal(t, "/grpc.health.v1.Health/Check", first["method"])
+	assert.Equal(t, "OK", first["status"])
+	assert.Equal(t, "req-1", first["id"])
+	assert.Equal(t, map[string]any{"X-Tenant-Id": "42"}, first["request_header"])
+	assert.Equal(t, `{"status":"SERVING"}`, first["response_body"])
+	assert.Equal(t, "NotFound", logger.infos[2]["status"])
+	assert.Equal(t, "AlreadyExists", logger.infos[3]["status"])
+
+	// Panics, 5xx HTTP errors and unexpected errors are server side failures.
+	require.Len(t, logger.errors, 3)
+	assert.Equal(t, "Internal", logger.errors[0]["status"])
+}
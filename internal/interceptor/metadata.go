@@ -0,0 +1,58 @@
+package interceptor
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	bctx "github.com/retail-ai-inc/bean/v2/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// TenantIDMetadataKey is the incoming metadata key holding the tenant id.
+const TenantIDMetadataKey = "x-tenant-id"
+
+var requestIDMetadataKey = strings.ToLower(echo.HeaderXRequestID)
+
+// UnaryMetadata sets the request id (generated if the client didn't send one) and the tenant id
+// into the context, and echoes the request id back in the response header.
+func UnaryMetadata() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(withMetadata(ctx), req)
+	}
+}
+
+// StreamMetadata is the stream version of `UnaryMetadata`.
+func StreamMetadata() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, withContext(ss, withMetadata(ss.Context())))
+	}
+}
+
+func withMetadata(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	requestID := firstValue(md, requestIDMetadataKey)
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+	if _, ok := bctx.GetRequestID(ctx); !ok {
+		ctx = bctx.SetRequestID(ctx, requestID)
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadataKey, requestID))
+
+	if tenantID := firstValue(md, TenantIDMetadataKey); tenantID != "" {
+		ctx = bctx.SetTenantID(ctx, tenantID)
+	}
+
+	return ctx
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
@@ -0,0 +1,67 @@
+package interceptor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/getsentry/sentry-go"
+	bctx "github.com/retail-ai-inc/bean/v2/context"
+	"github.com/retail-ai-inc/bean/v2/log"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryRecovery recovers a panic in the handler, sends it to Sentry if configured and returns `codes.Internal`.
+func UnaryRecovery() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				err = recoverPanic(ctx, info.FullMethod, v)
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecovery is the stream version of `UnaryRecovery`.
+func StreamRecovery() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				err = recoverPanic(ss.Context(), info.FullMethod, v)
+			}
+		}()
+
+		return handler(srv, ss)
+	}
+}
+
+func recoverPanic(ctx context.Context, fullMethod string, v any) error {
+	if viper.GetBool("sentry.on") {
+		hub := sentry.GetHubFromContext(ctx)
+		if hub == nil {
+			hub = sentry.CurrentHub()
+		}
+		hub.Clone().RecoverWithContext(ctx, v)
+	}
+
+	msg := map[string]any{
+		"message": "Recovered panic from gRPC handler.",
+		"method":  fullMethod,
+		"cause":   fmt.Sprint(v),
+		"stack":   string(debug.Stack()),
+	}
+	if reqID, ok := bctx.GetRequestID(ctx); ok {
+		msg["request_id"] = reqID
+	}
+	if l := log.Logger(); l != nil {
+		l.Errorj(msg)
+	}
+
+	return status.Error(codes.Internal, http.StatusText(http.StatusInternalServerError))
+}
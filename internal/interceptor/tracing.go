@@ -0,0 +1,127 @@
+package interceptor
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+	bctx "github.com/retail-ai-inc/bean/v2/context"
+	"github.com/retail-ai-inc/bean/v2/internal/regex"
+	"github.com/retail-ai-inc/bean/v2/trace"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const instrumentationName = "github.com/retail-ai-inc/bean/v2/internal/interceptor"
+
+// UnaryTracing starts a server span (Sentry transaction or OpenTelemetry span, see `tracing.provider`)
+// which continues the trace sent by the client in the configured propagation formats.
+func UnaryTracing() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, finish := startServerSpan(ctx, info.FullMethod)
+		resp, err := handler(ctx, req)
+		finish(status.Code(err))
+		return resp, err
+	}
+}
+
+// StreamTracing is the stream version of `UnaryTracing`.
+func StreamTracing() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, finish := startServerSpan(ss.Context(), info.FullMethod)
+		err := handler(srv, withContext(ss, ctx))
+		finish(status.Code(err))
+		return err
+	}
+}
+
+func startServerSpan(ctx context.Context, fullMethod string) (context.Context, func(code codes.Code)) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	header := trace.MetadataToHeader(md)
+
+	// Keep the caller's trace id for log correlation, same as the HTTP `IncomingTraceContext` middleware.
+	if sc := trace.IncomingSpanContext(header); sc.IsValid() {
+		ctx = bctx.SetTraceID(ctx, sc.TraceID().String())
+		if ts := sc.TraceState().String(); ts != "" {
+			ctx = bctx.SetTraceState(ctx, ts)
+		}
+	}
+
+	if trace.IsOtel() {
+		if !trace.CurrentTracer().Enabled() || regex.SkipSampling(fullMethod) {
+			return ctx, func(codes.Code) {}
+		}
+
+		service, method := splitMethod(fullMethod)
+		ctx = trace.Propagator().Extract(ctx, trace.MetadataCarrier(md))
+		ctx, span := otel.Tracer(instrumentationName).Start(ctx, strings.TrimPrefix(fullMethod, "/"),
+			oteltrace.WithSpanKind(oteltrace.SpanKindServer),
+			oteltrace.WithAttributes(
+				attribute.String("rpc.system", "grpc"),
+				attribute.String("rpc.service", service),
+				attribute.String("rpc.method", method),
+			),
+		)
+
+		return ctx, func(code codes.Code) {
+			span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(code)))
+			if isServerError(code) {
+				span.SetStatus(otelcodes.Error, code.String())
+			}
+			span.End()
+		}
+	}
+
+	if !viper.GetBool("sentry.on") {
+		return ctx, func(codes.Code) {}
+	}
+
+	// Set a hub per call, so `trace.SentryCaptureException(ctx, err)` works in the handlers.
+	hub := sentry.GetHubFromContext(ctx)
+	if hub == nil {
+		hub = sentry.CurrentHub().Clone()
+		ctx = sentry.SetHubOnContext(ctx, hub)
+	}
+	hub.Scope().SetTag("grpc.method", fullMethod)
+
+	if !trace.CurrentTracer().Enabled() {
+		return ctx, func(codes.Code) {}
+	}
+
+	span := sentry.StartTransaction(ctx, fullMethod,
+		sentry.WithOpName("grpc.server"),
+		sentry.ContinueFromHeaders(trace.SentryTraceFromHeader(header), header.Get(sentry.SentryBaggageHeader)),
+	)
+	if regex.SkipSampling(fullMethod) {
+		span.Sampled = sentry.SampledFalse
+	}
+
+	return span.Context(), func(code codes.Code) {
+		// Sentry span statuses follow the gRPC codes, shifted by `SpanStatusUndefined`.
+		span.Status = sentry.SpanStatus(code) + sentry.SpanStatusOK
+		span.Finish()
+	}
+}
+
+// splitMethod splits `/package.Service/Method` into service and method.
+func splitMethod(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	return path.Dir(fullMethod), path.Base(fullMethod)
+}
+
+// isServerError reports whether the code is a server side failure, which is logged and traced as an error.
+func isServerError(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		return true
+	}
+	return false
+}
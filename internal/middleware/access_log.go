@@ -24,17 +24,17 @@ package middleware
 
 import (
 	"bufio"
-	"bytes"
 	"fmt"
-	"io"
 	"net"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 
 	"github.com/retail-ai-inc/bean/v2/log"
+	"github.com/retail-ai-inc/bean/v2/stream"
 )
 
 type LoggerConfig struct {
@@ -43,12 +43,36 @@ type LoggerConfig struct {
 	RequestHeader  []string
 	ResponseHeader []string
 	Logger         log.AccessLogger
+
+	// Combined writes a single `ACCESS` entry once the request is done, with the status, latency and
+	// sizes, and the bodies if `BodyDump` is on; instead of `ACCESS` before the handler and `DUMP` after.
+	// 4xx responses are logged as warnings if the logger implements `log.WarningLogger`, 5xx as errors.
+	Combined bool
+	// TenantID and UserID extract the ids written to the combined entry, empty ids are left out.
+	TenantID func(c echo.Context) string
+	UserID   func(c echo.Context) string
+
+	handlers *handlerNames
+
+	// BodyDumpMaxSize is the number of bytes of each body kept for the dump. Longer bodies are
+	// cut and marked as truncated. Default `DefaultBodyDumpMaxSize`.
+	BodyDumpMaxSize int64
+	// BodyDumpContentTypes are the content types of the bodies dumped. Default `DefaultBodyDumpContentTypes`.
+	BodyDumpContentTypes []string
+	// BodyDumpSampleRate is the fraction, between 0 and 1, of the responses below `BodyDumpAlwaysStatus` dumped.
+	BodyDumpSampleRate float64
+	// BodyDumpAlwaysStatus is the status from which every response is dumped. Default `DefaultBodyDumpAlwaysStatus`.
+	BodyDumpAlwaysStatus int
 }
 
+// bodyDumpResponseWriter writes the response through and keeps its first bytes for the dump.
 type bodyDumpResponseWriter struct {
-	io.Writer
 	http.ResponseWriter
 	Status int
+
+	config  LoggerConfig
+	body    *bodyCapture
+	checked bool
 }
 
 var DefaultLoggerConfig = LoggerConfig{
@@ -59,6 +83,16 @@ func AccessLoggerWithConfig(config LoggerConfig) echo.MiddlewareFunc {
 	if config.Skipper == nil {
 		config.Skipper = DefaultLoggerConfig.Skipper
 	}
+	if config.BodyDumpMaxSize <= 0 {
+		config.BodyDumpMaxSize = DefaultBodyDumpMaxSize
+	}
+	if len(config.BodyDumpContentTypes) == 0 {
+		config.BodyDumpContentTypes = DefaultBodyDumpContentTypes
+	}
+	if config.BodyDumpAlwaysStatus <= 0 {
+		config.BodyDumpAlwaysStatus = DefaultBodyDumpAlwaysStatus
+	}
+	config.handlers = &handlerNames{}
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) (err error) {
@@ -76,25 +110,38 @@ func AccessLoggerWithConfig(config LoggerConfig) echo.MiddlewareFunc {
 			start := time.Now()
 
 			// Access Log (before)
-			logAccess(config, c)
+			if !config.Combined {
+				logAccess(config, c)
+			}
 
-			// ---- Body Dump handling ----
-			var reqBody []byte
-			if config.BodyDump && req.Body != nil {
-				reqBody, _ = io.ReadAll(req.Body)
-				req.Body = io.NopCloser(bytes.NewBuffer(reqBody))
+			// ---- Streaming (SSE/WebSocket) handling ----
+			// Never dump a stream: log one summary entry when it closes instead.
+			if stream.IsStreamingRoute(c) {
+				stats := &stream.Stats{}
+				c.SetRequest(req.WithContext(stream.WithStats(req.Context(), stats)))
+				if err = next(c); err != nil {
+					c.Error(err)
+				}
+				logStreamSummary(config, c, stats, start, time.Now(), err)
+				return
 			}
 
-			var resBody *bytes.Buffer
+			// ---- Body Dump handling ----
+			// Both bodies are captured up to `BodyDumpMaxSize` into pooled buffers, whether the
+			// response is sampled or not: its status is only known once the handler is done.
+			var reqBody *bodyCapture
 			var writer *bodyDumpResponseWriter
 
 			if config.BodyDump {
-				resBody = new(bytes.Buffer)
-				mw := io.MultiWriter(res.Writer, resBody)
+				reqBody = captureRequestBody(req, config.BodyDumpMaxSize, config.BodyDumpContentTypes)
+				defer reqBody.release()
+
 				writer = &bodyDumpResponseWriter{
-					Writer:         mw,
 					ResponseWriter: res.Writer,
+					config:         config,
+					body:           &bodyCapture{limit: config.BodyDumpMaxSize},
 				}
+				defer writer.body.release()
 				res.Writer = writer
 			}
 
@@ -106,8 +153,15 @@ func AccessLoggerWithConfig(config LoggerConfig) echo.MiddlewareFunc {
 			stop := time.Now()
 
 			// ---- Body Dump Log (after) ----
-			if config.BodyDump {
-				logBodyDump(config, c, reqBody, resBody, writer, start, stop, err)
+			dump := config.BodyDump && sampleBodyDump(config, res.Status)
+			switch {
+			case config.Combined:
+				if !dump {
+					reqBody, writer = nil, nil
+				}
+				logCombined(config, c, reqBody, writer, start, stop, err)
+			case dump:
+				logBodyDump(config, c, reqBody, writer.body, start, stop, err)
 			}
 
 			return
@@ -129,13 +183,7 @@ func logAccess(config LoggerConfig, c echo.Context) {
 	}
 
 	if len(config.RequestHeader) > 0 {
-		reqHeader := make(map[string]any)
-		for _, h := range config.RequestHeader {
-			if v := req.Header.Get(h); v != "" {
-				reqHeader[h] = v
-			}
-		}
-		fields["request_header"] = reqHeader
+		fields["request_header"] = headerFields(req.Header, config.RequestHeader)
 	}
 
 	config.Logger.TraceInfo(
@@ -145,11 +193,12 @@ func logAccess(config LoggerConfig, c echo.Context) {
 	)
 }
 
-func logBodyDump(
+// logCombined writes the single access log entry of a request, once it's done.
+// The bodies are left out when `reqBody` and `writer` are nil.
+func logCombined(
 	config LoggerConfig,
 	c echo.Context,
-	reqBody []byte,
-	resBody *bytes.Buffer,
+	reqBody *bodyCapture,
 	writer *bodyDumpResponseWriter,
 	start, stop time.Time,
 	handlerErr error,
@@ -159,8 +208,85 @@ func logBodyDump(
 
 	fields := map[string]any{
 		"id":            req.Header.Get(echo.HeaderXRequestID),
+		"remote_ip":     c.RealIP(),
+		"host":          req.Host,
+		"method":        req.Method,
 		"uri":           req.RequestURI,
-		"status":        writer.Status,
+		"route":         c.Path(),
+		"handler":       config.handlers.get(c),
+		"user_agent":    req.UserAgent(),
+		"status":        res.Status,
+		"latency":       int64(stop.Sub(start)),
+		"latency_human": stop.Sub(start).String(),
+		"bytes_in":      req.Header.Get(echo.HeaderContentLength),
+		"bytes_out":     res.Size,
+	}
+
+	if config.TenantID != nil {
+		if id := config.TenantID(c); id != "" {
+			fields["tenant_id"] = id
+		}
+	}
+
+	if config.UserID != nil {
+		if id := config.UserID(c); id != "" {
+			fields["user_id"] = id
+		}
+	}
+
+	if handlerErr != nil {
+		fields["error"] = handlerErr.Error()
+	}
+
+	if reqBody != nil {
+		if body := reqBody.String(); body != "" {
+			fields["request_body"] = body
+		}
+	}
+
+	if writer != nil {
+		if body := writer.body.String(); body != "" {
+			fields["response_body"] = body
+		}
+	}
+
+	if len(config.RequestHeader) > 0 {
+		fields["request_header"] = headerFields(req.Header, config.RequestHeader)
+	}
+
+	if len(config.ResponseHeader) > 0 {
+		fields["response_header"] = headerFields(res.Header(), config.ResponseHeader)
+	}
+
+	// The severity follows the status class.
+	switch {
+	case res.Status >= http.StatusInternalServerError:
+		config.Logger.TraceError(req.Context(), "ACCESS", fields)
+		return
+	case res.Status >= http.StatusBadRequest:
+		if l, ok := config.Logger.(log.WarningLogger); ok {
+			l.TraceWarn(req.Context(), "ACCESS", fields)
+			return
+		}
+	}
+
+	config.Logger.TraceInfo(req.Context(), "ACCESS", fields)
+}
+
+func logBodyDump(
+	config LoggerConfig,
+	c echo.Context,
+	reqBody, resBody *bodyCapture,
+	start, stop time.Time,
+	handlerErr error,
+) {
+	req := c.Request()
+	res := c.Response()
+
+	fields := map[string]any{
+		"id":            req.Header.Get(echo.HeaderXRequestID),
+		"uri":           req.RequestURI,
+		"status":        res.Status,
 		"latency":       int64(stop.Sub(start)),
 		"latency_human": stop.Sub(start).String(),
 		"bytes_in":      req.Header.Get(echo.HeaderContentLength),
@@ -172,35 +298,23 @@ func logBodyDump(
 	}
 
 	// ---- structured request body ----
-	if len(reqBody) > 0 {
-		fields["request_body"] = string(reqBody)
+	if body := reqBody.String(); body != "" {
+		fields["request_body"] = body
 	}
 
 	// ---- structured response body ----
-	if resBody != nil && resBody.Len() > 0 {
-		fields["response_body"] = resBody.String()
+	if body := resBody.String(); body != "" {
+		fields["response_body"] = body
 	}
 
 	// ---- request headers ----
 	if len(config.RequestHeader) > 0 {
-		reqHeader := make(map[string]any)
-		for _, h := range config.RequestHeader {
-			if v := req.Header.Get(h); v != "" {
-				reqHeader[h] = v
-			}
-		}
-		fields["request_header"] = reqHeader
+		fields["request_header"] = headerFields(req.Header, config.RequestHeader)
 	}
 
 	// ---- response headers ----
 	if len(config.ResponseHeader) > 0 {
-		resHeader := make(map[string]any)
-		for _, h := range config.ResponseHeader {
-			if v := res.Header().Get(h); v != "" {
-				resHeader[h] = v
-			}
-		}
-		fields["response_header"] = resHeader
+		fields["response_header"] = headerFields(res.Header(), config.ResponseHeader)
 	}
 
 	config.Logger.TraceInfo(
@@ -210,13 +324,108 @@ func logBodyDump(
 	)
 }
 
+func logStreamSummary(
+	config LoggerConfig,
+	c echo.Context,
+	stats *stream.Stats,
+	start, stop time.Time,
+	handlerErr error,
+) {
+	req := c.Request()
+
+	status := c.Response().Status
+	if stats.Kind == stream.KindWebSocket && handlerErr == nil {
+		status = http.StatusSwitchingProtocols
+	}
+
+	fields := map[string]any{
+		"id":             req.Header.Get(echo.HeaderXRequestID),
+		"uri":            req.RequestURI,
+		"kind":           stats.Kind,
+		"status":         status,
+		"duration":       int64(stop.Sub(start)),
+		"duration_human": stop.Sub(start).String(),
+		"messages_in":    stats.MessagesIn.Load(),
+		"messages_out":   stats.MessagesOut.Load(),
+		"bytes_in":       stats.BytesIn.Load(),
+		"bytes_out":      stats.BytesOut.Load(),
+		"close_reason":   stats.CloseReason(),
+	}
+
+	if handlerErr != nil {
+		fields["error"] = handlerErr.Error()
+	}
+
+	config.Logger.TraceInfo(
+		req.Context(),
+		"STREAM",
+		fields,
+	)
+}
+
+func headerFields(header http.Header, names []string) map[string]any {
+	fields := make(map[string]any)
+	for _, h := range names {
+		if v := header.Get(h); v != "" {
+			fields[h] = v
+		}
+	}
+	return fields
+}
+
+// handlerNames caches the handler function name of the routes, which echo keeps as the route name.
+type handlerNames struct {
+	names sync.Map
+}
+
+func (h *handlerNames) get(c echo.Context) string {
+	key := c.Request().Method + " " + c.Path()
+	if name, ok := h.names.Load(key); ok {
+		return name.(string)
+	}
+
+	name := ""
+	for _, r := range c.Echo().Routes() {
+		if r.Method == c.Request().Method && r.Path == c.Path() {
+			name = r.Name
+			break
+		}
+	}
+	h.names.Store(key, name)
+
+	return name
+}
+
 func (w *bodyDumpResponseWriter) WriteHeader(code int) {
 	w.Status = code
 	w.ResponseWriter.WriteHeader(code)
 }
 
 func (w *bodyDumpResponseWriter) Write(b []byte) (int, error) {
-	return w.Writer.Write(b)
+	if !w.checked {
+		// The content type is final once the body starts.
+		w.checked = true
+		w.body.contentType = w.Header().Get(echo.HeaderContentType)
+		if dumpableContentType(w.body.contentType, w.config.BodyDumpContentTypes) {
+			w.body.buf = getBodyBuffer()
+		} else {
+			w.body.skipped = true
+		}
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	if w.body.buf != nil && n > 0 {
+		if room := w.body.limit - int64(w.body.buf.Len()); room >= int64(n) {
+			w.body.buf.Write(b[:n])
+		} else {
+			if room > 0 {
+				w.body.buf.Write(b[:room])
+			}
+			w.body.truncated = true
+		}
+	}
+
+	return n, err
 }
 
 func (w *bodyDumpResponseWriter) Flush() {
@@ -225,6 +434,10 @@ func (w *bodyDumpResponseWriter) Flush() {
 	}
 }
 
+func (w *bodyDumpResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 func (w *bodyDumpResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	h, ok := w.ResponseWriter.(http.Hijacker)
 	if !ok {
@@ -0,0 +1,192 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordLogger struct {
+	mu      sync.Mutex
+	entries map[string][]map[string]any
+}
+
+func (l *recordLogger) TraceInfo(_ context.Context, level string, fields map[string]any) {
+	l.record(level, fields)
+}
+
+func (l *recordLogger) TraceWarn(_ context.Context, level string, fields map[string]any) {
+	l.record("WARNING:"+level, fields)
+}
+
+func (l *recordLogger) TraceError(_ context.Context, level string, fields map[string]any) {
+	l.record("ERROR:"+level, fields)
+}
+
+func (l *recordLogger) record(level string, fields map[string]any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.entries == nil {
+		l.entries = map[string][]map[string]any{}
+	}
+	l.entries[level] = append(l.entries[level], fields)
+}
+
+func (l *recordLogger) dumps() []map[string]any {
+	return l.get("DUMP")
+}
+
+func (l *recordLogger) get(level string) []map[string]any {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.entries[level]
+}
+
+func serveBodyDump(t *testing.T, config LoggerConfig, req *http.Request, handler echo.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+
+	e := echo.New()
+	e.Use(AccessLoggerWithConfig(config))
+	e.POST("/", handler)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAccessLogger_BodyDumpTruncates(t *testing.T) {
+	logger := &recordLogger{}
+	config := LoggerConfig{BodyDump: true, Logger: logger, BodyDumpMaxSize: 8, BodyDumpSampleRate: 1}
+
+	reqBody := `{"name":"a long request body"}`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(reqBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+	var received string
+	rec := serveBodyDump(t, config, req, func(c echo.Context) error {
+		b, err := io.ReadAll(c.Request().Body)
+		require.NoError(t, err)
+		received = string(b)
+		return c.String(http.StatusOK, "a long response body")
+	})
+
+	// The handler and the client get the whole bodies.
+	assert.Equal(t, reqBody, received)
+	assert.Equal(t, "a long response body", rec.Body.String())
+
+	require.Len(t, logger.dumps(), 1)
+	dump := logger.dumps()[0]
+	assert.Equal(t, `{"name":`+bodyTruncatedMarker, dump["request_body"])
+	assert.Equal(t, "a long r"+bodyTruncatedMarker, dump["response_body"])
+	assert.Equal(t, http.StatusOK, dump["status"])
+}
+
+func TestAccessLogger_BodyDumpContentTypes(t *testing.T) {
+	logger := &recordLogger{}
+	config := LoggerConfig{BodyDump: true, Logger: logger, BodyDumpSampleRate: 1}
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("--boundary\r\n"))
+	req.Header.Set(echo.HeaderContentType, "multipart/form-data; boundary=boundary")
+
+	serveBodyDump(t, config, req, func(c echo.Context) error {
+		return c.Blob(http.StatusOK, "image/png", []byte{0x89, 'P', 'N', 'G'})
+	})
+
+	require.Len(t, logger.dumps(), 1)
+	dump := logger.dumps()[0]
+	assert.Equal(t, "[multipart/form-data; boundary=boundary body not dumped]", dump["request_body"])
+	assert.Equal(t, "[image/png body not dumped]", dump["response_body"])
+}
+
+func TestAccessLogger_BodyDumpSampling(t *testing.T) {
+	logger := &recordLogger{}
+	config := LoggerConfig{BodyDump: true, Logger: logger, BodyDumpSampleRate: 0, BodyDumpAlwaysStatus: 500}
+
+	for _, status := range []int{http.StatusOK, http.StatusNotFound, http.StatusInternalServerError} {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		serveBodyDump(t, config, req, func(c echo.Context) error {
+			return c.JSON(status, map[string]int{"status": status})
+		})
+	}
+
+	// Only the response at or above the always status is dumped.
+	require.Len(t, logger.dumps(), 1)
+	assert.Equal(t, http.StatusInternalServerError, logger.dumps()[0]["status"])
+	assert.Equal(t, "{}", logger.dumps()[0]["request_body"])
+}
+
+func TestDumpableContentType(t *testing.T) {
+	patterns := DefaultBodyDumpContentTypes
+
+	assert.True(t, dumpableContentType("", patterns))
+	assert.True(t, dumpableContentType("application/json; charset=UTF-8", patterns))
+	assert.True(t, dumpableContentType("text/plain", patterns))
+	assert.True(t, dumpableContentType("application/problem+json", patterns))
+	assert.False(t, dumpableContentType("application/octet-stream", patterns))
+	assert.False(t, dumpableContentType("multipart/form-data; boundary=x", patterns))
+	assert.True(t, dumpableContentType("image/png", []string{"*/*"}))
+}
+
+func TestAccessLogger_Combined(t *testing.T) {
+	logger := &recordLogger{}
+	config := LoggerConfig{
+		Logger:   logger,
+		Combined: true,
+		TenantID: func(c echo.Context) string { return c.Request().Header.Get("X-Tenant-Id") },
+		UserID:   func(c echo.Context) string { return "" },
+	}
+
+	e := echo.New()
+	e.Use(AccessLoggerWithConfig(config))
+	e.GET("/items/:id", getItem)
+	e.GET("/fail", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "down")
+	})
+	e.GET("/missing", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusNotFound, "no item")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/items/42", nil)
+	req.Header.Set("X-Tenant-Id", "7")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	// A single entry per request, written once it's done.
+	require.Len(t, logger.get("ACCESS"), 1)
+	assert.Empty(t, logger.dumps())
+
+	entry := logger.get("ACCESS")[0]
+	assert.Equal(t, http.StatusOK, entry["status"])
+	assert.Equal(t, "/items/:id", entry["route"])
+	assert.Contains(t, entry["handler"], "middleware.getItem")
+	assert.Equal(t, "7", entry["tenant_id"])
+	assert.NotContains(t, entry, "user_id")
+	assert.EqualValues(t, len("item 42"), entry["bytes_out"])
+	assert.Contains(t, entry, "latency")
+
+	// 4xx responses are warnings.
+	require.Len(t, logger.get("WARNING:ACCESS"), 1)
+	entry = logger.get("WARNING:ACCESS")[0]
+	assert.Equal(t, http.StatusNotFound, entry["status"])
+	assert.Contains(t, entry["error"], "no item")
+
+	// 5xx responses are errors.
+	require.Len(t, logger.get("ERROR:ACCESS"), 1)
+	entry = logger.get("ERROR:ACCESS")[0]
+	assert.Equal(t, http.StatusServiceUnavailable, entry["status"])
+	assert.Contains(t, entry["error"], "down")
+}
+
+func getItem(c echo.Context) error {
+	return c.String(http.StatusOK, "item "+c.Param("id"))
+}
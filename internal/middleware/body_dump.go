@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"math/rand/v2"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultBodyDumpMaxSize is the number of bytes captured per direction when `BodyDumpMaxSize` is not set.
+	DefaultBodyDumpMaxSize = 64 * 1024
+	// DefaultBodyDumpAlwaysStatus is the status from which a response is dumped regardless of the sample rate.
+	DefaultBodyDumpAlwaysStatus = http.StatusBadRequest
+
+	// bodyTruncatedMarker is appended to a dumped body cut to `BodyDumpMaxSize`.
+	bodyTruncatedMarker = "...[truncated]"
+	// maxPooledBodyBuffer keeps unusually large buffers out of the pool.
+	maxPooledBodyBuffer = 1 << 20
+)
+
+// DefaultBodyDumpContentTypes are the textual content types dumped when `BodyDumpContentTypes` is empty.
+// Binary and multipart bodies are never dumped by default.
+var DefaultBodyDumpContentTypes = []string{
+	"application/json",
+	"application/xml",
+	"application/x-www-form-urlencoded",
+	"text/*",
+	"+json",
+	"+xml",
+}
+
+var bodyBufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+func getBodyBuffer() *bytes.Buffer {
+	return bodyBufferPool.Get().(*bytes.Buffer)
+}
+
+func putBodyBuffer(b *bytes.Buffer) {
+	if b == nil || b.Cap() > maxPooledBodyBuffer {
+		return
+	}
+	b.Reset()
+	bodyBufferPool.Put(b)
+}
+
+// bodyCapture is the part of a body kept for the dump.
+type bodyCapture struct {
+	buf         *bytes.Buffer
+	limit       int64
+	truncated   bool
+	contentType string
+	skipped     bool
+}
+
+// String returns the captured body, with the truncation marker if it was cut, or a note if its
+// content type is not dumped.
+func (b *bodyCapture) String() string {
+	if b.skipped {
+		return "[" + b.contentType + " body not dumped]"
+	}
+	if b.buf == nil || b.buf.Len() == 0 {
+		return ""
+	}
+	data := b.buf.Bytes()
+	if int64(len(data)) > b.limit {
+		data = data[:b.limit]
+	}
+	if b.truncated {
+		return string(data) + bodyTruncatedMarker
+	}
+	return string(data)
+}
+
+func (b *bodyCapture) release() {
+	putBodyBuffer(b.buf)
+	b.buf = nil
+}
+
+// captureRequestBody reads up to `limit` bytes of the request body into a pooled buffer and
+// replaces the body, so the handler still reads it whole. The buffer is only valid until the
+// handler returns, as is the body itself.
+func captureRequestBody(req *http.Request, limit int64, contentTypes []string) *bodyCapture {
+	capture := &bodyCapture{limit: limit, contentType: req.Header.Get("Content-Type")}
+	if req.Body == nil || req.Body == http.NoBody {
+		return capture
+	}
+	if !dumpableContentType(capture.contentType, contentTypes) {
+		capture.skipped = true
+		return capture
+	}
+
+	capture.buf = getBodyBuffer()
+	// One byte more than the limit tells whether the body is truncated.
+	n, _ := io.CopyN(capture.buf, req.Body, limit+1)
+	capture.truncated = n > limit
+
+	req.Body = &prefixedReadCloser{
+		Reader: io.MultiReader(bytes.NewReader(capture.buf.Bytes()), req.Body),
+		Closer: req.Body,
+	}
+
+	return capture
+}
+
+type prefixedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// dumpableContentType reports whether a body of the content type is dumped. Patterns are either a
+// media type (`application/json`), a type wildcard (`text/*`) or a structured syntax suffix (`+json`).
+// A body without a content type is dumped.
+func dumpableContentType(contentType string, patterns []string) bool {
+	if contentType == "" {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		switch {
+		case p == "*/*":
+			return true
+		case strings.HasSuffix(p, "/*"):
+			if strings.HasPrefix(mediaType, strings.TrimSuffix(p, "*")) {
+				return true
+			}
+		case strings.HasPrefix(p, "+"):
+			if strings.HasSuffix(mediaType, p) {
+				return true
+			}
+		case mediaType == p:
+			return true
+		}
+	}
+
+	return false
+}
+
+// sampleBodyDump reports whether the bodies of a response with the status are dumped.
+func sampleBodyDump(config LoggerConfig, status int) bool {
+	if status >= config.BodyDumpAlwaysStatus {
+		return true
+	}
+	if config.BodyDumpSampleRate >= 1 {
+		return true
+	}
+	return config.BodyDumpSampleRate > 0 && rand.Float64() < config.BodyDumpSampleRate
+}
@@ -39,6 +39,7 @@ var sortAllowedMethodOnce sync.Once
 
 // MethodNotAllowedAndRouteNotFound middleware will reply HTTP 405 if a wrong method been called for an API route.
 // This middleware will also return 404 if a page doesn't exist.
+// It runs before the route groups, so their `berror.UseProblemDetails` doesn't apply: only `http.problemDetails.on` does.
 func MethodNotAllowedAndRouteNotFound() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) (err error) {
@@ -76,25 +77,26 @@ func MethodNotAllowedAndRouteNotFound() echo.MiddlewareFunc {
 			}
 
 			if !isRouteMatched {
-				if !strings.Contains(c.Request().Header.Get("Content-Type"), "application/json") {
-					return c.Render(http.StatusNotFound, "errors/html/404", echo.Map{"stacktrace": nil})
-				} else {
-					return c.JSON(http.StatusNotFound, map[string]interface{}{
+				return berror.WriteErrorResponse(c, &berror.ErrorResponse{
+					Status: http.StatusNotFound,
+					Code:   berror.RESOURCE_NOT_FOUND,
+					Body: map[string]interface{}{
 						"errorCode": berror.RESOURCE_NOT_FOUND,
 						"errors":    nil,
-					})
-				}
+					},
+					Template: "errors/html/404",
+				})
 
 			} else if isRouteMatched && !isMethodMatched {
-
-				if !strings.Contains(c.Request().Header.Get("Content-Type"), "application/json") {
-					return c.Render(http.StatusMethodNotAllowed, "errors/html/405", echo.Map{"stacktrace": nil})
-				} else {
-					return c.JSON(http.StatusMethodNotAllowed, map[string]interface{}{
+				return berror.WriteErrorResponse(c, &berror.ErrorResponse{
+					Status: http.StatusMethodNotAllowed,
+					Code:   berror.METHOD_NOT_ALLOWED,
+					Body: map[string]interface{}{
 						"errorCode": berror.METHOD_NOT_ALLOWED,
 						"errors":    nil,
-					})
-				}
+					},
+					Template: "errors/html/405",
+				})
 			}
 
 			return next(c)
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/retail-ai-inc/bean/v2/internal/regex"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const otelInstrumentationName = "github.com/retail-ai-inc/bean/v2/internal/middleware"
+
+// OtelTracing starts an OpenTelemetry server span for every incoming request. The trace is continued from
+// the incoming propagation headers and the span is stored in the request context, so `trace.StartSpan`
+// and the log trace extractor can pick it up. Paths matched by `skipTracesEndpoints` are not traced.
+var OtelTracing = func(next echo.HandlerFunc) echo.HandlerFunc {
+	tracer := otel.Tracer(otelInstrumentationName)
+
+	return func(c echo.Context) error {
+		req := c.Request()
+		if regex.SkipSampling(req.URL.Path) {
+			return next(c)
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+
+		// `c.Path()` is the registered route template, which keeps the span name low cardinality.
+		route := c.Path()
+		if route == "" {
+			route = req.URL.Path
+		}
+
+		ctx, span := tracer.Start(ctx, fmt.Sprintf("%s %s", req.Method, route),
+			oteltrace.WithSpanKind(oteltrace.SpanKindServer),
+			oteltrace.WithAttributes(
+				attribute.String("http.request.method", req.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", req.URL.Path),
+				attribute.String("url.scheme", c.Scheme()),
+				attribute.String("server.address", req.Host),
+				attribute.String("client.address", c.RealIP()),
+				attribute.String("user_agent.original", req.UserAgent()),
+			),
+		)
+		defer span.End()
+
+		// The request id middleware sets the generated id on the response only.
+		id := c.Response().Header().Get(echo.HeaderXRequestID)
+		if id == "" {
+			id = req.Header.Get(echo.HeaderXRequestID)
+		}
+		if id != "" {
+			span.SetAttributes(attribute.String("http.request.header.x-request-id", id))
+		}
+
+		c.SetRequest(req.WithContext(ctx))
+
+		// The error is handled here, as by the access log middleware, to know the status.
+		err := next(c)
+		if err != nil {
+			span.RecordError(err)
+			c.Error(err)
+		}
+
+		status := c.Response().Status
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+
+		return err
+	}
+}
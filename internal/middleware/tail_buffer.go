@@ -0,0 +1,78 @@
+// MIT License
+
+// Copyright (c) The RAI Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+
+	"github.com/retail-ai-inc/bean/v2/log"
+)
+
+type TailBufferConfig struct {
+	Skipper middleware.Skipper
+	// MaxEntries is the number of debug entries kept per request. Default `log.DefaultTailBufferSize`.
+	MaxEntries int
+}
+
+// TailBufferWithConfig keeps the debug entries of each request in a `log.TailBuffer` and writes them
+// only if the request fails with a 5xx status or a panic, or is marked with `log.MarkForFlush`.
+// Otherwise they are discarded once the response is done.
+func TailBufferWithConfig(config TailBufferConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			buf := log.NewTailBuffer(config.MaxEntries)
+			c.SetRequest(c.Request().WithContext(log.WithTailBuffer(c.Request().Context(), buf)))
+
+			defer func() {
+				if r := recover(); r != nil {
+					buf.Flush()
+					panic(r)
+				}
+			}()
+
+			// The error is handled here, as by the access log middleware, to know the status.
+			if err = next(c); err != nil {
+				c.Error(err)
+			}
+
+			if buf.Marked() || c.Response().Status >= http.StatusInternalServerError {
+				buf.Flush()
+			} else {
+				buf.Discard()
+			}
+
+			return err
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/retail-ai-inc/bean/v2/log"
+)
+
+func TestTailBuffer(t *testing.T) {
+	e := echo.New()
+	var out bytes.Buffer
+	e.Logger.SetOutput(&out)
+	logger, err := log.NewLogger(e.Logger, log.WithAppMinSeverity(log.Info))
+	require.NoError(t, err)
+
+	e.Use(TailBufferWithConfig(TailBufferConfig{}))
+	e.GET("/:status", func(c echo.Context) error {
+		ctx := c.Request().Context()
+		logger.FromContext(ctx).Debug("handling " + c.Param("status"))
+		switch c.Param("status") {
+		case "error":
+			return errors.New("boom")
+		case "marked":
+			log.MarkForFlush(ctx)
+		case "bad":
+			return echo.NewHTTPError(http.StatusBadRequest)
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	serve := func(path string) int {
+		out.Reset()
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("/ok"))
+	assert.NotContains(t, out.String(), "handling ok")
+
+	assert.Equal(t, http.StatusBadRequest, serve("/bad"))
+	assert.NotContains(t, out.String(), "handling bad")
+
+	assert.Equal(t, http.StatusInternalServerError, serve("/error"))
+	assert.Contains(t, out.String(), `"message":"handling error"`)
+
+	assert.Equal(t, http.StatusOK, serve("/marked"))
+	assert.Contains(t, out.String(), `"message":"handling marked"`)
+}
+
+func TestTailBuffer_Panic(t *testing.T) {
+	e := echo.New()
+	var out bytes.Buffer
+	e.Logger.SetOutput(&out)
+	logger, err := log.NewLogger(e.Logger)
+	require.NoError(t, err)
+
+	e.Use(TailBufferWithConfig(TailBufferConfig{}))
+	e.GET("/", func(c echo.Context) error {
+		logger.FromContext(c.Request().Context()).Debug("about to panic")
+		panic("boom")
+	})
+
+	assert.Panics(t, func() {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+	assert.Contains(t, out.String(), `"message":"about to panic"`)
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"github.com/getsentry/sentry-go"
+	"github.com/labstack/echo/v4"
+	bctx "github.com/retail-ai-inc/bean/v2/context"
+	"github.com/retail-ai-inc/bean/v2/trace"
+)
+
+// IncomingTraceContext reads the trace context sent by the caller in any of the configured propagation formats.
+// The trace id is kept in the request context so log entries are correlated with the caller even when
+// nothing is traced locally, and so is the W3C `tracestate`, forwarded on the outgoing requests. When Sentry is the tracing provider and the caller only sent a W3C `traceparent`,
+// a `sentry-trace` header is derived from it so that the Sentry middleware continues the same trace.
+// It must be registered before the Sentry and OpenTelemetry middlewares.
+var IncomingTraceContext = func(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+
+		if !trace.IsOtel() && req.Header.Get(sentry.SentryTraceHeader) == "" {
+			if sentryTrace := trace.SentryTraceFromHeader(req.Header); sentryTrace != "" {
+				req.Header.Set(sentry.SentryTraceHeader, sentryTrace)
+			}
+		}
+
+		if sc := trace.IncomingSpanContext(req.Header); sc.IsValid() {
+			ctx := bctx.SetTraceID(req.Context(), sc.TraceID().String())
+			if ts := sc.TraceState().String(); ts != "" {
+				ctx = bctx.SetTraceState(ctx, ts)
+			}
+			c.SetRequest(req.WithContext(ctx))
+		}
+
+		return next(c)
+	}
+}
@@ -0,0 +1,183 @@
+package log
+
+import (
+	"context"
+	"maps"
+
+	bctx "github.com/retail-ai-inc/bean/v2/context"
+)
+
+// AppLevel is the level of the application entries of `FromContext` and `NewSlogHandler`.
+const AppLevel = "APP"
+
+// badKey is the key of an argument which isn't a field, a map of fields or an error, and isn't
+// preceded by a key, as `log/slog` does.
+const badKey = "!BADKEY"
+
+// ErrorDedup throttles the application entries of `ERROR` and above logged with an error, all are
+// written if nil. `bean` sets it to the `Allow` of `error.LogDedup`, so an error is counted under the
+// same fingerprint whether the error handlers or the application log it.
+var ErrorDedup func(err error) bool
+
+// allowError reports whether the entry of an error is written, see `ErrorDedup`.
+func allowError(severity Severity, err error) bool {
+	return err == nil || ErrorDedup == nil || severity.rank() < Error.rank() || ErrorDedup(err)
+}
+
+// AppLogger writes structured application logs through the pipeline of the access logs, so they go
+// through the same processors, masking and sinks. The trace id, span id and sampling decision of the
+// span of the context, its request id and tenant id are attached to the entries.
+//
+//	log.FromContext(ctx).With("order_id", id).Info("order created", log.Int("items", n))
+type AppLogger struct {
+	l      *logger
+	ctx    context.Context
+	level  string
+	fields map[string]any
+	attrs  []Field
+}
+
+// FromContext returns an application logger of the logger of `Init`. Its entries are dropped before `Init`.
+func FromContext(ctx context.Context) *AppLogger {
+	l, _ := blogger.(*logger)
+	return newAppLogger(l, ctx)
+}
+
+func newAppLogger(l *logger, ctx context.Context) *AppLogger {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &AppLogger{l: l, ctx: ctx, level: AppLevel}
+}
+
+// With returns a logger adding fields to the entries. The arguments are `Field`s, maps of fields,
+// errors written as `error`, or key-value pairs: `With("order_id", id, log.Int("items", n))`.
+func (a *AppLogger) With(args ...any) *AppLogger {
+	c := *a
+	var fields map[string]any
+	c.attrs, fields = appendArgs(a.attrs[:len(a.attrs):len(a.attrs)], nil, args)
+	if len(fields) > 0 {
+		c.fields = cloneFields(a.fields)
+		if c.fields == nil {
+			c.fields = make(map[string]any, len(fields))
+		}
+		maps.Copy(c.fields, cloneFields(fields))
+	}
+	return &c
+}
+
+// WithLevel returns a logger writing entries of another level than `APP`, e.g. `PAYMENT`.
+func (a *AppLogger) WithLevel(level string) *AppLogger {
+	c := *a
+	c.level = level
+	return &c
+}
+
+// Enabled reports whether the entries of the severity are written, see `WithAppMinSeverity`, `LevelControl`
+// and `TailBuffer`.
+func (a *AppLogger) Enabled(severity Severity) bool {
+	return a.l != nil && a.l.appEnabled(a.ctx, a.level, severity)
+}
+
+func (a *AppLogger) Debug(msg string, args ...any) {
+	a.log(Debug, msg, args)
+}
+
+func (a *AppLogger) Info(msg string, args ...any) {
+	a.log(Info, msg, args)
+}
+
+func (a *AppLogger) Warn(msg string, args ...any) {
+	a.log(Warning, msg, args)
+}
+
+func (a *AppLogger) Error(msg string, args ...any) {
+	a.log(Error, msg, args)
+}
+
+func (a *AppLogger) Critical(msg string, args ...any) {
+	a.log(Critical, msg, args)
+}
+
+// Log writes an entry of any severity.
+func (a *AppLogger) Log(severity Severity, msg string, args ...any) {
+	a.log(severity, msg, args)
+}
+
+func (a *AppLogger) log(severity Severity, msg string, args []any) {
+	if !a.Enabled(severity) || !allowError(severity, argsError(args)) {
+		return
+	}
+
+	attrs := make([]Field, 0, 3+len(a.attrs)+len(args))
+	attrs = append(attrs, String("message", msg))
+	attrs = appendContextFields(attrs, a.ctx)
+	for _, f := range a.attrs {
+		// The processors change the maps in place, the ones of `With` are shared.
+		if f.kind == KindAny {
+			f = Any(f.Key, cloneValue(f.any))
+		}
+		attrs = append(attrs, f)
+	}
+
+	// The processors change the maps in place, the ones of `With` are shared.
+	fields := cloneFields(a.fields)
+	attrs, fields = appendArgs(attrs, fields, args)
+
+	var pc uintptr
+	if a.l.addSource {
+		// Skip log and Info / Error / ...
+		pc = callerPC(2)
+	}
+	a.l.write(a.ctx, severity, a.level, fields, attrs, pc)
+}
+
+// appendContextFields appends the request id and the tenant id of the context.
+func appendContextFields(attrs []Field, ctx context.Context) []Field {
+	if id, ok := bctx.GetRequestID(ctx); ok {
+		attrs = append(attrs, String("request_id", id))
+	}
+	if id, ok := bctx.GetTenantID(ctx); ok {
+		attrs = append(attrs, String("tenant_id", id))
+	}
+	return attrs
+}
+
+// argsError returns the first error of the arguments.
+func argsError(args []any) error {
+	for _, arg := range args {
+		if err, ok := arg.(error); ok {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendArgs appends the arguments of `AppLogger` to the typed fields, the maps to fields.
+func appendArgs(attrs []Field, fields map[string]any, args []any) ([]Field, map[string]any) {
+	for i := 0; i < len(args); i++ {
+		switch v := args[i].(type) {
+		case Field:
+			attrs = append(attrs, v)
+		case []Field:
+			attrs = append(attrs, v...)
+		case map[string]any:
+			if fields == nil {
+				fields = make(map[string]any, len(v))
+			}
+			maps.Copy(fields, v)
+		case error:
+			attrs = append(attrs, Err(v))
+		case string:
+			if i+1 == len(args) {
+				attrs = append(attrs, String(badKey, v))
+				break
+			}
+			attrs = append(attrs, Any(v, args[i+1]))
+			i++
+		default:
+			attrs = append(attrs, Any(badKey, v))
+		}
+	}
+	return attrs, fields
+}
@@ -0,0 +1,198 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	bctx "github.com/retail-ai-inc/bean/v2/context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// lockedBuffer is a buffer the sink can write from several goroutines.
+type lockedBuffer struct {
+	mu sync.Mutex
+	bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.Buffer.Write(p)
+}
+
+func newBufferLogger(t *testing.T, options ...LoggerOptions) (*logger, *lockedBuffer) {
+	t.Helper()
+	var buf lockedBuffer
+	e := echo.New()
+	e.Logger.SetOutput(&buf)
+	l, err := NewLogger(e.Logger, options...)
+	require.NoError(t, err)
+	return l, &buf
+}
+
+func decodeLines(t *testing.T, buf *lockedBuffer) []map[string]any {
+	t.Helper()
+	var docs []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var doc map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &doc), line)
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+func TestAppLogger(t *testing.T) {
+	l, buf := newBufferLogger(t, WithMaskFields([]string{"password"}))
+
+	ctx := bctx.SetRequestID(context.Background(), "req-1")
+	ctx = bctx.SetTenantID(ctx, "42")
+
+	l.FromContext(ctx).
+		With("order_id", "o-1", Int("items", 3)).
+		Info("order created", map[string]any{"user": map[string]any{"password": "p"}}, errors.New("boom"), "dangling")
+
+	docs := decodeLines(t, buf)
+	require.Len(t, docs, 1)
+	doc := docs[0]
+	assert.Equal(t, "INFO", doc["severity"])
+	assert.Equal(t, AppLevel, doc["level"])
+	assert.Equal(t, "order created", doc["message"])
+	assert.Equal(t, "req-1", doc["request_id"])
+	assert.Equal(t, "42", doc["tenant_id"])
+	assert.Equal(t, "o-1", doc["order_id"])
+	assert.Equal(t, float64(3), doc["items"])
+	assert.Equal(t, map[string]any{"password": "****"}, doc["user"])
+	assert.Equal(t, "boom", doc["error"])
+	assert.Equal(t, "dangling", doc[badKey])
+}
+
+var testSpanContext = oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+	TraceID:    oteltrace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+	SpanID:     oteltrace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+	TraceFlags: oteltrace.FlagsSampled,
+})
+
+func TestAppLogger_Span(t *testing.T) {
+	l, buf := newBufferLogger(t, WithTraceExtractor(NewOtelExtractor()))
+
+	ctx := oteltrace.ContextWithSpanContext(context.Background(), testSpanContext)
+	l.FromContext(ctx).Info("order created")
+
+	docs := decodeLines(t, buf)
+	require.Len(t, docs, 1)
+	assert.Equal(t, testSpanContext.TraceID().String(), docs[0]["trace"])
+	assert.Equal(t, testSpanContext.SpanID().String(), docs[0]["span_id"])
+	assert.Equal(t, true, docs[0]["trace_sampled"])
+}
+
+// allowOnce lets the first entry of each error message through, as `ErrorDedup`.
+func allowOnce(t *testing.T) {
+	t.Helper()
+	seen := map[string]bool{}
+	ErrorDedup = func(err error) bool {
+		if seen[err.Error()] {
+			return false
+		}
+		seen[err.Error()] = true
+		return true
+	}
+	t.Cleanup(func() { ErrorDedup = nil })
+}
+
+func TestAppLogger_ErrorDedup(t *testing.T) {
+	allowOnce(t)
+	l, buf := newBufferLogger(t)
+
+	err := errors.New("db down")
+	for i := 0; i < 3; i++ {
+		l.FromContext(context.Background()).Error("query failed", err)
+	}
+	l.FromContext(context.Background()).Error("query failed", "cause", errors.New("cache down"))
+	// Below ERROR, the entries aren't throttled.
+	l.FromContext(context.Background()).Warn("retrying", err)
+
+	docs := decodeLines(t, buf)
+	require.Len(t, docs, 3)
+	assert.Equal(t, "db down", docs[0]["error"])
+	assert.Equal(t, "cache down", docs[1]["cause"])
+	assert.Equal(t, "WARNING", docs[2]["severity"])
+}
+
+func TestAppLogger_WithLevelAndMinSeverity(t *testing.T) {
+	l, buf := newBufferLogger(t, WithAppMinSeverity(Warning))
+
+	a := l.FromContext(context.Background()).WithLevel("PAYMENT")
+	assert.False(t, a.Enabled(Info))
+	assert.True(t, a.Enabled(Error))
+
+	a.Debug("dropped")
+	a.Info("dropped")
+	a.Warn("kept")
+	a.Error("kept")
+
+	docs := decodeLines(t, buf)
+	require.Len(t, docs, 2)
+	assert.Equal(t, "WARNING", docs[0]["severity"])
+	assert.Equal(t, "PAYMENT", docs[0]["level"])
+	assert.Equal(t, "ERROR", docs[1]["severity"])
+}
+
+func TestAppLogger_WithIsShared(t *testing.T) {
+	l, buf := newBufferLogger(t, WithMaskFields([]string{"password"}))
+
+	base := l.FromContext(context.Background()).With(map[string]any{"user": map[string]any{"password": "p"}})
+	child := base.With("child", true)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			base.Info("base")
+			child.Info("child")
+		}()
+	}
+	wg.Wait()
+
+	docs := decodeLines(t, buf)
+	require.Len(t, docs, 16)
+	assert.Equal(t, map[string]any{"password": "p"}, base.fields["user"], "the fields of With aren't masked in place")
+	for _, doc := range docs {
+		assert.Equal(t, map[string]any{"password": "****"}, doc["user"])
+		if doc["message"] == "base" {
+			assert.NotContains(t, doc, "child")
+		}
+	}
+}
+
+func TestAppLogger_SourceLocation(t *testing.T) {
+	l, buf := newBufferLogger(t, WithEncoding(EncoderConfig{Format: EncodingGCP}))
+
+	l.FromContext(context.Background()).Info("here")
+
+	docs := decodeLines(t, buf)
+	require.Len(t, docs, 1)
+	source, ok := docs[0]["logging.googleapis.com/sourceLocation"].(map[string]any)
+	require.True(t, ok, docs[0])
+	assert.Contains(t, source["file"], "app_logger_test.go")
+	assert.Contains(t, source["function"], "TestAppLogger_SourceLocation")
+}
+
+func TestFromContext_BeforeInit(t *testing.T) {
+	assert.NotPanics(t, func() {
+		a := newAppLogger(nil, nil)
+		assert.False(t, a.Enabled(Critical))
+		a.With("k", "v").Error("dropped")
+	})
+}
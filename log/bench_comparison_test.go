@@ -411,3 +411,109 @@ func BenchmarkCmpPipeline_Opt(b *testing.B) {
 	b.StopTimer()
 	_ = s.Close(context.Background())
 }
+
+// ─────────────────────────────────────────────────────────────────────────────
+// 6.  Entry encoding  –  pooled map + encoding/json  vs  append encoder
+//
+//  Baseline: the previous sink path, copying the entry into a pooled map
+//            and running json.Encoder (noLockSinkWrite above)
+//  Opt:      appendEntryJSON, typed switch on the values, sorted keys
+// ─────────────────────────────────────────────────────────────────────────────
+
+func BenchmarkCmpEncode_MapJSON(b *testing.B) {
+	e := makeEntry(true)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = noLockSinkWrite(io.Discard, "trace", e)
+	}
+}
+
+func BenchmarkCmpEncode_Append(b *testing.B) {
+	e := makeEntry(true)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf := bufPool.Get().(*bytes.Buffer)
+		buf.Reset()
+		buf.Write(appendEntryJSON(buf.AvailableBuffer(), e, "trace"))
+		_, _ = io.Discard.Write(buf.Bytes())
+		bufPool.Put(buf)
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// 7.  MaskProcessor  –  JSON body dump
+//
+//  Baseline: json.Unmarshal into interface{} trees, mask, json.Marshal
+//  Opt:      single pass streaming masker, keys kept in order
+// ─────────────────────────────────────────────────────────────────────────────
+
+const benchBody = `{"user":{"name":"john","password":"secret","roles":["admin","dev"]},` +
+	`"items":[{"id":1,"price":9.99,"sku":"A-1"},{"id":2,"price":19.99,"sku":"B-2"}],"note":"deliver after 6pm"}`
+
+func BenchmarkCmpMask_JSONBody_Baseline(b *testing.B) {
+	proc := &baselineMask{fields: map[string]struct{}{"password": {}}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = proc.processEntry(Entry{Fields: map[string]any{"request_body": benchBody, "response_body": benchBody}})
+	}
+}
+
+func BenchmarkCmpMask_JSONBody_Opt(b *testing.B) {
+	proc := NewMaskProcessor([]string{"password"})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = proc.Process(Entry{Fields: map[string]any{"request_body": benchBody, "response_body": benchBody}})
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// 8.  Full write  –  map fields  vs  typed fields
+//
+//  Baseline: TraceInfo with map[string]any fields
+//  Opt:      Log with typed fields, nothing boxed
+// ─────────────────────────────────────────────────────────────────────────────
+
+func benchLogger(b *testing.B) *logger {
+	b.Helper()
+	s, _ := NewSink(NopWriteCloser{Writer: io.Discard}, "trace", SinkConfig{})
+	return &logger{
+		traceExtractor: NewSentryExtractor(),
+		pipeline:       NewPipeline(s, NewMaskProcessor([]string{"password"}), NewRemoveEscapeProcessor()),
+	}
+}
+
+func BenchmarkCmpLog_MapFields(b *testing.B) {
+	l := benchLogger(b)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.TraceInfo(ctx, "ACCESS", map[string]any{
+			"method":     "POST",
+			"uri":        "/api/v1/orders",
+			"status":     200,
+			"latency_ms": 38,
+			"user_agent": "Go-http-client/2.0",
+		})
+	}
+}
+
+func BenchmarkCmpLog_TypedFields(b *testing.B) {
+	l := benchLogger(b)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Log(ctx, Info, "ACCESS",
+			String("method", "POST"),
+			String("uri", "/api/v1/orders"),
+			Int("status", 200),
+			Int("latency_ms", 38),
+			String("user_agent", "Go-http-client/2.0"),
+		)
+	}
+}
@@ -0,0 +1,445 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Encoder writes an entry to the buffer written to the output at once, ending with a newline.
+// The payload holds what the JSON encoder writes: `timestamp`, `severity`, `level`, the fields
+// and the trace id under the key of the runtime platform. Encoders must not modify `e.Fields`.
+type Encoder func(buf *bytes.Buffer, e Entry, payload map[string]any) error
+
+// Encoding formats of `NewEncoder`.
+const (
+	EncodingJSON   = "json"
+	EncodingLogfmt = "logfmt"
+	EncodingECS    = "ecs"
+	EncodingGCP    = "gcp"
+	EncodingEMF    = "emf"
+)
+
+// EncoderConfig selects an encoder and its options.
+type EncoderConfig struct {
+	// Format is `json` (default), `logfmt`, `ecs`, `gcp` or `emf`.
+	Format string
+	// GCPProjectID makes the `gcp` trace `projects/<id>/traces/<trace id>`, the form Cloud Logging links to Cloud Trace.
+	GCPProjectID string
+	// EMF configures the metrics of the `emf` format.
+	EMF EMFConfig
+}
+
+// NewEncoder returns the encoder of a format, nil for the JSON encoder of the sinks.
+func NewEncoder(cfg EncoderConfig) (Encoder, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Format)) {
+	case "", EncodingJSON:
+		return nil, nil
+	case EncodingLogfmt:
+		return EncodeLogfmt, nil
+	case EncodingECS:
+		return EncodeECS, nil
+	case EncodingGCP:
+		return NewGCPEncoder(cfg.GCPProjectID), nil
+	case EncodingEMF:
+		return NewEMFEncoder(cfg.EMF), nil
+	default:
+		return nil, fmt.Errorf("log sink: unknown encoding %q", cfg.Format)
+	}
+}
+
+// fieldMap returns the fields with the typed ones, without modifying `Fields`.
+func (e Entry) fieldMap() map[string]any {
+	if len(e.Attrs) == 0 {
+		return e.Fields
+	}
+	fields := make(map[string]any, len(e.Fields)+len(e.Attrs))
+	for k, v := range e.Fields {
+		fields[k] = v
+	}
+	for _, f := range e.Attrs {
+		fields[f.Key] = f.Value()
+	}
+	return fields
+}
+
+// needsSource reports whether the format writes where the entry was logged from.
+func (cfg EncoderConfig) needsSource() bool {
+	switch strings.ToLower(strings.TrimSpace(cfg.Format)) {
+	case EncodingECS, EncodingGCP:
+		return true
+	default:
+		return false
+	}
+}
+
+// EncodeLogfmt writes `time=... severity=... level=...` followed by the other keys of the payload in order,
+// nested maps flattened with dots. Meant for reading logs in a terminal during development.
+func EncodeLogfmt(buf *bytes.Buffer, e Entry, payload map[string]any) error {
+	buf.WriteString("time=")
+	buf.WriteString(e.Timestamp.Format(time.RFC3339Nano))
+	logfmtPair(buf, "severity", string(e.Severity))
+	logfmtPair(buf, "level", e.Level)
+
+	keys := make([]string, 0, len(payload))
+	for k := range payload {
+		switch k {
+		case "timestamp", "severity", "level":
+		default:
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		logfmtValue(buf, k, payload[k])
+	}
+
+	buf.WriteByte('\n')
+	return nil
+}
+
+func logfmtValue(buf *bytes.Buffer, key string, v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			logfmtValue(buf, key+"."+k, v[k])
+		}
+	case nil:
+		logfmtPair(buf, key, "")
+	case string:
+		logfmtPair(buf, key, v)
+	case time.Time:
+		logfmtPair(buf, key, v.Format(time.RFC3339Nano))
+	case error:
+		logfmtPair(buf, key, v.Error())
+	case fmt.Stringer:
+		logfmtPair(buf, key, v.String())
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, json.Number, Severity:
+		logfmtPair(buf, key, fmt.Sprint(v))
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			logfmtPair(buf, key, fmt.Sprint(v))
+			return
+		}
+		logfmtPair(buf, key, string(b))
+	}
+}
+
+func logfmtPair(buf *bytes.Buffer, key, value string) {
+	buf.WriteByte(' ')
+	for _, r := range key {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			r = '_'
+		}
+		buf.WriteRune(r)
+	}
+	buf.WriteByte('=')
+
+	if logfmtNeedsQuote(value) {
+		buf.WriteString(strconv.Quote(value))
+	} else {
+		buf.WriteString(value)
+	}
+}
+
+func logfmtNeedsQuote(s string) bool {
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+// ecsFields renames the fields of the access and outbound logs to Elastic Common Schema names.
+var ecsFields = map[string]string{
+	"message":       "message",
+	"id":            "http.request.id",
+	"remote_ip":     "client.ip",
+	"host":          "url.domain",
+	"method":        "http.request.method",
+	"uri":           "url.original",
+	"url":           "url.full",
+	"user_agent":    "user_agent.original",
+	"status":        "http.response.status_code",
+	"user_id":       "user.id",
+	"error":         "error.message",
+	"request_body":  "http.request.body.content",
+	"response_body": "http.response.body.content",
+}
+
+const ecsVersion = "8.11.0"
+
+// EncodeECS writes a JSON line with Elastic Common Schema field names, e.g. `@timestamp`, `log.level`,
+// `http.request.method` and `event.duration`. Fields without an ECS equivalent keep their name.
+func EncodeECS(buf *bytes.Buffer, e Entry, _ map[string]any) error {
+	fields := e.fieldMap()
+	doc := make(map[string]any, len(fields)+8)
+	for k, v := range fields {
+		name, ok := ecsFields[k]
+		if !ok {
+			name = k
+		}
+		doc[name] = v
+	}
+
+	// Durations are nanoseconds in ECS.
+	if v, ok := toInt64(fields["latency"]); ok {
+		doc["event.duration"] = v
+		delete(doc, "latency")
+	} else if v, ok := toInt64(fields["latency_ms"]); ok {
+		doc["event.duration"] = v * int64(time.Millisecond)
+		delete(doc, "latency_ms")
+	}
+	if v, ok := toInt64(fields["bytes_in"]); ok {
+		doc["http.request.body.bytes"] = v
+		delete(doc, "bytes_in")
+	}
+	if v, ok := toInt64(fields["bytes_out"]); ok {
+		doc["http.response.body.bytes"] = v
+		delete(doc, "bytes_out")
+	}
+
+	doc["@timestamp"] = e.Timestamp.UTC().Format(time.RFC3339Nano)
+	doc["log.level"] = strings.ToLower(string(e.Severity))
+	doc["log.logger"] = e.Level
+	doc["ecs.version"] = ecsVersion
+	if e.Trace.TraceID != "" {
+		doc["trace.id"] = e.Trace.TraceID
+	}
+	if e.Trace.SpanID != "" {
+		doc["span.id"] = e.Trace.SpanID
+	}
+	if e.Source != nil {
+		doc["log.origin.file.name"] = e.Source.File
+		doc["log.origin.file.line"] = e.Source.Line
+		doc["log.origin.function"] = e.Source.Function
+	}
+
+	return json.NewEncoder(buf).Encode(doc)
+}
+
+// NewGCPEncoder writes the JSON line of Cloud Logging structured logging: the access and outbound fields
+// are moved to the `httpRequest` object, and the trace, span and source location to their special keys.
+func NewGCPEncoder(projectID string) Encoder {
+	projectID = strings.TrimSpace(projectID)
+
+	return func(buf *bytes.Buffer, e Entry, _ map[string]any) error {
+		fields := e.fieldMap()
+		doc := make(map[string]any, len(fields)+6)
+		for k, v := range fields {
+			doc[k] = v
+		}
+
+		if req := gcpHTTPRequest(fields); req != nil {
+			for _, k := range gcpHTTPRequestFields {
+				delete(doc, k)
+			}
+			doc["httpRequest"] = req
+		}
+
+		doc["timestamp"] = e.Timestamp.Format(time.RFC3339Nano)
+		doc["severity"] = e.Severity
+		doc["level"] = e.Level
+		doc["logging.googleapis.com/labels"] = map[string]string{"level": e.Level}
+
+		if e.Trace.TraceID != "" {
+			trace := e.Trace.TraceID
+			if projectID != "" && !strings.HasPrefix(trace, "projects/") {
+				trace = "projects/" + projectID + "/traces/" + trace
+			}
+			doc["logging.googleapis.com/trace"] = trace
+		}
+		if e.Trace.SpanID != "" {
+			doc["logging.googleapis.com/spanId"] = e.Trace.SpanID
+		}
+		if e.Source != nil {
+			doc["logging.googleapis.com/sourceLocation"] = map[string]string{
+				"file":     e.Source.File,
+				"line":     strconv.Itoa(e.Source.Line),
+				"function": e.Source.Function,
+			}
+		}
+
+		return json.NewEncoder(buf).Encode(doc)
+	}
+}
+
+var gcpHTTPRequestFields = []string{"method", "uri", "url", "status", "user_agent", "remote_ip", "latency", "latency_ms", "bytes_in", "bytes_out"}
+
+// gcpHTTPRequest builds the `HttpRequest` of the LogEntry, nil if the fields aren't about a request.
+func gcpHTTPRequest(fields map[string]any) map[string]any {
+	method, ok := fields["method"].(string)
+	if !ok || method == "" {
+		return nil
+	}
+
+	req := map[string]any{"requestMethod": method}
+	if v, ok := fields["uri"].(string); ok {
+		req["requestUrl"] = v
+	} else if v, ok := fields["url"].(string); ok {
+		req["requestUrl"] = v
+	}
+	if v, ok := toInt64(fields["status"]); ok && v > 0 {
+		req["status"] = v
+	}
+	if v, ok := fields["user_agent"].(string); ok && v != "" {
+		req["userAgent"] = v
+	}
+	if v, ok := fields["remote_ip"].(string); ok && v != "" {
+		req["remoteIp"] = v
+	}
+
+	// The latency is a protobuf Duration, e.g. `0.123456789s`.
+	if v, ok := toInt64(fields["latency"]); ok {
+		req["latency"] = strconv.FormatFloat(time.Duration(v).Seconds(), 'f', -1, 64) + "s"
+	} else if v, ok := toInt64(fields["latency_ms"]); ok {
+		req["latency"] = strconv.FormatFloat((time.Duration(v)*time.Millisecond).Seconds(), 'f', -1, 64) + "s"
+	}
+
+	// int64 values are strings in the JSON of protobuf.
+	if v, ok := toInt64(fields["bytes_in"]); ok {
+		req["requestSize"] = strconv.FormatInt(v, 10)
+	}
+	if v, ok := toInt64(fields["bytes_out"]); ok {
+		req["responseSize"] = strconv.FormatInt(v, 10)
+	}
+
+	return req
+}
+
+// EMFConfig configures the metrics extracted by the CloudWatch Embedded Metric Format encoder.
+type EMFConfig struct {
+	// Namespace of the metrics. Default `bean`.
+	Namespace string
+	// Dimensions are the sets of fields the metrics are aggregated by. A set is only used when the entry has
+	// all of its fields. Default `[["level"]]`.
+	Dimensions [][]string
+	// Metrics maps the fields written as metrics to their unit, e.g. `Milliseconds`, `Bytes` or `Count`.
+	// Default `latency_ms` in milliseconds.
+	Metrics map[string]string
+}
+
+// NewEMFEncoder writes a JSON line with the `_aws` metadata of the CloudWatch Embedded Metric Format,
+// so CloudWatch Logs turns the numeric fields of `Metrics` into metrics. The `latency_ms` field is
+// added from `latency` when it's missing. Entries without any of the metrics are plain JSON lines.
+func NewEMFEncoder(cfg EMFConfig) Encoder {
+	if cfg.Namespace == "" {
+		cfg.Namespace = "bean"
+	}
+	if len(cfg.Dimensions) == 0 {
+		cfg.Dimensions = [][]string{{"level"}}
+	}
+	if len(cfg.Metrics) == 0 {
+		cfg.Metrics = map[string]string{"latency_ms": "Milliseconds"}
+	}
+	metricNames := make([]string, 0, len(cfg.Metrics))
+	for name := range cfg.Metrics {
+		metricNames = append(metricNames, name)
+	}
+	sort.Strings(metricNames)
+
+	return func(buf *bytes.Buffer, e Entry, payload map[string]any) error {
+		if _, ok := payload["latency_ms"]; !ok {
+			if v, ok := toInt64(payload["latency"]); ok {
+				payload["latency_ms"] = float64(v) / float64(time.Millisecond)
+			}
+		}
+
+		metrics := make([]map[string]string, 0, len(metricNames))
+		for _, name := range metricNames {
+			v, ok := toFloat64(payload[name])
+			if !ok {
+				continue
+			}
+			// The values of the metrics must be numbers.
+			payload[name] = v
+			metrics = append(metrics, map[string]string{"Name": name, "Unit": cfg.Metrics[name]})
+		}
+		if len(metrics) == 0 {
+			return encodeJSONLine(buf, e, payload)
+		}
+
+		dimensions := make([][]string, 0, len(cfg.Dimensions))
+	sets:
+		for _, set := range cfg.Dimensions {
+			for _, k := range set {
+				if _, ok := payload[k]; !ok {
+					continue sets
+				}
+			}
+			dimensions = append(dimensions, set)
+			// The values of the dimensions must be strings.
+			for _, k := range set {
+				if _, ok := payload[k].(string); !ok {
+					payload[k] = fmt.Sprint(payload[k])
+				}
+			}
+		}
+
+		payload["_aws"] = map[string]any{
+			"Timestamp": e.Timestamp.UnixMilli(),
+			"CloudWatchMetrics": []map[string]any{{
+				"Namespace":  cfg.Namespace,
+				"Dimensions": dimensions,
+				"Metrics":    metrics,
+			}},
+		}
+
+		return encodeJSONLine(buf, e, payload)
+	}
+}
+
+func toInt64(v any) (int64, bool) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		return int64(v), true
+	case float64:
+		return int64(v), true
+	case time.Duration:
+		return int64(v), true
+	case json.Number:
+		i, err := v.Int64()
+		return i, err == nil
+	case string:
+		i, err := strconv.ParseInt(v, 10, 64)
+		return i, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func toFloat64(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	default:
+		i, ok := toInt64(v)
+		return float64(i), ok
+	}
+}
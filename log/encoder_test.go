@@ -0,0 +1,172 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func accessEntry() Entry {
+	return Entry{
+		Timestamp: time.Date(2026, 1, 2, 3, 4, 5, 6000, time.UTC),
+		Severity:  Info,
+		Level:     "ACCESS",
+		Fields: map[string]any{
+			"id":         "req-1",
+			"method":     "GET",
+			"uri":        "/users/1?q=a b",
+			"route":      "/users/:id",
+			"status":     200,
+			"latency":    int64(1500 * time.Microsecond),
+			"bytes_in":   "12",
+			"bytes_out":  int64(34),
+			"user_agent": "curl/8.0",
+			"remote_ip":  "10.0.0.1",
+			"request_header": map[string]any{
+				"X-Request-Id": "req-1",
+			},
+		},
+		Trace: Trace{TraceID: "0af7651916cd43dd8448eb211c80319c", SpanID: "b7ad6b7169203331"},
+	}
+}
+
+func encode(t *testing.T, encoder Encoder, e Entry) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	s, err := NewSink(NopWriteCloser{Writer: &buf}, "trace", SinkConfig{Encoder: encoder})
+	require.NoError(t, err)
+	require.NoError(t, s.Write(e))
+	return buf.String()
+}
+
+func decode(t *testing.T, line string) map[string]any {
+	t.Helper()
+
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal([]byte(line), &doc))
+	return doc
+}
+
+func TestEncodeLogfmt(t *testing.T) {
+	line := encode(t, EncodeLogfmt, accessEntry())
+
+	assert.True(t, strings.HasPrefix(line, "time=2026-01-02T03:04:05.000006Z severity=INFO level=ACCESS "), line)
+	assert.True(t, strings.HasSuffix(line, "\n"))
+	assert.Contains(t, line, ` uri="/users/1?q=a b" `)
+	assert.Contains(t, line, " status=200 ")
+	assert.Contains(t, line, " request_header.X-Request-Id=req-1 ")
+	assert.Contains(t, line, " trace=0af7651916cd43dd8448eb211c80319c")
+}
+
+func TestEncodeECS(t *testing.T) {
+	doc := decode(t, encode(t, EncodeECS, accessEntry()))
+
+	assert.Equal(t, "2026-01-02T03:04:05.000006Z", doc["@timestamp"])
+	assert.Equal(t, "info", doc["log.level"])
+	assert.Equal(t, "ACCESS", doc["log.logger"])
+	assert.Equal(t, "GET", doc["http.request.method"])
+	assert.Equal(t, float64(200), doc["http.response.status_code"])
+	assert.Equal(t, float64(1500*time.Microsecond), doc["event.duration"])
+	assert.Equal(t, float64(12), doc["http.request.body.bytes"])
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", doc["trace.id"])
+	assert.Equal(t, "b7ad6b7169203331", doc["span.id"])
+	assert.Equal(t, "/users/:id", doc["route"])
+	assert.NotContains(t, doc, "latency")
+}
+
+func TestGCPEncoder(t *testing.T) {
+	e := accessEntry()
+	e.Source = &SourceLocation{File: "main.go", Line: 42, Function: "main.handler"}
+	doc := decode(t, encode(t, NewGCPEncoder("my-project"), e))
+
+	assert.Equal(t, "INFO", doc["severity"])
+	assert.Equal(t, "projects/my-project/traces/0af7651916cd43dd8448eb211c80319c", doc["logging.googleapis.com/trace"])
+	assert.Equal(t, "b7ad6b7169203331", doc["logging.googleapis.com/spanId"])
+	assert.Equal(t, map[string]any{"file": "main.go", "line": "42", "function": "main.handler"}, doc["logging.googleapis.com/sourceLocation"])
+	assert.Equal(t, map[string]any{
+		"requestMethod": "GET",
+		"requestUrl":    "/users/1?q=a b",
+		"status":        float64(200),
+		"userAgent":     "curl/8.0",
+		"remoteIp":      "10.0.0.1",
+		"latency":       "0.0015s",
+		"requestSize":   "12",
+		"responseSize":  "34",
+	}, doc["httpRequest"])
+	assert.NotContains(t, doc, "method")
+	assert.Equal(t, "req-1", doc["id"])
+
+	// Entries which aren't about a request have no `httpRequest`.
+	doc = decode(t, encode(t, NewGCPEncoder(""), testEntry("APP", map[string]any{"message": "hi"})))
+	assert.NotContains(t, doc, "httpRequest")
+	assert.Equal(t, "trace-1", doc["logging.googleapis.com/trace"])
+}
+
+func TestEMFEncoder(t *testing.T) {
+	encoder := NewEMFEncoder(EMFConfig{
+		Namespace:  "shop",
+		Dimensions: [][]string{{"level"}, {"level", "route"}, {"level", "missing"}},
+		Metrics:    map[string]string{"latency_ms": "Milliseconds", "bytes_in": "Bytes"},
+	})
+	doc := decode(t, encode(t, encoder, accessEntry()))
+
+	assert.Equal(t, 1.5, doc["latency_ms"])
+	assert.Equal(t, float64(12), doc["bytes_in"])
+	assert.Equal(t, map[string]any{
+		"Timestamp": float64(accessEntry().Timestamp.UnixMilli()),
+		"CloudWatchMetrics": []any{map[string]any{
+			"Namespace":  "shop",
+			"Dimensions": []any{[]any{"level"}, []any{"level", "route"}},
+			"Metrics": []any{
+				map[string]any{"Name": "bytes_in", "Unit": "Bytes"},
+				map[string]any{"Name": "latency_ms", "Unit": "Milliseconds"},
+			},
+		}},
+	}, doc["_aws"])
+
+	// No metric, no metadata.
+	doc = decode(t, encode(t, encoder, testEntry("APP", map[string]any{"message": "hi"})))
+	assert.NotContains(t, doc, "_aws")
+}
+
+func TestNewEncoder(t *testing.T) {
+	for _, format := range []string{"LOGFMT", "ecs", "gcp", "emf"} {
+		encoder, err := NewEncoder(EncoderConfig{Format: format})
+		assert.NoError(t, err, format)
+		assert.NotNil(t, encoder, format)
+	}
+
+	// The sinks write JSON without an encoder.
+	for _, format := range []string{"", "json"} {
+		encoder, err := NewEncoder(EncoderConfig{Format: format})
+		assert.NoError(t, err, format)
+		assert.Nil(t, encoder, format)
+	}
+
+	_, err := NewEncoder(EncoderConfig{Format: "xml"})
+	assert.Error(t, err)
+}
+
+func TestLogger_SourceLocation(t *testing.T) {
+	var buf bytes.Buffer
+	e := echo.New()
+	e.Logger.SetOutput(&buf)
+
+	l, err := NewLogger(e.Logger, WithEncoding(EncoderConfig{Format: EncodingGCP}))
+	require.NoError(t, err)
+	l.TraceInfo(context.Background(), "APP", map[string]any{"message": "hi"})
+
+	doc := decode(t, buf.String())
+	loc, ok := doc["logging.googleapis.com/sourceLocation"].(map[string]any)
+	require.True(t, ok, buf.String())
+	assert.True(t, strings.HasSuffix(loc["file"].(string), "encoder_test.go"), loc["file"])
+	assert.Contains(t, loc["function"], "TestLogger_SourceLocation")
+}
@@ -0,0 +1,275 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// SinkRoute sends the entries matching its filters to a sink.
+type SinkRoute struct {
+	Sink Sink
+	// MinSeverity drops the entries below it, e.g. `Error` keeps ERROR and CRITICAL. All entries if empty.
+	MinSeverity Severity
+	// Levels keeps the entries of these levels only (`ACCESS`, `DUMP`, `OUTBOUND_API`...). All levels if empty.
+	Levels []string
+	// Filter drops the entries it returns false for, e.g. `SampleFilter(0.1)`.
+	Filter func(Entry) bool
+	// Processors run on the entry of this route only, after the processors of the pipeline.
+	Processors []Processor
+	// Async writes from a goroutine of the route with a bounded queue, dropping the entries when
+	// it's full, so a stalled sink never blocks the request nor the other routes.
+	Async     bool
+	QueueSize int
+}
+
+// SampleFilter keeps about `rate` (0 to 1) of the entries.
+func SampleFilter(rate float64) func(Entry) bool {
+	return func(Entry) bool {
+		return rate >= 1 || (rate > 0 && rand.Float64() < rate)
+	}
+}
+
+// FanoutSink writes every entry to the routes it matches. A failing route doesn't stop the others.
+type FanoutSink struct {
+	routes []*route
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+type route struct {
+	SinkRoute
+	minRank int
+	// clone gives the route its own copy of the fields, its processors may change them in place.
+	clone bool
+
+	queue   chan Entry
+	wg      sync.WaitGroup
+	dropped atomic.Uint64
+	// failed counts the async writes the sink returned an error for, lastErr keeps the latest one.
+	failed  atomic.Uint64
+	lastErr atomic.Pointer[error]
+}
+
+func NewFanoutSink(routes ...SinkRoute) *FanoutSink {
+	f := &FanoutSink{routes: make([]*route, 0, len(routes))}
+
+	for _, r := range routes {
+		rt := &route{
+			SinkRoute: r,
+			minRank:   r.MinSeverity.rank(),
+			clone:     len(r.Processors) > 0 && len(routes) > 1,
+		}
+		if r.Async {
+			qsize := r.QueueSize
+			if qsize <= 0 {
+				qsize = defaultAsyncQueueSize
+			}
+			rt.queue = make(chan Entry, qsize)
+			rt.wg.Add(1)
+			go rt.run()
+		}
+		f.routes = append(f.routes, rt)
+	}
+
+	return f
+}
+
+func (f *FanoutSink) Write(e Entry) error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.closed {
+		return ErrSinkClosed
+	}
+
+	var errs []error
+	for _, r := range f.routes {
+		if !r.match(e) {
+			continue
+		}
+
+		entry := e
+		if r.clone {
+			entry.Fields = cloneFields(e.Fields)
+		}
+
+		if !r.Async {
+			if err := r.write(entry); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		select {
+		case r.queue <- entry:
+		default:
+			r.dropped.Add(1)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close drains the queue of every route, then closes their sinks, all within `ctx`. The sinks are
+// closed even when the drain times out, and the failed async writes are reported.
+func (f *FanoutSink) Close(ctx context.Context) error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return nil
+	}
+	f.closed = true
+	f.mu.Unlock()
+
+	errs := make([]error, len(f.routes))
+	var wg sync.WaitGroup
+	for i, r := range f.routes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = r.close(ctx)
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return errors.Join(errs...)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WriteErrorCount returns the number of entries the sinks of the async routes failed to write.
+// `Close` reports them too.
+func (f *FanoutSink) WriteErrorCount() uint64 {
+	var n uint64
+	for _, r := range f.routes {
+		n += r.failed.Load()
+	}
+	return n
+}
+
+// DroppedCount returns the number of entries dropped by the async routes.
+func (f *FanoutSink) DroppedCount() uint64 {
+	var n uint64
+	for _, r := range f.routes {
+		n += r.dropped.Load()
+	}
+	return n
+}
+
+func (r *route) match(e Entry) bool {
+	if r.minRank > 0 && e.Severity.rank() < r.minRank {
+		return false
+	}
+	if len(r.Levels) > 0 && !slices.Contains(r.Levels, e.Level) {
+		return false
+	}
+	return r.Filter == nil || r.Filter(e)
+}
+
+func (r *route) write(e Entry) error {
+	if e.Fields != nil {
+		for _, p := range r.Processors {
+			e = p.Process(e)
+		}
+	}
+	return r.Sink.Write(e)
+}
+
+func (r *route) run() {
+	defer r.wg.Done()
+	for e := range r.queue {
+		if err := r.write(e); err != nil {
+			r.failed.Add(1)
+			r.lastErr.Store(&err)
+		}
+	}
+}
+
+// close drains the queue of an async route, then closes the sink even if the drain timed out.
+func (r *route) close(ctx context.Context) error {
+	var drainErr error
+	if r.Async {
+		close(r.queue)
+
+		done := make(chan struct{})
+		go func() {
+			r.wg.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			drainErr = ctx.Err()
+		}
+	}
+
+	var writeErr error
+	if err := r.lastErr.Load(); err != nil {
+		writeErr = fmt.Errorf("log sink: %d async writes failed, the last one: %w", r.failed.Load(), *err)
+	}
+
+	var closeErr error
+	if c, ok := r.Sink.(interface{ Close(context.Context) error }); ok {
+		closeErr = c.Close(ctx)
+	}
+	return errors.Join(drainErr, writeErr, closeErr)
+}
+
+// rank orders the severities, 0 for an empty or unknown one.
+func (s Severity) rank() int {
+	switch Severity(strings.ToUpper(string(s))) {
+	case Debug:
+		return 1
+	case Info:
+		return 2
+	case Warning:
+		return 3
+	case Error:
+		return 4
+	case Critical:
+		return 5
+	default:
+		return 0
+	}
+}
+
+func cloneFields(fields map[string]any) map[string]any {
+	if fields == nil {
+		return nil
+	}
+	return cloneValue(fields).(map[string]any)
+}
+
+func cloneValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, vv := range v {
+			m[k] = cloneValue(vv)
+		}
+		return m
+	case []any:
+		s := make([]any, len(v))
+		for i, vv := range v {
+			s[i] = cloneValue(vv)
+		}
+		return s
+	default:
+		return v
+	}
+}
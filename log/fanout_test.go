@@ -0,0 +1,141 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memorySink struct {
+	mu      sync.Mutex
+	entries []Entry
+	block   chan struct{}
+	closed  bool
+}
+
+func (s *memorySink) Write(e Entry) error {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+func (s *memorySink) Close(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *memorySink) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+type setFieldProcessor struct{ key, value string }
+
+func (p setFieldProcessor) Process(e Entry) Entry {
+	e.Fields[p.key] = p.value
+	return e
+}
+
+func TestFanoutSink_Routes(t *testing.T) {
+	all, errs, access := &memorySink{}, &memorySink{}, &memorySink{}
+
+	f := NewFanoutSink(
+		SinkRoute{Sink: all},
+		SinkRoute{Sink: errs, MinSeverity: Error, Processors: []Processor{setFieldProcessor{"secret", "****"}}},
+		SinkRoute{Sink: access, Levels: []string{"ACCESS"}, Async: true},
+	)
+
+	require.NoError(t, f.Write(Entry{Severity: Info, Level: "ACCESS", Fields: map[string]any{"secret": "s"}}))
+	require.NoError(t, f.Write(Entry{Severity: Error, Level: "DUMP", Fields: map[string]any{"secret": "s"}}))
+	require.NoError(t, f.Write(Entry{Severity: Critical, Level: "ACCESS", Fields: map[string]any{"secret": "s"}}))
+	require.NoError(t, f.Close(context.Background()))
+
+	assert.Equal(t, 3, all.len())
+	assert.Equal(t, 2, errs.len())
+	assert.Equal(t, 2, access.len())
+
+	// The processors of a route don't change the entries of the others.
+	assert.Equal(t, "****", errs.entries[0].Fields["secret"])
+	assert.Equal(t, "s", all.entries[1].Fields["secret"])
+
+	assert.True(t, all.closed && errs.closed && access.closed)
+	assert.ErrorIs(t, f.Write(Entry{Severity: Info}), ErrSinkClosed)
+}
+
+func TestFanoutSink_StalledRoute(t *testing.T) {
+	stalled := &memorySink{block: make(chan struct{})}
+	healthy := &memorySink{}
+
+	f := NewFanoutSink(
+		SinkRoute{Sink: stalled, Async: true, QueueSize: 2},
+		SinkRoute{Sink: healthy},
+	)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			_ = f.Write(Entry{Severity: Info, Level: "ACCESS", Fields: map[string]any{"i": i}})
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("a stalled route blocked the writes")
+	}
+	assert.Equal(t, 10, healthy.len())
+	assert.Positive(t, f.DroppedCount())
+
+	// Close gives up on the stalled route when the context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, f.Close(ctx), context.DeadlineExceeded)
+	// The sink is closed anyway.
+	assert.Eventually(t, func() bool {
+		stalled.mu.Lock()
+		defer stalled.mu.Unlock()
+		return stalled.closed
+	}, time.Second, 10*time.Millisecond)
+	close(stalled.block)
+}
+
+type failingRouteSink struct{ memorySink }
+
+var errFailingRouteSink = errors.New("sink is down")
+
+func (s *failingRouteSink) Write(Entry) error { return errFailingRouteSink }
+
+func TestFanoutSink_AsyncWriteErrors(t *testing.T) {
+	failing := &failingRouteSink{}
+	f := NewFanoutSink(SinkRoute{Sink: failing, Async: true})
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, f.Write(Entry{Severity: Info, Level: "ACCESS"}))
+	}
+
+	err := f.Close(context.Background())
+	assert.ErrorIs(t, err, errFailingRouteSink)
+	assert.Contains(t, err.Error(), "3 async writes failed")
+	assert.EqualValues(t, 3, f.WriteErrorCount())
+	assert.True(t, failing.closed)
+}
+
+func TestSeverity_rank(t *testing.T) {
+	assert.Less(t, Debug.rank(), Info.rank())
+	assert.Less(t, Warning.rank(), Error.rank())
+	assert.Equal(t, Error.rank(), Severity("error").rank())
+	assert.Zero(t, Severity("").rank())
+}
@@ -0,0 +1,165 @@
+package log
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// Kind is the type of the value of a `Field`.
+type Kind uint8
+
+const (
+	KindAny Kind = iota
+	KindString
+	KindInt64
+	KindUint64
+	KindFloat64
+	KindBool
+	KindDuration
+	KindTime
+	KindRawJSON
+)
+
+// Field is a typed key-value pair of an entry. Scalars are kept unboxed, so the JSON sink writes
+// them without allocating, unlike the values of `Entry.Fields`.
+type Field struct {
+	Key  string
+	kind Kind
+	num  uint64
+	str  string
+	any  any
+}
+
+func String(key, value string) Field {
+	return Field{Key: key, kind: KindString, str: value}
+}
+
+func Int(key string, value int) Field {
+	return Int64(key, int64(value))
+}
+
+func Int64(key string, value int64) Field {
+	return Field{Key: key, kind: KindInt64, num: uint64(value)}
+}
+
+func Uint64(key string, value uint64) Field {
+	return Field{Key: key, kind: KindUint64, num: value}
+}
+
+func Float64(key string, value float64) Field {
+	return Field{Key: key, kind: KindFloat64, num: math.Float64bits(value)}
+}
+
+func Bool(key string, value bool) Field {
+	var num uint64
+	if value {
+		num = 1
+	}
+	return Field{Key: key, kind: KindBool, num: num}
+}
+
+// Duration is written as its number of nanoseconds, like `encoding/json` does.
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, kind: KindDuration, num: uint64(value)}
+}
+
+// Time is written in RFC 3339 with nanoseconds.
+func Time(key string, value time.Time) Field {
+	return Field{Key: key, kind: KindTime, num: uint64(value.UnixNano()), any: value.Location()}
+}
+
+// RawJSON is written as is when it's valid JSON, compacted, and as a string otherwise.
+func RawJSON(key string, value []byte) Field {
+	return Field{Key: key, kind: KindRawJSON, any: json.RawMessage(value)}
+}
+
+// Err is the `error` field of the message of err, or null.
+func Err(err error) Field {
+	if err == nil {
+		return Any("error", nil)
+	}
+	return String("error", err.Error())
+}
+
+// Any keeps the type of the scalars it knows and boxes the other values.
+func Any(key string, value any) Field {
+	switch v := value.(type) {
+	case string:
+		return String(key, v)
+	case int:
+		return Int(key, v)
+	case int64:
+		return Int64(key, v)
+	case uint64:
+		return Uint64(key, v)
+	case float64:
+		return Float64(key, v)
+	case bool:
+		return Bool(key, v)
+	case time.Duration:
+		return Duration(key, v)
+	case time.Time:
+		return Time(key, v)
+	case json.RawMessage:
+		return RawJSON(key, v)
+	default:
+		return Field{Key: key, kind: KindAny, any: v}
+	}
+}
+
+func (f Field) Kind() Kind {
+	return f.kind
+}
+
+// Value returns the value boxed, for the processors and encoders working on `map[string]any`.
+func (f Field) Value() any {
+	switch f.kind {
+	case KindString:
+		return f.str
+	case KindInt64:
+		return int64(f.num)
+	case KindUint64:
+		return f.num
+	case KindFloat64:
+		return math.Float64frombits(f.num)
+	case KindBool:
+		return f.num == 1
+	case KindDuration:
+		return time.Duration(f.num)
+	case KindTime:
+		return f.time()
+	default:
+		return f.any
+	}
+}
+
+func (f Field) time() time.Time {
+	t := time.Unix(0, int64(f.num))
+	if loc, ok := f.any.(*time.Location); ok {
+		t = t.In(loc)
+	}
+	return t
+}
+
+// appendJSON appends the value of the field.
+func (f Field) appendJSON(dst []byte) []byte {
+	switch f.kind {
+	case KindString:
+		return appendJSONString(dst, f.str)
+	case KindInt64:
+		return appendInt(dst, int64(f.num))
+	case KindUint64:
+		return appendUint(dst, f.num)
+	case KindFloat64:
+		return appendJSONFloat(dst, math.Float64frombits(f.num), 64)
+	case KindBool:
+		return appendBool(dst, f.num == 1)
+	case KindDuration:
+		return appendInt(dst, int64(f.num))
+	case KindTime:
+		return appendJSONTime(dst, f.time())
+	default:
+		return appendJSONValue(dst, f.any)
+	}
+}
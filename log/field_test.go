@@ -0,0 +1,86 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestField_Value(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 6, time.FixedZone("JST", 9*3600))
+
+	tests := []struct {
+		field Field
+		kind  Kind
+		value any
+	}{
+		{String("k", "v"), KindString, "v"},
+		{Int("k", -1), KindInt64, int64(-1)},
+		{Uint64("k", 1), KindUint64, uint64(1)},
+		{Float64("k", 1.5), KindFloat64, 1.5},
+		{Bool("k", true), KindBool, true},
+		{Duration("k", time.Second), KindDuration, time.Second},
+		{Any("k", 3), KindInt64, int64(3)},
+		{Any("k", []int{1}), KindAny, []int{1}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.kind, tt.field.Kind())
+		assert.Equal(t, tt.value, tt.field.Value())
+	}
+
+	f := Time("k", now)
+	assert.Equal(t, KindTime, f.Kind())
+	assert.True(t, now.Equal(f.Value().(time.Time)))
+	assert.Equal(t, `"2026-01-02T03:04:05.000000006+09:00"`, string(f.appendJSON(nil)))
+}
+
+func TestLogger_Log(t *testing.T) {
+	var buf bytes.Buffer
+	e := echo.New()
+	e.Logger.SetOutput(&buf)
+
+	l, err := NewLogger(e.Logger, WithMaskFields([]string{"password"}))
+	require.NoError(t, err)
+
+	var fl FieldLogger = l
+	fl.Log(context.Background(), Warning, "APP",
+		String("message", "login"),
+		Int("attempts", 3),
+		Duration("elapsed", 2*time.Millisecond),
+		String("password", "secret"),
+	)
+
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, "WARNING", doc["severity"])
+	assert.Equal(t, "APP", doc["level"])
+	assert.Equal(t, "login", doc["message"])
+	assert.Equal(t, float64(3), doc["attempts"])
+	assert.Equal(t, float64(2*time.Millisecond), doc["elapsed"])
+	assert.Equal(t, "****", doc["password"])
+}
+
+func BenchmarkLogger_Log(b *testing.B) {
+	e := echo.New()
+	e.Logger.SetOutput(io.Discard)
+	l, _ := NewLogger(e.Logger, WithMaskFields([]string{"password"}))
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.Log(ctx, Info, "ACCESS",
+			String("method", "POST"),
+			String("uri", "/api/v1/orders"),
+			Int("status", 200),
+			Int64("latency_ms", 38),
+			String("user_agent", "Go-http-client/2.0"),
+		)
+	}
+}
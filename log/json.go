@@ -0,0 +1,385 @@
+package log
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// The JSON encoder of the sinks appends to a byte slice with a type switch on the values, in a
+// deterministic order: `timestamp`, `severity` and `level`, the fields sorted by key, the typed
+// fields in order, then the trace id, span id and sampling decision. Values it doesn't know go through
+// `encoding/json`.
+
+var keysPool = sync.Pool{
+	New: func() any {
+		keys := make([]string, 0, 16)
+		return &keys
+	},
+}
+
+// appendEntryJSON appends an entry as a line of JSON. A field overrides the key of the same name
+// written before it, except the trace keys which are written last.
+func appendEntryJSON(dst []byte, e Entry, payloadTrace string) []byte {
+	traced := e.Trace.TraceID != ""
+	spanKey, sampledKey := spanPayloadKeys(payloadTrace)
+	spanned := traced && e.Trace.SpanID != ""
+	skip := func(k string) bool {
+		return traced && k == payloadTrace || spanned && (k == spanKey || k == sampledKey)
+	}
+
+	dst = append(dst, '{')
+
+	if !e.hasKey("timestamp") {
+		dst = appendKey(dst, "timestamp")
+		dst = appendJSONTime(dst, e.Timestamp)
+	}
+	if !e.hasKey("severity") {
+		dst = appendKey(dst, "severity")
+		dst = appendJSONString(dst, string(e.Severity))
+	}
+	if !e.hasKey("level") {
+		dst = appendKey(dst, "level")
+		dst = appendJSONString(dst, e.Level)
+	}
+
+	if len(e.Fields) > 0 {
+		kp := keysPool.Get().(*[]string)
+		keys := (*kp)[:0]
+		for k := range e.Fields {
+			if skip(k) || e.hasAttr(k) {
+				continue
+			}
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			dst = appendKey(dst, k)
+			dst = appendJSONValue(dst, e.Fields[k])
+		}
+		clear(keys)
+		*kp = keys[:0]
+		keysPool.Put(kp)
+	}
+
+	for _, f := range e.Attrs {
+		if skip(f.Key) {
+			continue
+		}
+		dst = appendKey(dst, f.Key)
+		dst = f.appendJSON(dst)
+	}
+
+	if traced {
+		dst = appendKey(dst, payloadTrace)
+		dst = appendJSONString(dst, e.Trace.TraceID)
+	}
+	if spanned {
+		dst = appendKey(dst, spanKey)
+		dst = appendJSONString(dst, e.Trace.SpanID)
+		dst = appendKey(dst, sampledKey)
+		dst = strconv.AppendBool(dst, e.Trace.Sampled)
+	}
+
+	return append(dst, '}', '\n')
+}
+
+// appendKey appends the key of an object member, after a comma unless it's the first member.
+func appendKey(dst []byte, k string) []byte {
+	if n := len(dst); n > 0 && dst[n-1] != '{' {
+		dst = append(dst, ',')
+	}
+	dst = appendJSONString(dst, k)
+	return append(dst, ':')
+}
+
+// hasKey reports whether the fields override a key written before them.
+func (e Entry) hasKey(k string) bool {
+	if _, ok := e.Fields[k]; ok {
+		return true
+	}
+	return e.hasAttr(k)
+}
+
+func (e Entry) hasAttr(k string) bool {
+	for _, f := range e.Attrs {
+		if f.Key == k {
+			return true
+		}
+	}
+	return false
+}
+
+// appendJSONValue appends v as `encoding/json` would, except errors which are written as their message
+// and the floats which JSON can't hold, written as strings.
+func appendJSONValue(dst []byte, v any) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(dst, "null"...)
+	case string:
+		return appendJSONString(dst, v)
+	case Severity:
+		return appendJSONString(dst, string(v))
+	case bool:
+		return appendBool(dst, v)
+	case int:
+		return appendInt(dst, int64(v))
+	case int8:
+		return appendInt(dst, int64(v))
+	case int16:
+		return appendInt(dst, int64(v))
+	case int32:
+		return appendInt(dst, int64(v))
+	case int64:
+		return appendInt(dst, v)
+	case uint:
+		return appendUint(dst, uint64(v))
+	case uint8:
+		return appendUint(dst, uint64(v))
+	case uint16:
+		return appendUint(dst, uint64(v))
+	case uint32:
+		return appendUint(dst, uint64(v))
+	case uint64:
+		return appendUint(dst, v)
+	case float32:
+		return appendJSONFloat(dst, float64(v), 32)
+	case float64:
+		return appendJSONFloat(dst, v, 64)
+	case time.Duration:
+		return appendInt(dst, int64(v))
+	case time.Time:
+		return appendJSONTime(dst, v)
+	case json.Number:
+		if v == "" {
+			return append(dst, '0')
+		}
+		return appendRawJSON(dst, string(v))
+	case json.RawMessage:
+		if v == nil {
+			return append(dst, "null"...)
+		}
+		return appendRawJSON(dst, []byte(v))
+	case []byte:
+		if v == nil {
+			return append(dst, "null"...)
+		}
+		dst = append(dst, '"')
+		dst = base64.StdEncoding.AppendEncode(dst, v)
+		return append(dst, '"')
+	case Field:
+		return v.appendJSON(dst)
+	case map[string]any:
+		return appendJSONMap(dst, v)
+	case map[string]string:
+		if v == nil {
+			return append(dst, "null"...)
+		}
+		kp := keysPool.Get().(*[]string)
+		keys := (*kp)[:0]
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		dst = append(dst, '{')
+		for i, k := range keys {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendJSONString(dst, k)
+			dst = append(dst, ':')
+			dst = appendJSONString(dst, v[k])
+		}
+		clear(keys)
+		*kp = keys[:0]
+		keysPool.Put(kp)
+		return append(dst, '}')
+	case []any:
+		if v == nil {
+			return append(dst, "null"...)
+		}
+		dst = append(dst, '[')
+		for i, vv := range v {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendJSONValue(dst, vv)
+		}
+		return append(dst, ']')
+	case []string:
+		if v == nil {
+			return append(dst, "null"...)
+		}
+		dst = append(dst, '[')
+		for i, vv := range v {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendJSONString(dst, vv)
+		}
+		return append(dst, ']')
+	case json.Marshaler:
+		return appendMarshal(dst, v)
+	case error:
+		return appendJSONString(dst, v.Error())
+	default:
+		return appendMarshal(dst, v)
+	}
+}
+
+func appendJSONMap(dst []byte, m map[string]any) []byte {
+	if m == nil {
+		return append(dst, "null"...)
+	}
+
+	kp := keysPool.Get().(*[]string)
+	keys := (*kp)[:0]
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	dst = append(dst, '{')
+	for i, k := range keys {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = appendJSONString(dst, k)
+		dst = append(dst, ':')
+		dst = appendJSONValue(dst, m[k])
+	}
+
+	clear(keys)
+	*kp = keys[:0]
+	keysPool.Put(kp)
+
+	return append(dst, '}')
+}
+
+func appendMarshal(dst []byte, v any) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return appendJSONString(dst, "!ERROR: "+err.Error())
+	}
+	return append(dst, b...)
+}
+
+// appendRawJSON appends raw compacted, or as a string if it isn't valid JSON.
+func appendRawJSON[T string | []byte](dst []byte, raw T) []byte {
+	if out, ok := compactJSON(dst, raw); ok {
+		return out
+	}
+	return appendJSONString(dst, raw)
+}
+
+func appendBool(dst []byte, v bool) []byte {
+	if v {
+		return append(dst, "true"...)
+	}
+	return append(dst, "false"...)
+}
+
+func appendInt(dst []byte, v int64) []byte {
+	return strconv.AppendInt(dst, v, 10)
+}
+
+func appendUint(dst []byte, v uint64) []byte {
+	return strconv.AppendUint(dst, v, 10)
+}
+
+func appendJSONTime(dst []byte, t time.Time) []byte {
+	dst = append(dst, '"')
+	dst = t.AppendFormat(dst, time.RFC3339Nano)
+	return append(dst, '"')
+}
+
+// appendJSONFloat formats like `encoding/json`. NaN and infinities are written as strings.
+func appendJSONFloat(dst []byte, f float64, bits int) []byte {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		dst = append(dst, '"')
+		dst = strconv.AppendFloat(dst, f, 'g', -1, bits)
+		return append(dst, '"')
+	}
+
+	abs := math.Abs(f)
+	format := byte('f')
+	if abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	dst = strconv.AppendFloat(dst, f, format, -1, bits)
+	if format == 'e' {
+		// Clean up e-09 to e-9.
+		n := len(dst)
+		if n >= 4 && dst[n-4] == 'e' && dst[n-3] == '-' && dst[n-2] == '0' {
+			dst[n-2] = dst[n-1]
+			dst = dst[:n-1]
+		}
+	}
+	return dst
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString appends s quoted and escaped as `encoding/json` does, HTML characters included.
+func appendJSONString[T string | []byte](dst []byte, s T) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				dst = append(dst, '\\', c)
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := decodeRune(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 and U+2029 break JavaScript, `encoding/json` escapes them.
+		if r == '\u2028' || r == '\u2029' {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hexDigits[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
+
+// decodeRune decodes the first rune of s without converting it.
+func decodeRune[T string | []byte](s T) (rune, int) {
+	var b [utf8.UTFMax]byte
+	n := copy(b[:], s[:min(len(s), utf8.UTFMax)])
+	return utf8.DecodeRune(b[:n])
+}
@@ -0,0 +1,120 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendJSONValue_MatchesEncodingJSON(t *testing.T) {
+	type point struct {
+		X int `json:"x"`
+	}
+
+	values := []any{
+		nil,
+		"plain",
+		`quote " backslash \ <html> & co`,
+		"line\nbreak\ttab\r\x00\x1f",
+		"unicode é 漢字    ",
+		"invalid \xff utf8",
+		true,
+		-42,
+		int8(-8),
+		uint16(16),
+		uint64(math.MaxUint64),
+		0.1,
+		1e-7,
+		1e21,
+		123456789.0,
+		float32(3.14),
+		Info,
+		90 * time.Millisecond,
+		time.Date(2026, 1, 2, 3, 4, 5, 6, time.FixedZone("JST", 9*3600)),
+		json.Number("12.50"),
+		json.RawMessage(`{ "a" : [1, 2] }`),
+		[]byte("bytes"),
+		map[string]any{"b": 1, "a": map[string]any{"z": nil, "y": []any{"x", 1.5}}},
+		map[string]string{"k2": "v2", "k1": "v1"},
+		[]string{"a", "b"},
+		[]any{},
+		point{X: 1},
+		&point{X: 2},
+	}
+
+	for _, v := range values {
+		want, err := json.Marshal(v)
+		require.NoError(t, err)
+		assert.Equal(t, string(want), string(appendJSONValue(nil, v)), "%T %v", v, v)
+	}
+}
+
+func TestAppendJSONValue_Lenient(t *testing.T) {
+	// encoding/json would fail the whole entry on these.
+	assert.Equal(t, `"NaN"`, string(appendJSONValue(nil, math.NaN())))
+	assert.Equal(t, `"+Inf"`, string(appendJSONValue(nil, math.Inf(1))))
+	assert.Equal(t, `"{ invalid"`, string(appendJSONValue(nil, json.RawMessage(`{ invalid`))))
+	assert.Equal(t, `"boom"`, string(appendJSONValue(nil, errors.New("boom"))))
+}
+
+func TestAppendEntryJSON(t *testing.T) {
+	e := Entry{
+		Timestamp: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Severity:  Warning,
+		Level:     "ACCESS",
+		Fields:    map[string]any{"z": 1, "a": "x", "trace": "from fields", "dup": "field"},
+		Attrs:     []Field{Int("dup", 2), String("b", "y")},
+		Trace:     Trace{TraceID: "t-1"},
+	}
+
+	line := string(appendEntryJSON(nil, e, "trace"))
+	assert.Equal(t, `{"timestamp":"2026-01-02T03:04:05Z","severity":"WARNING","level":"ACCESS","a":"x","z":1,"dup":2,"b":"y","trace":"t-1"}`+"\n", line)
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal([]byte(line), &decoded))
+
+	// A field overrides the fixed keys.
+	e = Entry{Level: "ACCESS", Fields: map[string]any{"level": "custom"}}
+	assert.Contains(t, string(appendEntryJSON(nil, e, "trace")), `"severity":"","level":"custom"}`)
+}
+
+func TestAppendEntryJSON_Span(t *testing.T) {
+	e := Entry{
+		Timestamp: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Severity:  Info,
+		Level:     "ACCESS",
+		Fields:    map[string]any{"span_id": "from fields"},
+		Trace:     Trace{TraceID: "t-1", SpanID: "s-1", Sampled: true},
+	}
+
+	line := string(appendEntryJSON(nil, e, "trace"))
+	assert.Equal(t, `{"timestamp":"2026-01-02T03:04:05Z","severity":"INFO","level":"ACCESS","trace":"t-1","span_id":"s-1","trace_sampled":true}`+"\n", line)
+
+	line = string(appendEntryJSON(nil, e, "logging.googleapis.com/trace"))
+	assert.Contains(t, line, `"logging.googleapis.com/trace":"t-1","logging.googleapis.com/spanId":"s-1","logging.googleapis.com/trace_sampled":true}`)
+
+	// Nothing about the span without one, e.g. with the trace id of the caller only.
+	e.Trace = Trace{TraceID: "t-1"}
+	assert.NotContains(t, string(appendEntryJSON(nil, e, "trace")), `"trace_sampled"`)
+
+	s := &sink{payloadTrace: "trace_id"}
+	payload := map[string]any{}
+	s.fillPayload(payload, Entry{Trace: Trace{TraceID: "t-1", SpanID: "s-1"}})
+	assert.Equal(t, "s-1", payload["span_id"])
+	assert.Equal(t, false, payload["trace_sampled"])
+}
+
+func BenchmarkAppendEntryJSON(b *testing.B) {
+	e := makeEntry(true)
+	dst := make([]byte, 0, 1024)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		dst = appendEntryJSON(dst[:0], e, "trace")
+	}
+}
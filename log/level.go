@@ -0,0 +1,378 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	elog "github.com/labstack/gommon/log"
+)
+
+// LevelAudit is the level of the entries recording the changes of the log levels.
+const LevelAudit = "LOG_LEVEL"
+
+// severityOff is the rank of `OFF`, above every severity.
+const severityOff = 100
+
+// LevelConfig is the initial state of a `LevelControl`.
+type LevelConfig struct {
+	// Level is the level of the echo logger: `DEBUG`, `INFO`, `WARN`, `ERROR` or `OFF`. Unchanged if empty.
+	Level string
+	// Names are the lowest severities of the structured entries per level name, e.g. `{"DUMP": "OFF"}`.
+	Names map[string]string
+}
+
+// LevelControl changes the log levels at runtime: the level of the echo logger, and the lowest severity
+// of the structured entries per level name, e.g. `DUMP` in `DEBUG` for 10 minutes. A change with a
+// duration reverts to the configured level after it. Every change writes a `LOG_LEVEL` entry with who made it.
+type LevelControl struct {
+	mu     sync.Mutex
+	echo   echo.Logger
+	audit  func(fields map[string]any)
+	base   levelState
+	state  atomic.Pointer[levelState]
+	timers map[string]pendingRevert
+	// gen numbers the scheduled reverts.
+	gen uint64
+	// expires are the end of the changes with a duration, by target.
+	expires map[string]time.Time
+}
+
+// pendingRevert is the scheduled revert of a target. A timer which already fired when it's stopped
+// still runs its callback, which is a no-op once gen no longer matches the pending revert.
+type pendingRevert struct {
+	timer *time.Timer
+	gen   uint64
+}
+
+// levelState is an immutable snapshot of the levels, read without locking.
+type levelState struct {
+	echo  elog.Lvl
+	names map[string]int
+}
+
+// LevelStatus is the state of the levels, as served by `LevelHandler`.
+type LevelStatus struct {
+	Level     string               `json:"level"`
+	BaseLevel string               `json:"base_level"`
+	ExpiresAt *time.Time           `json:"expires_at,omitempty"`
+	Names     map[string]NameLevel `json:"names"`
+}
+
+// NameLevel is the lowest severity of the entries of a level name.
+type NameLevel struct {
+	Severity     string     `json:"severity"`
+	BaseSeverity string     `json:"base_severity,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+}
+
+// NewLevelControl applies the configured levels to the echo logger. audit receives the fields of the
+// entries recording the changes, they are dropped if it's nil.
+func NewLevelControl(e echo.Logger, cfg LevelConfig, audit func(fields map[string]any)) (*LevelControl, error) {
+	c := &LevelControl{
+		echo:    e,
+		audit:   audit,
+		timers:  map[string]pendingRevert{},
+		expires: map[string]time.Time{},
+	}
+
+	c.base.echo = e.Level()
+	if cfg.Level != "" {
+		lvl, err := parseEchoLevel(cfg.Level)
+		if err != nil {
+			return nil, err
+		}
+		c.base.echo = lvl
+	}
+	c.base.names = make(map[string]int, len(cfg.Names))
+	for name, severity := range cfg.Names {
+		rank, err := parseSeverityRank(severity)
+		if err != nil {
+			return nil, fmt.Errorf("log level of %s: %w", name, err)
+		}
+		c.base.names[strings.ToUpper(name)] = rank
+	}
+
+	state := c.base
+	c.state.Store(&state)
+	e.SetLevel(state.echo)
+	return c, nil
+}
+
+// Enabled reports whether the structured entries of the level name and severity are written.
+func (c *LevelControl) Enabled(level string, severity Severity) bool {
+	if c == nil {
+		return true
+	}
+	names := c.state.Load().names
+	if len(names) == 0 {
+		return true
+	}
+	rank, ok := names[strings.ToUpper(level)]
+	return !ok || severity.rank() >= rank
+}
+
+// SetLevel sets the level of the echo logger, reverted after d if it isn't 0.
+func (c *LevelControl) SetLevel(level string, d time.Duration, actor string) error {
+	lvl, err := parseEchoLevel(level)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	from := c.state.Load().echo
+	c.update(func(s *levelState) { s.echo = lvl })
+	c.schedule("", d, func(gen uint64) { c.revertName("", gen) })
+	c.record(actor, "", echoLevelName(from), echoLevelName(lvl), d)
+	return nil
+}
+
+// SetName sets the lowest severity of the entries of a level name (`OFF` drops them), reverted after d
+// if it isn't 0.
+func (c *LevelControl) SetName(name, severity string, d time.Duration, actor string) error {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if name == "" {
+		return errors.New("empty level name")
+	}
+	rank, err := parseSeverityRank(severity)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	from, ok := c.state.Load().names[name]
+	c.update(func(s *levelState) { s.names[name] = rank })
+	c.schedule(name, d, func(gen uint64) { c.revertName(name, gen) })
+	c.record(actor, name, rankName(from, ok), rankName(rank, true), d)
+	return nil
+}
+
+// Toggle switches the echo logger between DEBUG and its configured level, for SIGUSR1.
+func (c *LevelControl) Toggle(actor string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	from := c.state.Load().echo
+	to := elog.DEBUG
+	if from == elog.DEBUG {
+		to = c.base.echo
+	}
+	c.update(func(s *levelState) { s.echo = to })
+	c.schedule("", 0, nil)
+	c.record(actor, "", echoLevelName(from), echoLevelName(to), 0)
+}
+
+// Reset reverts a level name, or the echo logger if name is empty, to its configured level.
+func (c *LevelControl) Reset(name, actor string) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.schedule(name, 0, nil)
+	c.revertLocked(name, actor)
+}
+
+// Status returns the current levels.
+func (c *LevelControl) Status() LevelStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.state.Load()
+	status := LevelStatus{
+		Level:     echoLevelName(s.echo),
+		BaseLevel: echoLevelName(c.base.echo),
+		Names:     make(map[string]NameLevel, len(s.names)),
+	}
+	if t, ok := c.expires[""]; ok {
+		status.ExpiresAt = &t
+	}
+	for name, rank := range s.names {
+		base, ok := c.base.names[name]
+		nl := NameLevel{Severity: rankName(rank, true), BaseSeverity: rankName(base, ok)}
+		if t, ok := c.expires[name]; ok {
+			nl.ExpiresAt = &t
+		}
+		status.Names[name] = nl
+	}
+	return status
+}
+
+// Close stops the pending reverts.
+func (c *LevelControl) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for target, p := range c.timers {
+		p.timer.Stop()
+		delete(c.timers, target)
+	}
+}
+
+// revertName reverts a level name, or the echo logger if name is empty, when gen is still the
+// pending revert: not replaced by a newer change, reset or closed since it was scheduled.
+func (c *LevelControl) revertName(name string, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := c.timers[name]; !ok || p.gen != gen {
+		return
+	}
+	delete(c.timers, name)
+	delete(c.expires, name)
+	c.revertLocked(name, "auto-revert")
+}
+
+func (c *LevelControl) revertLocked(name, actor string) {
+	s := c.state.Load()
+	if name == "" {
+		from := s.echo
+		c.update(func(s *levelState) { s.echo = c.base.echo })
+		c.record(actor, "", echoLevelName(from), echoLevelName(c.base.echo), 0)
+		return
+	}
+
+	from, had := s.names[name]
+	base, ok := c.base.names[name]
+	c.update(func(s *levelState) {
+		if ok {
+			s.names[name] = base
+		} else {
+			delete(s.names, name)
+		}
+	})
+	c.record(actor, name, rankName(from, had), rankName(base, ok), 0)
+}
+
+// update replaces the state with a modified copy, and applies the level of the echo logger.
+func (c *LevelControl) update(change func(s *levelState)) {
+	cur := c.state.Load()
+	next := &levelState{echo: cur.echo, names: maps.Clone(cur.names)}
+	if next.names == nil {
+		next.names = map[string]int{}
+	}
+	change(next)
+	c.state.Store(next)
+	if next.echo != cur.echo {
+		c.echo.SetLevel(next.echo)
+	}
+}
+
+// schedule replaces the pending revert of the target, with none if d is 0.
+func (c *LevelControl) schedule(target string, d time.Duration, revert func(gen uint64)) {
+	if p, ok := c.timers[target]; ok {
+		p.timer.Stop()
+		delete(c.timers, target)
+		delete(c.expires, target)
+	}
+	if d <= 0 || revert == nil {
+		return
+	}
+	c.gen++
+	gen := c.gen
+	c.timers[target] = pendingRevert{timer: time.AfterFunc(d, func() { revert(gen) }), gen: gen}
+	c.expires[target] = time.Now().Add(d)
+}
+
+func (c *LevelControl) record(actor, name, from, to string, d time.Duration) {
+	if c.audit == nil {
+		return
+	}
+	fields := map[string]any{
+		"message": "log level changed",
+		"actor":   actor,
+		"from":    from,
+		"to":      to,
+	}
+	if name == "" {
+		fields["target"] = "echo"
+	} else {
+		fields["target"] = name
+	}
+	if d > 0 {
+		fields["duration"] = d.String()
+		fields["expires_at"] = time.Now().Add(d)
+	}
+	c.audit(fields)
+}
+
+// auditTo returns the audit function writing the changes through the pipeline of the logger, whatever the levels.
+func auditTo(l *logger) func(fields map[string]any) {
+	return func(fields map[string]any) {
+		l.writeEntry(context.Background(), Warning, LevelAudit, fields, nil, 0)
+	}
+}
+
+func parseEchoLevel(level string) (elog.Lvl, error) {
+	switch strings.ToUpper(strings.TrimSpace(level)) {
+	case "DEBUG":
+		return elog.DEBUG, nil
+	case "INFO":
+		return elog.INFO, nil
+	case "WARN", "WARNING":
+		return elog.WARN, nil
+	case "ERROR":
+		return elog.ERROR, nil
+	case "OFF":
+		return elog.OFF, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q", level)
+	}
+}
+
+func echoLevelName(lvl elog.Lvl) string {
+	switch lvl {
+	case elog.DEBUG:
+		return "DEBUG"
+	case elog.INFO:
+		return "INFO"
+	case elog.WARN:
+		return "WARN"
+	case elog.ERROR:
+		return "ERROR"
+	case elog.OFF:
+		return "OFF"
+	default:
+		return fmt.Sprint(lvl)
+	}
+}
+
+// parseSeverityRank parses a severity or `OFF`. An empty severity writes all the entries.
+func parseSeverityRank(severity string) (int, error) {
+	s := Severity(strings.ToUpper(strings.TrimSpace(severity)))
+	switch s {
+	case "":
+		return 0, nil
+	case "OFF":
+		return severityOff, nil
+	}
+	if rank := s.rank(); rank > 0 {
+		return rank, nil
+	}
+	return 0, fmt.Errorf("unknown severity %q", severity)
+}
+
+// rankName is the severity of a rank, `DEFAULT` when there's no level for the name.
+func rankName(rank int, ok bool) string {
+	if !ok {
+		return "DEFAULT"
+	}
+	for _, s := range []Severity{Debug, Info, Warning, Error, Critical} {
+		if s.rank() == rank {
+			return string(s)
+		}
+	}
+	if rank == severityOff {
+		return "OFF"
+	}
+	return "ALL"
+}
@@ -0,0 +1,87 @@
+package log
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DefaultLevelPath is the path of `LevelHandler` when none is configured.
+const DefaultLevelPath = "/admin/log-level"
+
+// LevelRequest changes a level: the echo logger's if `Name` is empty, else the lowest severity of the
+// entries of the level name. `Duration`, e.g. `10m`, reverts the change after it.
+type LevelRequest struct {
+	Level    string `json:"level"`
+	Name     string `json:"name"`
+	Severity string `json:"severity"`
+	Duration string `json:"duration"`
+	// Actor is who makes the change, recorded in the `LOG_LEVEL` entry with the client IP.
+	Actor string `json:"actor"`
+}
+
+// LevelHandler serves the levels of the control to the requests with the bearer token: GET returns
+// them, PUT changes one with a `LevelRequest` and DELETE reverts the echo logger, or the `name` of the
+// query, to its configured level.
+func LevelHandler(lc *LevelControl, token string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !validBearer(c.Request().Header.Get(echo.HeaderAuthorization), token) {
+			return echo.NewHTTPError(http.StatusUnauthorized)
+		}
+
+		switch c.Request().Method {
+		case http.MethodGet:
+			return c.JSON(http.StatusOK, lc.Status())
+
+		case http.MethodPut:
+			var req LevelRequest
+			if err := c.Bind(&req); err != nil {
+				return err
+			}
+			if strings.TrimSpace(req.Actor) == "" {
+				return echo.NewHTTPError(http.StatusBadRequest, "actor is required")
+			}
+			var d time.Duration
+			if req.Duration != "" {
+				var err error
+				if d, err = time.ParseDuration(req.Duration); err != nil || d < 0 {
+					return echo.NewHTTPError(http.StatusBadRequest, "invalid duration")
+				}
+			}
+
+			actor := req.Actor + " (" + c.RealIP() + ")"
+			var err error
+			if req.Name == "" {
+				err = lc.SetLevel(req.Level, d, actor)
+			} else {
+				err = lc.SetName(req.Name, req.Severity, d, actor)
+			}
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+			return c.JSON(http.StatusOK, lc.Status())
+
+		case http.MethodDelete:
+			actor := c.QueryParam("actor")
+			if strings.TrimSpace(actor) == "" {
+				return echo.NewHTTPError(http.StatusBadRequest, "actor is required")
+			}
+			lc.Reset(c.QueryParam("name"), actor+" ("+c.RealIP()+")")
+			return c.JSON(http.StatusOK, lc.Status())
+
+		default:
+			return echo.ErrMethodNotAllowed
+		}
+	}
+}
+
+func validBearer(header, token string) bool {
+	if token == "" {
+		return false
+	}
+	got, ok := strings.CutPrefix(header, "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
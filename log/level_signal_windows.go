@@ -0,0 +1,6 @@
+package log
+
+// WatchSignal does nothing on Windows, which has no SIGUSR1.
+func (c *LevelControl) WatchSignal() (stop func()) {
+	return func() {}
+}
@@ -0,0 +1,199 @@
+package log
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	elog "github.com/labstack/gommon/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type auditRecorder struct {
+	mu      sync.Mutex
+	changes []map[string]any
+}
+
+func (r *auditRecorder) record(fields map[string]any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changes = append(r.changes, fields)
+}
+
+func (r *auditRecorder) last() map[string]any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.changes) == 0 {
+		return nil
+	}
+	return r.changes[len(r.changes)-1]
+}
+
+func TestNewLevelControl(t *testing.T) {
+	e := echo.New()
+	e.Logger.SetLevel(elog.DEBUG)
+
+	lc, err := NewLevelControl(e.Logger, LevelConfig{Level: "warn", Names: map[string]string{"dump": "OFF"}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, elog.WARN, e.Logger.Level())
+	assert.False(t, lc.Enabled("DUMP", Critical))
+	assert.True(t, lc.Enabled("ACCESS", Debug))
+
+	_, err = NewLevelControl(e.Logger, LevelConfig{Level: "loud"}, nil)
+	assert.ErrorContains(t, err, `unknown log level "loud"`)
+	_, err = NewLevelControl(e.Logger, LevelConfig{Names: map[string]string{"DUMP": "LOUD"}}, nil)
+	assert.ErrorContains(t, err, `unknown severity "LOUD"`)
+}
+
+func TestLevelControl_SetLevel(t *testing.T) {
+	e := echo.New()
+	var audit auditRecorder
+	lc, err := NewLevelControl(e.Logger, LevelConfig{Level: "ERROR"}, audit.record)
+	require.NoError(t, err)
+	defer lc.Close()
+
+	require.NoError(t, lc.SetLevel("debug", 50*time.Millisecond, "alice"))
+	assert.Equal(t, elog.DEBUG, e.Logger.Level())
+	assert.Equal(t, "alice", audit.last()["actor"])
+	assert.Equal(t, "echo", audit.last()["target"])
+	assert.Equal(t, "ERROR", audit.last()["from"])
+	assert.Equal(t, "DEBUG", audit.last()["to"])
+	assert.Equal(t, "50ms", audit.last()["duration"])
+	assert.NotNil(t, lc.Status().ExpiresAt)
+
+	assert.Eventually(t, func() bool { return e.Logger.Level() == elog.ERROR }, time.Second, 5*time.Millisecond)
+	assert.Eventually(t, func() bool { return audit.last()["actor"] == "auto-revert" }, time.Second, 5*time.Millisecond)
+	assert.Nil(t, lc.Status().ExpiresAt)
+
+	assert.Error(t, lc.SetLevel("LOUD", 0, "alice"))
+}
+
+func TestLevelControl_OverrideAroundExpiry(t *testing.T) {
+	e := echo.New()
+	lc, err := NewLevelControl(e.Logger, LevelConfig{Level: "ERROR"}, nil)
+	require.NoError(t, err)
+	defer lc.Close()
+
+	// The second override lands around the expiry of the first one: its revert may already be running.
+	for i := 0; i < 20; i++ {
+		require.NoError(t, lc.SetLevel("debug", time.Millisecond, "alice"))
+		time.Sleep(time.Millisecond)
+		require.NoError(t, lc.SetLevel("warn", time.Hour, "bob"))
+		time.Sleep(2 * time.Millisecond)
+		require.Equal(t, elog.WARN, e.Logger.Level(), "iteration %d", i)
+		require.NotNil(t, lc.Status().ExpiresAt)
+	}
+
+	// The callback of a timer which fired before it was stopped.
+	lc.mu.Lock()
+	stale := lc.timers[""].gen
+	lc.mu.Unlock()
+	require.NoError(t, lc.SetName("dump", "OFF", time.Hour, "bob"))
+	require.NoError(t, lc.SetLevel("info", time.Hour, "bob"))
+	lc.revertName("", stale)
+	assert.Equal(t, elog.INFO, e.Logger.Level())
+	assert.NotNil(t, lc.Status().ExpiresAt)
+}
+
+func TestLevelControl_SetName(t *testing.T) {
+	e := echo.New()
+	var audit auditRecorder
+	lc, err := NewLevelControl(e.Logger, LevelConfig{Names: map[string]string{"DUMP": "OFF"}}, audit.record)
+	require.NoError(t, err)
+	defer lc.Close()
+
+	require.NoError(t, lc.SetName("dump", "DEBUG", 50*time.Millisecond, "bob"))
+	assert.True(t, lc.Enabled("DUMP", Info))
+	assert.Equal(t, "DUMP", audit.last()["target"])
+	assert.Equal(t, "OFF", audit.last()["from"])
+	assert.Equal(t, "DEBUG", audit.last()["to"])
+	assert.Equal(t, NameLevel{Severity: "DEBUG", BaseSeverity: "OFF", ExpiresAt: lc.Status().Names["DUMP"].ExpiresAt}, lc.Status().Names["DUMP"])
+
+	assert.Eventually(t, func() bool { return !lc.Enabled("DUMP", Info) }, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, lc.SetName("OUTBOUND_API", "ERROR", 0, "bob"))
+	assert.False(t, lc.Enabled("OUTBOUND_API", Warning))
+	assert.True(t, lc.Enabled("OUTBOUND_API", Error))
+	assert.Equal(t, "DEFAULT", audit.last()["from"])
+
+	lc.Reset("OUTBOUND_API", "bob")
+	assert.True(t, lc.Enabled("OUTBOUND_API", Debug))
+	assert.NotContains(t, lc.Status().Names, "OUTBOUND_API")
+
+	assert.Error(t, lc.SetName("", "DEBUG", 0, "bob"))
+}
+
+func TestLevelControl_Toggle(t *testing.T) {
+	e := echo.New()
+	lc, err := NewLevelControl(e.Logger, LevelConfig{Level: "INFO"}, nil)
+	require.NoError(t, err)
+
+	lc.Toggle("signal")
+	assert.Equal(t, elog.DEBUG, e.Logger.Level())
+	lc.Toggle("signal")
+	assert.Equal(t, elog.INFO, e.Logger.Level())
+}
+
+func TestLogger_Levels(t *testing.T) {
+	l, buf := newBufferLogger(t, WithLevels(LevelConfig{Names: map[string]string{"DUMP": "OFF"}}))
+
+	l.TraceInfo(context.Background(), "DUMP", map[string]any{"n": 1})
+	l.FromContext(context.Background()).WithLevel("DUMP").Error("dropped")
+	assert.Empty(t, buf.String())
+
+	require.NoError(t, l.Levels().SetName("DUMP", "INFO", 0, "alice"))
+	l.TraceInfo(context.Background(), "DUMP", map[string]any{"n": 2})
+
+	docs := decodeLines(t, buf)
+	require.Len(t, docs, 2)
+	assert.Equal(t, LevelAudit, docs[0]["level"])
+	assert.Equal(t, "WARNING", docs[0]["severity"])
+	assert.Equal(t, "alice", docs[0]["actor"])
+	assert.Equal(t, float64(2), docs[1]["n"])
+}
+
+func TestLevelHandler(t *testing.T) {
+	e := echo.New()
+	lc, err := NewLevelControl(e.Logger, LevelConfig{Level: "INFO"}, nil)
+	require.NoError(t, err)
+	defer lc.Close()
+	e.Match([]string{http.MethodGet, http.MethodPut, http.MethodDelete}, DefaultLevelPath, LevelHandler(lc, "s3cret"))
+
+	do := func(method, target, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if token != "" {
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, DefaultLevelPath, "", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, DefaultLevelPath, "wrong", "").Code)
+
+	rec := do(http.MethodGet, DefaultLevelPath, "s3cret", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"level":"INFO","base_level":"INFO","names":{}}`, rec.Body.String())
+
+	rec = do(http.MethodPut, DefaultLevelPath, "s3cret", `{"level":"DEBUG"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "the actor is required")
+
+	rec = do(http.MethodPut, DefaultLevelPath, "s3cret", `{"name":"DUMP","severity":"DEBUG","duration":"10m","actor":"alice"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"DUMP":{"severity":"DEBUG","base_severity":"DEFAULT","expires_at":`)
+
+	rec = do(http.MethodPut, DefaultLevelPath, "s3cret", `{"level":"LOUD","actor":"alice"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = do(http.MethodDelete, DefaultLevelPath+"?name=DUMP&actor=alice", "s3cret", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"level":"INFO","base_level":"INFO","names":{}}`, rec.Body.String())
+}
@@ -3,13 +3,13 @@ package log
 import (
 	"context"
 	"io"
+	"runtime"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/retail-ai-inc/bean/v2/config"
-	"github.com/retail-ai-inc/bean/v2/helpers"
 )
 
 type Severity string
@@ -32,9 +32,30 @@ type AccessLogger interface {
 	TraceError(ctx context.Context, level string, fields map[string]any)
 }
 
+// WarningLogger is implemented by the access loggers which write WARNING entries, e.g. the logger
+// of `NewLogger`. The combined access log writes the 4xx responses with it.
+type WarningLogger interface {
+	TraceWarn(ctx context.Context, level string, fields map[string]any)
+}
+
+// FieldLogger writes entries with typed fields, which the JSON sink encodes without boxing them.
+// The logger of `NewLogger` implements it.
+type FieldLogger interface {
+	Log(ctx context.Context, severity Severity, level string, fields ...Field)
+}
+
 type Trace struct {
 	TraceID string
 	SpanID  string
+	// Sampled is the sampling decision of the span, written with its id.
+	Sampled bool
+}
+
+// SourceLocation is where an entry was logged from.
+type SourceLocation struct {
+	File     string
+	Line     int
+	Function string
 }
 
 type Entry struct {
@@ -42,21 +63,40 @@ type Entry struct {
 	Severity  Severity
 	Level     string
 	Fields    map[string]any
-	Trace     Trace
+	// Attrs are typed fields, written after `Fields` and overriding them.
+	Attrs []Field
+	Trace Trace
+	// Source is only set when the encoding writes it, see `WithSourceLocation`.
+	Source *SourceLocation
 }
 
 type logger struct {
 	echo.Logger
 	traceExtractor TraceExtractor
 	pipeline       *Pipeline
+	addSource      bool
+	// appMinRank is the rank of the lowest severity of the application logs written.
+	appMinRank int
+	levels     *LevelControl
 }
 
 type Config struct {
 	accessLogPath    string
+	accessLogRotate  RotateConfig
 	maskFields       []string
+	maskRules        []MaskRule
+	redact           RedactConfig
 	runtimePlatform  string
 	sinkAsync        bool
 	sinkAsyncQueueSz int
+	sinkOverflow     OverflowConfig
+	encoding         EncoderConfig
+	addSource        bool
+	appMinSeverity   Severity
+	levels           LevelConfig
+	traceExtractor   TraceExtractor
+	sinks            []config.LogSink
+	sinkRoutes       []SinkRoute
 }
 
 type LoggerOptions func(*Config)
@@ -65,10 +105,25 @@ func WithAccessLogPath(accessLogPath string) LoggerOptions {
 	return func(c *Config) { c.accessLogPath = accessLogPath }
 }
 
+// WithAccessLogRotation rotates the file at the access log path.
+func WithAccessLogRotation(rotate RotateConfig) LoggerOptions {
+	return func(c *Config) { c.accessLogRotate = rotate }
+}
+
 func WithMaskFields(maskFields []string) LoggerOptions {
 	return func(c *Config) { c.maskFields = maskFields }
 }
 
+// WithMaskRules masks the values at the paths of the rules, compiled by `NewLogger`.
+func WithMaskRules(rules []MaskRule) LoggerOptions {
+	return func(c *Config) { c.maskRules = rules }
+}
+
+// WithRedaction redacts personal data in the values of the fields, after the masking of `WithMaskFields`.
+func WithRedaction(redact RedactConfig) LoggerOptions {
+	return func(c *Config) { c.redact = redact }
+}
+
 func WithRuntimePlatform(platform string) LoggerOptions {
 	return func(c *Config) { c.runtimePlatform = platform }
 }
@@ -80,6 +135,50 @@ func WithSinkAsync(async bool, queueSize int) LoggerOptions {
 	}
 }
 
+// WithSinkOverflow sets what the async sink does when its queue is full.
+func WithSinkOverflow(overflow OverflowConfig) LoggerOptions {
+	return func(c *Config) { c.sinkOverflow = overflow }
+}
+
+// WithEncoding selects how the entries are written, JSON by default. The `gcp` and `ecs` encodings
+// turn `WithSourceLocation` on.
+func WithEncoding(encoding EncoderConfig) LoggerOptions {
+	return func(c *Config) { c.encoding = encoding }
+}
+
+// WithSourceLocation records the file, line and function calling `TraceInfo` / `TraceError` in `Entry.Source`.
+func WithSourceLocation(on bool) LoggerOptions {
+	return func(c *Config) { c.addSource = on }
+}
+
+// WithAppMinSeverity drops the application logs of `FromContext` and `NewSlogHandler` below the
+// severity, e.g. `Info` drops the DEBUG entries. All are written if empty.
+func WithAppMinSeverity(severity Severity) LoggerOptions {
+	return func(c *Config) { c.appMinSeverity = severity }
+}
+
+// WithLevels sets the level of the echo logger and the lowest severities per level name, changed
+// at runtime with the `LevelControl` of `Levels`.
+func WithLevels(levels LevelConfig) LoggerOptions {
+	return func(c *Config) { c.levels = levels }
+}
+
+// WithSinks replaces the single output (stdout or the access log path) with the sinks configured in `env.json`.
+func WithSinks(sinks []config.LogSink) LoggerOptions {
+	return func(c *Config) { c.sinks = sinks }
+}
+
+// WithSinkRoutes replaces the single output (stdout or the access log path) with a `FanoutSink` of the routes.
+// They are added after the ones of `WithSinks`.
+func WithSinkRoutes(routes ...SinkRoute) LoggerOptions {
+	return func(c *Config) { c.sinkRoutes = append(c.sinkRoutes, routes...) }
+}
+
+// WithTraceExtractor replaces the default Sentry trace extractor, e.g. with `NewOtelExtractor()`.
+func WithTraceExtractor(extractor TraceExtractor) LoggerOptions {
+	return func(c *Config) { c.traceExtractor = extractor }
+}
+
 func tracePayloadKey(platform string) string {
 	switch strings.ToLower(strings.TrimSpace(platform)) {
 	case "gcp", "google":
@@ -91,8 +190,16 @@ func tracePayloadKey(platform string) string {
 	}
 }
 
+// spanPayloadKeys returns the keys of the span id and of the sampling decision going with the trace key.
+func spanPayloadKeys(payloadTrace string) (spanKey, sampledKey string) {
+	if payloadTrace == "logging.googleapis.com/trace" {
+		return "logging.googleapis.com/spanId", "logging.googleapis.com/trace_sampled"
+	}
+	return "span_id", "trace_sampled"
+}
+
 func NewLogger(elogger echo.Logger, options ...LoggerOptions) (*logger, error) {
-	cfg := &Config{maskFields: []string{}}
+	cfg := &Config{maskFields: []string{}, traceExtractor: NewSentryExtractor()}
 
 	for _, option := range options {
 		option(cfg)
@@ -100,54 +207,171 @@ func NewLogger(elogger echo.Logger, options ...LoggerOptions) (*logger, error) {
 
 	payloadTrace := tracePayloadKey(cfg.runtimePlatform)
 
+	encoder, err := NewEncoder(cfg.encoding)
+	if err != nil {
+		return nil, err
+	}
+	addSource := cfg.addSource || cfg.encoding.needsSource()
+
 	sinkCfg := SinkConfig{
 		Async:     cfg.sinkAsync,
 		QueueSize: cfg.sinkAsyncQueueSz,
+		Overflow:  cfg.sinkOverflow,
+		Encoder:   encoder,
 	}
 
-	var out io.WriteCloser = NopWriteCloser{Writer: elogger.Output()}
-	if cfg.accessLogPath != "" {
-		file, err := helpers.OpenFile(cfg.accessLogPath)
+	var s Sink
+	if len(cfg.sinks) > 0 || len(cfg.sinkRoutes) > 0 {
+		for _, sc := range cfg.sinks {
+			addSource = addSource || EncoderConfigFrom(sc.Encoding).needsSource()
+		}
+		routes, err := configSinkRoutes(elogger, payloadTrace, encoder, cfg.sinks)
 		if err != nil {
 			return nil, err
 		}
-		out = file
-	}
+		s = NewFanoutSink(append(routes, cfg.sinkRoutes...)...)
+	} else {
+		var out io.WriteCloser = NopWriteCloser{Writer: elogger.Output()}
+		if cfg.accessLogPath != "" {
+			file, err := NewRotatingFile(cfg.accessLogPath, cfg.accessLogRotate)
+			if err != nil {
+				return nil, err
+			}
+			out = file
+		}
 
-	s, err := NewSink(out, payloadTrace, sinkCfg)
-	if err != nil {
-		return nil, err
+		gs, err := NewSink(out, payloadTrace, sinkCfg)
+		if err != nil {
+			return nil, err
+		}
+		s = gs
 	}
 
-	processors := make([]Processor, 0, 2)
-	if len(cfg.maskFields) > 0 {
-		processors = append(processors, NewMaskProcessor(cfg.maskFields))
+	processors := make([]Processor, 0, 3)
+	if len(cfg.maskFields) > 0 || len(cfg.maskRules) > 0 {
+		mask, err := NewMaskProcessorWithRules(cfg.maskFields, cfg.maskRules)
+		if err != nil {
+			return nil, err
+		}
+		processors = append(processors, mask)
 	}
 	processors = append(processors, NewRemoveEscapeProcessor())
+	if len(cfg.redact.Rules) > 0 {
+		redact, err := NewRedactProcessor(cfg.redact)
+		if err != nil {
+			return nil, err
+		}
+		processors = append(processors, redact)
+	}
 
-	return &logger{
+	l := &logger{
 		Logger:         elogger,
-		traceExtractor: NewSentryExtractor(),
+		traceExtractor: cfg.traceExtractor,
 		pipeline:       NewPipeline(s, processors...),
-	}, nil
+		addSource:      addSource,
+		appMinRank:     cfg.appMinSeverity.rank(),
+	}
+	l.levels, err = NewLevelControl(elogger, cfg.levels, auditTo(l))
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
 }
 
 func (l *logger) TraceInfo(ctx context.Context, level string, fields map[string]any) {
-	l.traceLog(ctx, Info, level, fields)
+	l.traceLog(ctx, Info, level, fields, nil)
+}
+
+func (l *logger) TraceWarn(ctx context.Context, level string, fields map[string]any) {
+	l.traceLog(ctx, Warning, level, fields, nil)
 }
 
 func (l *logger) TraceError(ctx context.Context, level string, fields map[string]any) {
-	l.traceLog(ctx, Error, level, fields)
+	l.traceLog(ctx, Error, level, fields, nil)
+}
+
+func (l *logger) Log(ctx context.Context, severity Severity, level string, fields ...Field) {
+	l.traceLog(ctx, severity, level, nil, fields)
+}
+
+func (l *logger) traceLog(ctx context.Context, severity Severity, level string, fields map[string]any, attrs []Field) {
+	var pc uintptr
+	if l.addSource {
+		// Skip TraceInfo / TraceWarn / TraceError / Log.
+		pc = callerPC(2)
+	}
+	l.write(ctx, severity, level, fields, attrs, pc)
 }
 
-func (l *logger) traceLog(ctx context.Context, severity Severity, level string, fields map[string]any) {
-	_ = l.pipeline.Process(Entry{
+// write sends an entry through the pipeline unless its level is off, pc being where it was logged
+// from, if it's recorded.
+func (l *logger) write(ctx context.Context, severity Severity, level string, fields map[string]any, attrs []Field, pc uintptr) {
+	if !l.levels.Enabled(level, severity) {
+		return
+	}
+	entry := l.newEntry(ctx, severity, level, fields, attrs, pc)
+	if severity == Debug {
+		// Kept until the request is done, see `TailBuffer`.
+		if b := TailBufferFromContext(ctx); b != nil && b.add(l, entry) {
+			return
+		}
+	}
+	_ = l.pipeline.Process(entry)
+}
+
+func (l *logger) writeEntry(ctx context.Context, severity Severity, level string, fields map[string]any, attrs []Field, pc uintptr) {
+	_ = l.pipeline.Process(l.newEntry(ctx, severity, level, fields, attrs, pc))
+}
+
+func (l *logger) newEntry(ctx context.Context, severity Severity, level string, fields map[string]any, attrs []Field, pc uintptr) Entry {
+	entry := Entry{
 		Timestamp: time.Now(),
 		Severity:  severity,
 		Level:     level,
 		Fields:    fields,
+		Attrs:     attrs,
 		Trace:     l.traceExtractor.Extract(ctx),
-	})
+	}
+	if pc != 0 {
+		entry.Source = sourceLocation(pc)
+	}
+	return entry
+}
+
+func (l *logger) appEnabled(ctx context.Context, level string, severity Severity) bool {
+	if !l.levels.Enabled(level, severity) {
+		return false
+	}
+	return severity.rank() >= l.appMinRank || severity == Debug && TailBufferFromContext(ctx).capturing()
+}
+
+// Levels returns the control of the levels of the logger.
+func (l *logger) Levels() *LevelControl {
+	return l.levels
+}
+
+// FromContext returns an application logger writing through the logger.
+func (l *logger) FromContext(ctx context.Context) *AppLogger {
+	return newAppLogger(l, ctx)
+}
+
+// callerPC returns the program counter of the caller skip frames above the function calling it.
+func callerPC(skip int) uintptr {
+	var pcs [1]uintptr
+	// Skip runtime.Callers, callerPC and its caller.
+	if runtime.Callers(skip+2, pcs[:]) == 0 {
+		return 0
+	}
+	return pcs[0]
+}
+
+func sourceLocation(pc uintptr) *SourceLocation {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if frame.File == "" {
+		return nil
+	}
+	return &SourceLocation{File: frame.File, Line: frame.Line, Function: frame.Function}
 }
 
 var (
@@ -155,15 +379,27 @@ var (
 	once    sync.Once
 )
 
-func Init(logger echo.Logger) BeanLogger {
+// Init creates the global logger from `config.Bean`, the options being applied after it, e.g. the trace
+// extractor of the tracing provider.
+func Init(logger echo.Logger, options ...LoggerOptions) BeanLogger {
 	once.Do(func() {
-		var err error
-		blogger, err = NewLogger(logger,
+		options = append([]LoggerOptions{
 			WithMaskFields(config.Bean.AccessLog.BodyDumpMaskParam),
+			WithMaskRules(MaskRulesFrom(config.Bean.AccessLog.MaskRules)),
 			WithAccessLogPath(config.Bean.AccessLog.Path),
+			WithAccessLogRotation(RotateConfigFrom(config.Bean.AccessLog.Rotation)),
 			WithRuntimePlatform(config.Bean.AccessLog.RuntimePlatform),
 			WithSinkAsync(config.Bean.AccessLog.Async, config.Bean.AccessLog.AsyncQueueSize),
-		)
+			WithSinkOverflow(OverflowConfigFrom(config.Bean.AccessLog.AsyncOverflow)),
+			WithEncoding(EncoderConfigFrom(config.Bean.AccessLog.Encoding)),
+			WithRedaction(RedactConfigFrom(config.Bean.AccessLog.Redact, config.Bean.Secret)),
+			WithSinks(config.Bean.AccessLog.Sinks),
+			WithAppMinSeverity(Severity(strings.ToUpper(config.Bean.AccessLog.AppMinSeverity))),
+			WithLevels(LevelConfig{Level: config.Bean.Log.Level, Names: config.Bean.Log.Levels}),
+		}, options...)
+
+		var err error
+		blogger, err = NewLogger(logger, options...)
 		if err != nil {
 			panic(err)
 		}
@@ -171,12 +407,79 @@ func Init(logger echo.Logger) BeanLogger {
 	return blogger
 }
 
+// RotateConfigFrom converts the `env.json` rotation settings.
+func RotateConfigFrom(r config.LogRotation) RotateConfig {
+	return RotateConfig{
+		MaxSize:  int64(r.MaxSize) << 20,
+		Interval: r.Interval,
+		Compress: r.Compress,
+		MaxFiles: r.MaxFiles,
+		MaxAge:   r.MaxAge,
+	}
+}
+
+// OverflowConfigFrom converts the `env.json` overflow settings.
+func OverflowConfigFrom(o config.LogOverflow) OverflowConfig {
+	return OverflowConfig{
+		Policy:         OverflowPolicy(o.Policy),
+		BlockTimeout:   o.BlockTimeout,
+		SpillPath:      o.SpillPath,
+		SpillMaxSize:   int64(o.SpillMaxSize) << 20,
+		ReportInterval: o.ReportInterval,
+	}
+}
+
+// EncoderConfigFrom converts the `env.json` encoding settings.
+func EncoderConfigFrom(e config.LogEncoding) EncoderConfig {
+	return EncoderConfig{
+		Format:       e.Format,
+		GCPProjectID: e.GCPProjectID,
+		EMF: EMFConfig{
+			Namespace:  e.EMF.Namespace,
+			Dimensions: e.EMF.Dimensions,
+			Metrics:    e.EMF.Metrics,
+		},
+	}
+}
+
+// MaskRulesFrom converts the `env.json` mask rules.
+func MaskRulesFrom(rules []config.LogMaskRule) []MaskRule {
+	var out []MaskRule
+	for _, r := range rules {
+		out = append(out, MaskRule{Scope: r.Scope, Levels: r.Levels, Paths: r.Paths})
+	}
+	return out
+}
+
+// RedactConfigFrom converts the `env.json` redaction rules, the hash action keyed with secret.
+func RedactConfigFrom(rules []config.LogRedactRule, secret string) RedactConfig {
+	cfg := RedactConfig{Secret: secret}
+	for _, r := range rules {
+		cfg.Rules = append(cfg.Rules, RedactRule{
+			Fields:    r.Fields,
+			Detectors: r.Detectors,
+			Patterns:  r.Patterns,
+			Action:    RedactAction(r.Action),
+		})
+	}
+	return cfg
+}
+
 func Logger() BeanLogger {
 	return blogger
 }
 
+// Levels returns the control of the levels of the logger of `Init`, nil before it.
+func Levels() *LevelControl {
+	if l, ok := blogger.(*logger); ok && l != nil {
+		return l.levels
+	}
+	return nil
+}
+
 func Shutdown(ctx context.Context) error {
 	if l, ok := blogger.(*logger); ok && l != nil && l.pipeline != nil {
+		l.levels.Close()
 		return l.pipeline.Close(ctx)
 	}
 	return nil
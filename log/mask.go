@@ -1,6 +1,7 @@
 package log
 
 import (
+	"bytes"
 	"encoding/json"
 )
 
@@ -10,6 +11,8 @@ type Processor interface {
 
 type MaskProcessor struct {
 	fields map[string]struct{}
+	// paths are the compiled `MaskRule` paths by level, the ones of all the levels under "".
+	paths map[string][]maskPath
 }
 
 func NewMaskProcessor(fields []string) *MaskProcessor {
@@ -21,61 +24,144 @@ func NewMaskProcessor(fields []string) *MaskProcessor {
 	return &MaskProcessor{fields: fm}
 }
 
+// NewMaskProcessorWithRules masks the keys of fields wherever they are, and the values at the paths
+// of the rules. The paths are compiled once, an invalid one is an error.
+func NewMaskProcessorWithRules(fields []string, rules []MaskRule) (*MaskProcessor, error) {
+	p := NewMaskProcessor(fields)
+	if len(rules) == 0 {
+		return p, nil
+	}
+
+	paths, err := compileMaskRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	p.paths = paths
+	return p, nil
+}
+
 func (p *MaskProcessor) Process(entry Entry) Entry {
-	if len(p.fields) == 0 || entry.Fields == nil {
+	paths, ok := p.paths[entry.Level]
+	if !ok {
+		paths = p.paths[""]
+	}
+	if len(p.fields) == 0 && len(paths) == 0 {
 		return entry
 	}
 
-	masked := p.maskValue(entry.Fields)
-	if m, ok := masked.(map[string]interface{}); ok {
-		entry.Fields = m
+	loc := make([]pathStep, 0, 8)
+	if entry.Fields != nil {
+		masked := p.maskValue(entry.Fields, paths, loc)
+		if m, ok := masked.(map[string]interface{}); ok {
+			entry.Fields = m
+		}
+	}
+	if len(entry.Attrs) > 0 {
+		entry.Attrs = p.maskAttrs(entry.Attrs, paths, loc)
 	}
 
 	return entry
 }
 
-func (p *MaskProcessor) maskValue(val interface{}) interface{} {
+// maskAttrs returns the typed fields masked, in a copy if anything changed: the caller may reuse them.
+func (p *MaskProcessor) maskAttrs(attrs []Field, paths []maskPath, loc []pathStep) []Field {
+	var out []Field
+	for i, f := range attrs {
+		masked, changed := f, false
+		at := loc
+		if len(paths) > 0 {
+			at = append(loc, keyStep(f.Key))
+		}
+		if _, ok := p.fields[f.Key]; ok || len(paths) > 0 && pathMasked(paths, at) {
+			masked, changed = String(f.Key, "****"), true
+		} else {
+			switch f.kind {
+			case KindString:
+				if v := p.maskString(f.str, paths, at); v != f.str {
+					masked, changed = String(f.Key, v), true
+				}
+			case KindRawJSON:
+				if b, rawChanged, _ := p.maskJSONBytes(f.any.(json.RawMessage), paths, at); rawChanged {
+					masked, changed = RawJSON(f.Key, b), true
+				}
+			case KindAny:
+				masked, changed = Any(f.Key, p.maskValue(f.any, paths, at)), true
+			}
+		}
+		if !changed {
+			continue
+		}
+
+		if out == nil {
+			out = make([]Field, len(attrs))
+			copy(out, attrs)
+		}
+		out[i] = masked
+	}
+
+	if out == nil {
+		return attrs
+	}
+	return out
+}
+
+// maskString masks a string holding a JSON document, it returns v itself otherwise.
+func (p *MaskProcessor) maskString(v string, paths []maskPath, loc []pathStep) string {
+	if !looksLikeJSON(v) {
+		return v
+	}
+	if len(p.fields) == 0 && !pathsBelow(paths, loc) {
+		return v
+	}
+	buf := bufPool.Get().(*bytes.Buffer)
+	out, changed, ok := maskJSONPaths(buf.AvailableBuffer(), v, p.fields, paths, loc)
+	if ok && changed {
+		v = string(out)
+	}
+	bufPool.Put(buf)
+	return v
+}
+
+// maskValue masks val in place, val being at loc in the entry.
+func (p *MaskProcessor) maskValue(val interface{}, paths []maskPath, loc []pathStep) interface{} {
 	switch v := val.(type) {
 	case map[string]interface{}:
 		for k, vv := range v {
-			if _, ok := p.fields[k]; ok {
+			at := loc
+			if len(paths) > 0 {
+				at = append(loc, keyStep(k))
+			}
+			if _, ok := p.fields[k]; ok || len(paths) > 0 && pathMasked(paths, at) {
 				v[k] = "****"
 			} else {
-				v[k] = p.maskValue(vv)
+				v[k] = p.maskValue(vv, paths, at)
 			}
 		}
 		return v
 
 	case []interface{}:
 		for i, vv := range v {
-			v[i] = p.maskValue(vv)
+			at := loc
+			if len(paths) > 0 {
+				at = append(loc, pathStep{index: i})
+			}
+			if len(paths) > 0 && pathMasked(paths, at) {
+				v[i] = "****"
+			} else {
+				v[i] = p.maskValue(vv, paths, at)
+			}
 		}
 		return v
 
 	case string:
-		if !looksLikeJSON(v) {
-			return v
-		}
-		var decoded interface{}
-		if err := json.Unmarshal([]byte(v), &decoded); err != nil {
-			return v
-		}
-		masked := p.maskValue(decoded)
-		b, err := json.Marshal(masked)
-		if err != nil {
-			return v
-		}
-		return string(b)
+		return p.maskString(v, paths, loc)
 
 	case json.RawMessage:
-		b, ok := p.maskJSONBytes([]byte(v))
-		if !ok {
-			return v
-		}
+		b, _, _ := p.maskJSONBytes(v, paths, loc)
 		return json.RawMessage(b)
 
 	case []byte:
-		b, ok := p.maskJSONBytes(v)
+		b, _, ok := p.maskJSONBytes(v, paths, loc)
 		if !ok {
 			return string(v)
 		}
@@ -86,17 +172,17 @@ func (p *MaskProcessor) maskValue(val interface{}) interface{} {
 	}
 }
 
-func (p *MaskProcessor) maskJSONBytes(in []byte) ([]byte, bool) {
-	var decoded interface{}
-	if err := json.Unmarshal(in, &decoded); err != nil {
-		return nil, false
-	}
-	masked := p.maskValue(decoded)
-	out, err := json.Marshal(masked)
-	if err != nil {
-		return nil, false
+// maskJSONBytes masks a JSON document in a single pass, without decoding it. The keys keep their order.
+// It returns in itself when nothing changed, and ok false when in isn't valid JSON.
+func (p *MaskProcessor) maskJSONBytes(in []byte, paths []maskPath, loc []pathStep) (out []byte, changed, ok bool) {
+	buf := bufPool.Get().(*bytes.Buffer)
+	defer bufPool.Put(buf)
+
+	out, changed, ok = maskJSONPaths(buf.AvailableBuffer(), in, p.fields, paths, loc)
+	if !ok || !changed {
+		return in, false, ok
 	}
-	return out, true
+	return bytes.Clone(out), true, true
 }
 
 // looksLikeJSON checks the first byte for JSON structural characters ({, [, ").
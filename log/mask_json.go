@@ -0,0 +1,423 @@
+package log
+
+import (
+	"encoding/json"
+)
+
+// jsonMaxDepth bounds the nesting of the scanned documents, as `encoding/json` does.
+const jsonMaxDepth = 10000
+
+// jsonScanner rewrites a JSON document in a single pass, without decoding it: whitespace is removed and,
+// with a mask, the values of the masked keys, or at the masked paths, are replaced by "****". Strings
+// holding JSON documents which may contain a masked key or path are masked too.
+type jsonScanner[T string | []byte] struct {
+	src  T
+	pos  int
+	dst  []byte
+	mask map[string]struct{}
+	// paths are matched against loc, the location of the scanned value in the entry.
+	paths []maskPath
+	loc   []pathStep
+	// changed is set when dst differs from src.
+	changed bool
+	depth   int
+}
+
+// compactJSON appends src without whitespace to dst. It returns false if src isn't valid JSON.
+func compactJSON[T string | []byte](dst []byte, src T) ([]byte, bool) {
+	s := jsonScanner[T]{src: src, dst: dst}
+	if !s.document() {
+		return dst, false
+	}
+	return s.dst, true
+}
+
+// maskJSON appends src to dst compacted and with the values of the keys of mask replaced. changed
+// is false when the result is src itself. ok is false if src isn't valid JSON.
+func maskJSON[T string | []byte](dst []byte, src T, mask map[string]struct{}) (out []byte, changed, ok bool) {
+	return maskJSONPaths(dst, src, mask, nil, nil)
+}
+
+// maskJSONPaths is `maskJSON` masking the values at paths as well, src being at loc in the entry.
+func maskJSONPaths[T string | []byte](dst []byte, src T, mask map[string]struct{}, paths []maskPath, loc []pathStep) (out []byte, changed, ok bool) {
+	s := jsonScanner[T]{src: src, dst: dst, mask: mask, paths: paths, loc: loc}
+	if !s.document() {
+		return dst, false, false
+	}
+	return s.dst, s.changed, true
+}
+
+func (s *jsonScanner[T]) document() bool {
+	s.skipSpace()
+	if !s.value(true) {
+		return false
+	}
+	s.skipSpace()
+	return s.pos == len(s.src)
+}
+
+func (s *jsonScanner[T]) skipSpace() {
+	for s.pos < len(s.src) {
+		switch s.src[s.pos] {
+		case ' ', '\t', '\n', '\r':
+			s.pos++
+			s.changed = true
+		default:
+			return
+		}
+	}
+}
+
+// value scans the value at the position, appended to dst if write is true.
+func (s *jsonScanner[T]) value(write bool) bool {
+	if s.pos >= len(s.src) {
+		return false
+	}
+
+	switch c := s.src[s.pos]; {
+	case c == '{':
+		return s.object(write)
+	case c == '[':
+		return s.array(write)
+	case c == '"':
+		return s.stringValue(write)
+	case c == '-' || c >= '0' && c <= '9':
+		return s.number(write)
+	case c == 't':
+		return s.literal("true", write)
+	case c == 'f':
+		return s.literal("false", write)
+	case c == 'n':
+		return s.literal("null", write)
+	default:
+		return false
+	}
+}
+
+func (s *jsonScanner[T]) object(write bool) bool {
+	if s.depth++; s.depth > jsonMaxDepth {
+		return false
+	}
+	defer func() { s.depth-- }()
+
+	s.pos++
+	if write {
+		s.dst = append(s.dst, '{')
+	}
+	s.skipSpace()
+	if s.pos < len(s.src) && s.src[s.pos] == '}' {
+		s.pos++
+		if write {
+			s.dst = append(s.dst, '}')
+		}
+		return true
+	}
+
+	for {
+		if s.pos >= len(s.src) || s.src[s.pos] != '"' {
+			return false
+		}
+		start := s.pos
+		escaped, ok := s.skipString()
+		if !ok {
+			return false
+		}
+		if write {
+			s.dst = append(s.dst, s.src[start:s.pos]...)
+		}
+		masked := write && s.masked(s.src[start+1:s.pos-1], escaped)
+		if len(s.paths) > 0 {
+			s.loc = append(s.loc, keyStep(unquoteKey(s.src[start+1:s.pos-1], escaped)))
+			masked = masked || write && pathMasked(s.paths, s.loc)
+		}
+
+		s.skipSpace()
+		if s.pos >= len(s.src) || s.src[s.pos] != ':' {
+			return false
+		}
+		s.pos++
+		if write {
+			s.dst = append(s.dst, ':')
+		}
+		s.skipSpace()
+
+		if masked {
+			if !s.value(false) {
+				return false
+			}
+			s.dst = append(s.dst, `"****"`...)
+			s.changed = true
+		} else if !s.value(write) {
+			return false
+		}
+		if len(s.paths) > 0 {
+			s.loc = s.loc[:len(s.loc)-1]
+		}
+
+		s.skipSpace()
+		if s.pos >= len(s.src) {
+			return false
+		}
+		switch s.src[s.pos] {
+		case ',':
+			s.pos++
+			if write {
+				s.dst = append(s.dst, ',')
+			}
+			s.skipSpace()
+		case '}':
+			s.pos++
+			if write {
+				s.dst = append(s.dst, '}')
+			}
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+func (s *jsonScanner[T]) array(write bool) bool {
+	if s.depth++; s.depth > jsonMaxDepth {
+		return false
+	}
+	defer func() { s.depth-- }()
+
+	s.pos++
+	if write {
+		s.dst = append(s.dst, '[')
+	}
+	s.skipSpace()
+	if s.pos < len(s.src) && s.src[s.pos] == ']' {
+		s.pos++
+		if write {
+			s.dst = append(s.dst, ']')
+		}
+		return true
+	}
+
+	for i := 0; ; i++ {
+		if len(s.paths) > 0 {
+			s.loc = append(s.loc, pathStep{index: i})
+		}
+		if write && len(s.paths) > 0 && pathMasked(s.paths, s.loc) {
+			if !s.value(false) {
+				return false
+			}
+			s.dst = append(s.dst, `"****"`...)
+			s.changed = true
+		} else if !s.value(write) {
+			return false
+		}
+		if len(s.paths) > 0 {
+			s.loc = s.loc[:len(s.loc)-1]
+		}
+		s.skipSpace()
+		if s.pos >= len(s.src) {
+			return false
+		}
+		switch s.src[s.pos] {
+		case ',':
+			s.pos++
+			if write {
+				s.dst = append(s.dst, ',')
+			}
+			s.skipSpace()
+		case ']':
+			s.pos++
+			if write {
+				s.dst = append(s.dst, ']')
+			}
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// masked reports whether the raw content of a key is one of the masked keys.
+func (s *jsonScanner[T]) masked(key T, escaped bool) bool {
+	if len(s.mask) == 0 {
+		return false
+	}
+	if !escaped {
+		_, ok := s.mask[string(key)]
+		return ok
+	}
+	_, ok := s.mask[unquoteKey(key, escaped)]
+	return ok
+}
+
+// unquoteKey returns the raw content of a key unescaped.
+func unquoteKey[T string | []byte](key T, escaped bool) string {
+	if !escaped {
+		return string(key)
+	}
+	var unquoted string
+	if err := json.Unmarshal([]byte(`"`+string(key)+`"`), &unquoted); err != nil {
+		return string(key)
+	}
+	return unquoted
+}
+
+// stringValue appends a string. A string holding a JSON document is masked as well.
+func (s *jsonScanner[T]) stringValue(write bool) bool {
+	start := s.pos
+	escaped, ok := s.skipString()
+	if !ok {
+		return false
+	}
+	if !write {
+		return true
+	}
+
+	raw := s.src[start:s.pos]
+	if len(raw) > 2 && (raw[1] == '{' || raw[1] == '[') && (len(s.mask) > 0 && s.mayContainMasked(raw) || len(s.paths) > 0 && pathsBelow(s.paths, s.loc)) {
+		if out, ok := s.maskNested(raw, escaped); ok {
+			s.dst = out
+			s.changed = true
+			return true
+		}
+	}
+
+	s.dst = append(s.dst, raw...)
+	return true
+}
+
+// mayContainMasked is a cheap check before decoding a nested document.
+func (s *jsonScanner[T]) mayContainMasked(raw T) bool {
+	for k := range s.mask {
+		if containsString(raw, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskNested masks the document held by a string, appending it quoted again when something was masked.
+func (s *jsonScanner[T]) maskNested(raw T, escaped bool) ([]byte, bool) {
+	inner := string(raw[1 : len(raw)-1])
+	if escaped {
+		if err := json.Unmarshal([]byte(string(raw)), &inner); err != nil {
+			return nil, false
+		}
+	}
+	masked, changed, ok := maskJSONPaths(nil, inner, s.mask, s.paths, s.loc)
+	if !ok || !changed {
+		return nil, false
+	}
+	return appendJSONString(s.dst, masked), true
+}
+
+// skipString moves past a string, reporting whether it has escape sequences.
+func (s *jsonScanner[T]) skipString() (escaped, ok bool) {
+	s.pos++
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		switch {
+		case c == '"':
+			s.pos++
+			return escaped, true
+		case c == '\\':
+			escaped = true
+			if s.pos+1 >= len(s.src) {
+				return escaped, false
+			}
+			switch s.src[s.pos+1] {
+			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+				s.pos += 2
+			case 'u':
+				if s.pos+6 > len(s.src) {
+					return escaped, false
+				}
+				for i := s.pos + 2; i < s.pos+6; i++ {
+					if !isHex(s.src[i]) {
+						return escaped, false
+					}
+				}
+				s.pos += 6
+			default:
+				return escaped, false
+			}
+		case c < 0x20:
+			return escaped, false
+		default:
+			s.pos++
+		}
+	}
+	return escaped, false
+}
+
+func (s *jsonScanner[T]) number(write bool) bool {
+	start := s.pos
+	if s.src[s.pos] == '-' {
+		s.pos++
+	}
+	if s.pos >= len(s.src) {
+		return false
+	}
+
+	if s.src[s.pos] == '0' {
+		s.pos++
+	} else if !s.digits() {
+		return false
+	}
+	if s.pos < len(s.src) && s.src[s.pos] == '.' {
+		s.pos++
+		if !s.digits() {
+			return false
+		}
+	}
+	if s.pos < len(s.src) && (s.src[s.pos] == 'e' || s.src[s.pos] == 'E') {
+		s.pos++
+		if s.pos < len(s.src) && (s.src[s.pos] == '+' || s.src[s.pos] == '-') {
+			s.pos++
+		}
+		if !s.digits() {
+			return false
+		}
+	}
+
+	if write {
+		s.dst = append(s.dst, s.src[start:s.pos]...)
+	}
+	return true
+}
+
+// digits moves past one digit or more.
+func (s *jsonScanner[T]) digits() bool {
+	start := s.pos
+	for s.pos < len(s.src) && s.src[s.pos] >= '0' && s.src[s.pos] <= '9' {
+		s.pos++
+	}
+	return s.pos > start
+}
+
+func (s *jsonScanner[T]) literal(lit string, write bool) bool {
+	if len(s.src)-s.pos < len(lit) {
+		return false
+	}
+	for i := 0; i < len(lit); i++ {
+		if s.src[s.pos+i] != lit[i] {
+			return false
+		}
+	}
+	s.pos += len(lit)
+	if write {
+		s.dst = append(s.dst, lit...)
+	}
+	return true
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func containsString[T string | []byte](s T, sub string) bool {
+	for i := 0; i+len(sub) <= len(s); i++ {
+		if string(s[i:i+len(sub)]) == sub {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package log
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaskJSON(t *testing.T) {
+	mask := map[string]struct{}{"password": {}, "card": {}}
+
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		changed bool
+	}{
+		{
+			name: "unchanged",
+			in:   `{"user":"john","tags":["a","b"],"n":-1.5e3,"ok":true,"none":null}`,
+			want: `{"user":"john","tags":["a","b"],"n":-1.5e3,"ok":true,"none":null}`,
+		},
+		{
+			name:    "compacted",
+			in:      "{ \"user\" : \"john\",\n\t\"tags\": [ 1 , 2 ] }",
+			want:    `{"user":"john","tags":[1,2]}`,
+			changed: true,
+		},
+		{
+			name:    "masked in order",
+			in:      `{"user":"john","password":"secret","profile":{"card":{"number":"4111"},"name":"J"}}`,
+			want:    `{"user":"john","password":"****","profile":{"card":"****","name":"J"}}`,
+			changed: true,
+		},
+		{
+			name:    "masked in arrays",
+			in:      `[{"password":1},{"password":[1,2]},{"other":"password"}]`,
+			want:    `[{"password":"****"},{"password":"****"},{"other":"password"}]`,
+			changed: true,
+		},
+		{
+			name:    "escaped key",
+			in:      `{"password":"secret"}`,
+			want:    `{"password":"****"}`,
+			changed: true,
+		},
+		{
+			name:    "document in a string",
+			in:      `{"data":"{\"password\":\"secret\",\"id\":1}"}`,
+			want:    `{"data":"{\"password\":\"****\",\"id\":1}"}`,
+			changed: true,
+		},
+		{
+			name: "document in a string without masked key",
+			in:   `{"data":"{\"id\": 1}"}`,
+			want: `{"data":"{\"id\": 1}"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, changed, ok := maskJSON(nil, tt.in, mask)
+			require.True(t, ok)
+			assert.Equal(t, tt.want, string(out))
+			assert.Equal(t, tt.changed, changed)
+			assert.True(t, json.Valid(out))
+
+			// Same result from bytes.
+			outBytes, _, ok := maskJSON(nil, []byte(tt.in), mask)
+			require.True(t, ok)
+			assert.Equal(t, tt.want, string(outBytes))
+		})
+	}
+}
+
+func TestMaskJSON_Invalid(t *testing.T) {
+	for _, in := range []string{
+		``, `{`, `{"a"}`, `{"a":}`, `{"a":1,}`, `[1,]`, `01`, `1.`, `-`, `1e`, `tru`, `"\x"`, "\"\x01\"",
+		`"\u12g4"`, `{"a":1} x`, `{'a':1}`, strings.Repeat("[", jsonMaxDepth+1) + strings.Repeat("]", jsonMaxDepth+1),
+	} {
+		_, _, ok := maskJSON(nil, in, map[string]struct{}{"a": {}})
+		assert.False(t, ok, in)
+		assert.False(t, json.Valid([]byte(in)), in)
+	}
+}
+
+func TestMaskProcessor_Attrs(t *testing.T) {
+	p := NewMaskProcessor([]string{"password"})
+
+	attrs := []Field{
+		String("password", "secret"),
+		String("body", `{"password":"secret"}`),
+		RawJSON("raw", []byte(`{"password":"secret"}`)),
+		Int("status", 200),
+	}
+	got := p.Process(Entry{Attrs: attrs})
+
+	assert.Equal(t, "****", got.Attrs[0].Value())
+	assert.Equal(t, `{"password":"****"}`, got.Attrs[1].Value())
+	assert.Equal(t, json.RawMessage(`{"password":"****"}`), got.Attrs[2].Value())
+	assert.Equal(t, int64(200), got.Attrs[3].Value())
+
+	// The fields of the caller are left alone.
+	assert.Equal(t, "secret", attrs[0].Value())
+}
+
+func BenchmarkMaskJSON(b *testing.B) {
+	mask := map[string]struct{}{"password": {}}
+	body := []byte(`{"user":{"name":"john","password":"secret","roles":["admin","dev"]},"items":[{"id":1,"price":9.99},{"id":2,"price":19.99}]}`)
+	dst := make([]byte, 0, 512)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		dst, _, _ = maskJSON(dst[:0], body, mask)
+	}
+}
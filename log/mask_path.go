@@ -0,0 +1,239 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Scopes of `MaskRule`.
+const (
+	MaskScopeEntry        = ""
+	MaskScopeRequestBody  = "request_body"
+	MaskScopeResponseBody = "response_body"
+	MaskScopeHeader       = "header"
+)
+
+// MaskRule masks the values at some paths of the entries, where the keys of `WithMaskFields` match
+// wherever they are.
+type MaskRule struct {
+	// Scope is where the paths start: `request_body`, `response_body`, `header`, or the entry if empty.
+	Scope string
+	// Levels are the levels of the entries the rule applies to, e.g. `OUTBOUND_API`. All if empty.
+	Levels []string
+	// Paths are JSONPath expressions, `$.` being optional: `$.items[*].card.number`, `$..password`,
+	// `users[0]['e-mail']`, `request_header.Authorization`. In the `header` scope, they are header
+	// names of `request_header` and `response_header`, matched case insensitively.
+	Paths []string
+}
+
+// maskPath is a compiled path, matched against the location of a value in an entry.
+type maskPath []maskStep
+
+type maskStep struct {
+	key string
+	// index is the array index of `[n]`, -1 for a key or a wildcard.
+	index int
+	// any matches any member or element, `*`.
+	any bool
+	// descend matches at any depth below, `..`.
+	descend bool
+	// fold matches the key case insensitively.
+	fold bool
+}
+
+// pathStep is a step of the location of a value: a member key or an array index.
+type pathStep struct {
+	key   string
+	index int
+}
+
+func keyStep(key string) pathStep {
+	return pathStep{key: key, index: -1}
+}
+
+// compileMaskRules compiles the paths of the rules by level, the ones for all levels under "".
+func compileMaskRules(rules []MaskRule) (map[string][]maskPath, error) {
+	all := []maskPath{}
+	byLevel := map[string][]maskPath{}
+
+	for i, r := range rules {
+		var paths []maskPath
+		for _, expr := range r.Paths {
+			compiled, err := compileMaskScope(r.Scope, expr)
+			if err != nil {
+				return nil, fmt.Errorf("mask rule %d: path %q: %w", i, expr, err)
+			}
+			paths = append(paths, compiled...)
+		}
+		if len(r.Levels) == 0 {
+			all = append(all, paths...)
+			continue
+		}
+		for _, level := range r.Levels {
+			byLevel[level] = append(byLevel[level], paths...)
+		}
+	}
+
+	compiled := map[string][]maskPath{}
+	if len(all) > 0 {
+		compiled[""] = all
+	}
+	for level, paths := range byLevel {
+		compiled[level] = append(append([]maskPath{}, all...), paths...)
+	}
+	return compiled, nil
+}
+
+func compileMaskScope(scope, expr string) ([]maskPath, error) {
+	switch strings.ToLower(strings.TrimSpace(scope)) {
+	case MaskScopeEntry:
+		path, err := parseMaskPath(expr)
+		if err != nil {
+			return nil, err
+		}
+		return []maskPath{path}, nil
+	case MaskScopeRequestBody, MaskScopeResponseBody:
+		path, err := parseMaskPath(expr)
+		if err != nil {
+			return nil, err
+		}
+		root := maskStep{key: strings.ToLower(strings.TrimSpace(scope)), index: -1}
+		return []maskPath{append(maskPath{root}, path...)}, nil
+	case MaskScopeHeader:
+		name := strings.TrimSpace(expr)
+		if name == "" {
+			return nil, errors.New("empty header name")
+		}
+		return []maskPath{
+			{{key: "request_header", index: -1}, {key: name, index: -1, fold: true}},
+			{{key: "response_header", index: -1}, {key: name, index: -1, fold: true}},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown scope %q", scope)
+	}
+}
+
+// parseMaskPath parses the JSONPath subset of `MaskRule.Paths`.
+func parseMaskPath(expr string) (maskPath, error) {
+	s := strings.TrimSpace(expr)
+	s = strings.TrimPrefix(s, "$")
+	if s != "" && s[0] != '.' && s[0] != '[' {
+		s = "." + s
+	}
+
+	var path maskPath
+	for s != "" {
+		step := maskStep{index: -1}
+		switch {
+		case strings.HasPrefix(s, ".."):
+			step.descend = true
+			s = s[2:]
+		case s[0] == '.':
+			s = s[1:]
+		case s[0] != '[':
+			return nil, fmt.Errorf("unexpected %q", s)
+		}
+
+		if s != "" && s[0] == '[' {
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return nil, errors.New("missing `]`")
+			}
+			inner := strings.TrimSpace(s[1:end])
+			s = s[end+1:]
+			switch {
+			case inner == "*":
+				step.any = true
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				step.key = inner[1 : len(inner)-1]
+			default:
+				n, err := strconv.Atoi(inner)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("invalid index %q", inner)
+				}
+				step.index = n
+			}
+		} else {
+			end := strings.IndexAny(s, ".[")
+			if end < 0 {
+				end = len(s)
+			}
+			switch key := s[:end]; key {
+			case "":
+				return nil, errors.New("empty key")
+			case "*":
+				step.any = true
+			default:
+				step.key = key
+			}
+			s = s[end:]
+		}
+		path = append(path, step)
+	}
+
+	if len(path) == 0 {
+		return nil, errors.New("empty path")
+	}
+	return path, nil
+}
+
+func (s maskStep) matches(l pathStep) bool {
+	switch {
+	case s.any:
+		return true
+	case s.index >= 0:
+		return l.index == s.index
+	case l.index >= 0:
+		return false
+	case s.fold:
+		return strings.EqualFold(s.key, l.key)
+	default:
+		return s.key == l.key
+	}
+}
+
+// match reports whether the path matches the location, or with prefix, a location below it.
+func (p maskPath) match(loc []pathStep, prefix bool) bool {
+	if len(loc) == 0 {
+		return len(p) == 0 || prefix
+	}
+	if len(p) == 0 {
+		return false
+	}
+
+	step := p[0]
+	if step.descend {
+		if prefix {
+			return true
+		}
+		for i := range loc {
+			if step.matches(loc[i]) && p[1:].match(loc[i+1:], false) {
+				return true
+			}
+		}
+		return false
+	}
+	return step.matches(loc[0]) && p[1:].match(loc[1:], prefix)
+}
+
+// pathMasked reports whether one of the paths matches the location.
+func pathMasked(paths []maskPath, loc []pathStep) bool {
+	for _, p := range paths {
+		if p.match(loc, false) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathsBelow reports whether one of the paths may match a location below loc.
+func pathsBelow(paths []maskPath, loc []pathStep) bool {
+	for _, p := range paths {
+		if p.match(loc, true) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMaskPath(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    maskPath
+		wantErr bool
+	}{
+		{
+			expr: "$.items[*].card.number",
+			want: maskPath{
+				{key: "items", index: -1},
+				{any: true, index: -1},
+				{key: "card", index: -1},
+				{key: "number", index: -1},
+			},
+		},
+		{
+			expr: "request_header.Authorization",
+			want: maskPath{{key: "request_header", index: -1}, {key: "Authorization", index: -1}},
+		},
+		{
+			expr: "$..password",
+			want: maskPath{{key: "password", index: -1, descend: true}},
+		},
+		{
+			expr: `users[0]['e-mail']`,
+			want: maskPath{{key: "users", index: -1}, {index: 0}, {key: "e-mail", index: -1}},
+		},
+		{
+			expr: "$.*",
+			want: maskPath{{any: true, index: -1}},
+		},
+		{expr: "$", wantErr: true},
+		{expr: "a..", wantErr: true},
+		{expr: "a[-1]", wantErr: true},
+		{expr: "a[0", wantErr: true},
+		{expr: "a[0]b", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := parseMaskPath(tt.expr)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMaskPath_Match(t *testing.T) {
+	loc := func(steps ...any) []pathStep {
+		var l []pathStep
+		for _, s := range steps {
+			switch s := s.(type) {
+			case string:
+				l = append(l, keyStep(s))
+			case int:
+				l = append(l, pathStep{index: s})
+			}
+		}
+		return l
+	}
+	path := func(expr string) maskPath {
+		p, err := parseMaskPath(expr)
+		require.NoError(t, err)
+		return p
+	}
+
+	assert.True(t, path("items[*].card").match(loc("items", 2, "card"), false))
+	assert.True(t, path("items[*].card").match(loc("items", "x", "card"), false), "a wildcard matches members too")
+	assert.False(t, path("items[0].card").match(loc("items", 1, "card"), false))
+	assert.False(t, path("items.card").match(loc("items", 0, "card"), false), "arrays are steps of the path")
+	assert.True(t, path("..password").match(loc("request_body", "user", "password"), false))
+	assert.False(t, path("..password").match(loc("request_body", "password", "x"), false))
+	assert.True(t, path("user.password").match(loc("user"), true), "a location above the path")
+	assert.False(t, path("user.password").match(loc("config"), true))
+}
+
+func TestMaskProcessor_Rules(t *testing.T) {
+	p, err := NewMaskProcessorWithRules([]string{"secret"}, []MaskRule{
+		{Paths: []string{"user.password"}},
+		{Scope: MaskScopeRequestBody, Paths: []string{"$.items[*].card.number", "$.tokens[0]"}},
+		{Scope: MaskScopeResponseBody, Paths: []string{"$..ssn"}},
+		{Scope: MaskScopeHeader, Paths: []string{"authorization"}},
+		{Scope: MaskScopeHeader, Levels: []string{"OUTBOUND_API"}, Paths: []string{"X-Api-Key"}},
+	})
+	require.NoError(t, err)
+
+	entry := func(level string) Entry {
+		return Entry{Level: level, Fields: map[string]any{
+			"user":   map[string]any{"password": "p", "name": "john"},
+			"config": map[string]any{"password": "kept", "secret": "s"},
+			"request_body": `{"items":[{"card":{"number":"4111","exp":"12/30"}},{"card":{"number":"5500"}}],` +
+				`"tokens":["a","b"],"payload":"{\"card\":{\"number\":\"1\"}}"}`,
+			"response_body": json.RawMessage(`{"people":[{"ssn":"123","name":"a"}]}`),
+			"request_header": map[string]any{
+				"Authorization": "Bearer x",
+				"X-Api-Key":     "key",
+			},
+			"response_header": map[string]any{"X-Api-Key": "key"},
+		}}
+	}
+
+	got := p.Process(entry("ACCESS"))
+	assert.Equal(t, map[string]any{"password": "****", "name": "john"}, got.Fields["user"])
+	assert.Equal(t, map[string]any{"password": "kept", "secret": "****"}, got.Fields["config"])
+	assert.Equal(t, `{"items":[{"card":{"number":"****","exp":"12/30"}},{"card":{"number":"****"}}],`+
+		`"tokens":["****","b"],"payload":"{\"card\":{\"number\":\"1\"}}"}`, got.Fields["request_body"])
+	assert.Equal(t, `{"people":[{"ssn":"****","name":"a"}]}`, string(got.Fields["response_body"].(json.RawMessage)))
+	assert.Equal(t, map[string]any{"Authorization": "****", "X-Api-Key": "key"}, got.Fields["request_header"])
+
+	got = p.Process(entry("OUTBOUND_API"))
+	assert.Equal(t, map[string]any{"Authorization": "****", "X-Api-Key": "****"}, got.Fields["request_header"])
+	assert.Equal(t, map[string]any{"X-Api-Key": "****"}, got.Fields["response_header"])
+}
+
+func TestMaskProcessor_RulesNestedDocument(t *testing.T) {
+	p, err := NewMaskProcessorWithRules(nil, []MaskRule{
+		{Scope: MaskScopeRequestBody, Paths: []string{"$.payload.card.number"}},
+	})
+	require.NoError(t, err)
+
+	got := p.Process(Entry{Fields: map[string]any{
+		"request_body": `{"payload":"{\"card\":{\"number\":\"4111\"}}"}`,
+	}})
+	assert.Equal(t, `{"payload":"{\"card\":{\"number\":\"****\"}}"}`, got.Fields["request_body"])
+}
+
+func TestMaskProcessor_RulesAttrs(t *testing.T) {
+	p, err := NewMaskProcessorWithRules(nil, []MaskRule{
+		{Paths: []string{"card", "body.card.number"}},
+	})
+	require.NoError(t, err)
+
+	attrs := []Field{
+		String("card", "4111"),
+		RawJSON("body", []byte(`{"card":{"number":"4111"}}`)),
+		String("other", "x"),
+	}
+	got := p.Process(Entry{Attrs: attrs})
+	assert.Equal(t, "****", got.Attrs[0].Value())
+	assert.Equal(t, `{"card":{"number":"****"}}`, string(got.Attrs[1].Value().(json.RawMessage)))
+	assert.Equal(t, "x", got.Attrs[2].Value())
+	assert.Equal(t, "4111", attrs[0].Value())
+}
+
+func TestNewMaskProcessorWithRules_Errors(t *testing.T) {
+	_, err := NewMaskProcessorWithRules(nil, []MaskRule{{Scope: "query", Paths: []string{"a"}}})
+	assert.ErrorContains(t, err, `unknown scope "query"`)
+
+	_, err = NewMaskProcessorWithRules(nil, []MaskRule{{Paths: []string{"a[x]"}}})
+	assert.ErrorContains(t, err, `mask rule 0: path "a[x]"`)
+}
+
+func TestNewLogger_WithMaskRules(t *testing.T) {
+	var buf bytes.Buffer
+	e := echo.New()
+	e.Logger.SetOutput(&buf)
+
+	l, err := NewLogger(e.Logger, WithMaskRules([]MaskRule{
+		{Scope: MaskScopeHeader, Levels: []string{"OUTBOUND_API"}, Paths: []string{"Authorization"}},
+	}))
+	require.NoError(t, err)
+
+	l.TraceInfo(context.Background(), "OUTBOUND_API", map[string]any{
+		"request_header": map[string]any{"Authorization": "Bearer x"},
+	})
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, map[string]any{"Authorization": "****"}, doc["request_header"])
+
+	_, err = NewLogger(e.Logger, WithMaskRules([]MaskRule{{Paths: []string{"a["}}}))
+	assert.Error(t, err)
+}
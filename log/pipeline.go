@@ -15,7 +15,7 @@ func NewPipeline(sink Sink, processors ...Processor) *Pipeline {
 }
 
 func (p *Pipeline) Process(entry Entry) error {
-	if entry.Fields != nil {
+	if entry.Fields != nil || len(entry.Attrs) > 0 {
 		for _, processor := range p.processors {
 			entry = processor.Process(entry)
 		}
@@ -0,0 +1,411 @@
+package log
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/retail-ai-inc/bean/v2/helpers"
+)
+
+// RedactAction is what a redaction writes instead of the detected text.
+type RedactAction string
+
+const (
+	// RedactMask replaces the text with `****`. It's the default.
+	RedactMask RedactAction = "mask"
+	// RedactLast4 keeps the last 4 characters, digits for a card number: `****1111`.
+	RedactLast4 RedactAction = "last4"
+	// RedactHash replaces the text with `hmac:` and an HMAC-SHA256 of it keyed with the secret, so the
+	// same value can be joined across entries without being logged.
+	RedactHash RedactAction = "hash"
+)
+
+// Built-in detectors of `RedactRule.Detectors`.
+const (
+	// DetectPAN finds card numbers of 13 to 19 digits, with spaces or dashes, which pass the Luhn check.
+	DetectPAN = "pan"
+	// DetectEmail finds email addresses, `@` URL encoded as well.
+	DetectEmail = "email"
+	// DetectPhone finds phone numbers in international format or with separators: `+81 90 1234 5678`, `090-1234-5678`.
+	DetectPhone = "phone"
+	// DetectJWT finds JSON Web Tokens.
+	DetectJWT = "jwt"
+	// DetectBearer finds the token of `Bearer <token>`, in an `Authorization` header for example.
+	DetectBearer = "bearer"
+)
+
+// RedactRule redacts the text found by its detectors and patterns in some fields.
+type RedactRule struct {
+	// Fields are the paths of the fields the rule applies to, the values below them included. The segments
+	// are separated by dots and matched case insensitively; `*` matches any segment. Arrays don't add a
+	// segment. E.g. `uri`, `request_header.Authorization`, `request_body.*.email`. All the fields if empty.
+	Fields []string
+	// Detectors are built-in detectors: `pan`, `email`, `phone`, `jwt` and `bearer`.
+	Detectors []string
+	// Patterns are custom regular expressions. The first group is redacted if there's one, else the whole match.
+	Patterns []string
+	Action   RedactAction
+}
+
+// RedactConfig configures a `RedactProcessor`.
+type RedactConfig struct {
+	Rules []RedactRule
+	// Secret keys the HMAC of `RedactHash`.
+	Secret string
+}
+
+// RedactProcessor redacts personal data in the text of the fields: free text, query strings, headers,
+// body dumps. Unlike `MaskProcessor`, it looks at the values, not the keys. It runs after
+// `RemoveEscapeProcessor` so that the paths can address the decoded bodies, e.g. `request_body.user.email`.
+type RedactProcessor struct {
+	rules  []*redactRule
+	secret []byte
+}
+
+type redactRule struct {
+	// paths are the split paths of the fields, nil for all of them.
+	paths     [][]string
+	detectors []*detector
+	action    RedactAction
+}
+
+type detector struct {
+	name string
+	re   *regexp.Regexp
+	// hint is a cheap check before running the regular expression.
+	hint func(s string) bool
+	// valid confirms a match, e.g. with the Luhn check.
+	valid func(match string) bool
+	// normalize returns the value hashed, so that the spellings of a value join.
+	normalize func(match string) string
+}
+
+var builtinDetectors = map[string]*detector{
+	DetectPAN: {
+		name:      DetectPAN,
+		re:        regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		hint:      hasDigits(13),
+		valid:     func(m string) bool { return helpers.IsValidLuhnNumber(digitsOnly(m)) },
+		normalize: digitsOnly,
+	},
+	DetectEmail: {
+		name:      DetectEmail,
+		re:        regexp.MustCompile(`[A-Za-z0-9._+-]+(?:@|%40)[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+		hint:      func(s string) bool { return strings.Contains(s, "@") || strings.Contains(s, "%40") },
+		normalize: func(m string) string { return strings.ToLower(strings.Replace(m, "%40", "@", 1)) },
+	},
+	DetectPhone: {
+		name: DetectPhone,
+		re: regexp.MustCompile(`\+\d[\d .-]{7,17}\d\b` +
+			`|\(\d{2,4}\)[ .-]?\d{3,4}[ .-]\d{3,4}\b` +
+			// Without dots, which would match the IPv4 addresses.
+			`|\b\d{2,4}[ -]\d{3,4}[ -]\d{3,4}\b`),
+		hint: hasDigits(9),
+		valid: func(m string) bool {
+			n := len(digitsOnly(m))
+			return n >= 9 && n <= 15
+		},
+		normalize: digitsOnly,
+	},
+	DetectJWT: {
+		name: DetectJWT,
+		re:   regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+		hint: func(s string) bool { return strings.Contains(s, "eyJ") },
+	},
+	DetectBearer: {
+		name: DetectBearer,
+		re:   regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)`),
+		hint: func(s string) bool { return strings.Contains(strings.ToLower(s), "bearer") },
+	},
+}
+
+// NewRedactProcessor compiles the rules.
+func NewRedactProcessor(cfg RedactConfig) (*RedactProcessor, error) {
+	p := &RedactProcessor{secret: []byte(cfg.Secret)}
+
+	for i, r := range cfg.Rules {
+		rule := &redactRule{action: RedactAction(strings.ToLower(string(r.Action)))}
+		switch rule.action {
+		case "":
+			rule.action = RedactMask
+		case RedactMask, RedactLast4:
+		case RedactHash:
+			if cfg.Secret == "" {
+				return nil, fmt.Errorf("redact rule %d: the hash action needs a secret", i)
+			}
+		default:
+			return nil, fmt.Errorf("redact rule %d: unknown action %q", i, r.Action)
+		}
+
+		for _, path := range r.Fields {
+			if path = strings.TrimSpace(path); path != "" {
+				rule.paths = append(rule.paths, strings.Split(path, "."))
+			}
+		}
+
+		for _, name := range r.Detectors {
+			d, ok := builtinDetectors[strings.ToLower(strings.TrimSpace(name))]
+			if !ok {
+				return nil, fmt.Errorf("redact rule %d: unknown detector %q", i, name)
+			}
+			rule.detectors = append(rule.detectors, d)
+		}
+		for _, pattern := range r.Patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("redact rule %d: %w", i, err)
+			}
+			rule.detectors = append(rule.detectors, &detector{name: pattern, re: re})
+		}
+		if len(rule.detectors) == 0 {
+			return nil, fmt.Errorf("redact rule %d: no detector or pattern", i)
+		}
+
+		p.rules = append(p.rules, rule)
+	}
+
+	if len(p.rules) == 0 {
+		return nil, errors.New("redact: no rule")
+	}
+
+	return p, nil
+}
+
+func (p *RedactProcessor) Process(entry Entry) Entry {
+	path := make([]string, 1, 8)
+
+	for k, v := range entry.Fields {
+		path[0] = k
+		entry.Fields[k] = p.redactValue(v, path)
+	}
+	if len(entry.Attrs) > 0 {
+		entry.Attrs = p.redactAttrs(entry.Attrs, path)
+	}
+
+	return entry
+}
+
+// redactAttrs returns the typed fields redacted, in a copy if anything changed: the caller may reuse them.
+func (p *RedactProcessor) redactAttrs(attrs []Field, path []string) []Field {
+	var out []Field
+	for i, f := range attrs {
+		path[0] = f.Key
+		if apply, descend := p.rulesAt(path); !apply && !descend {
+			continue
+		}
+
+		redacted, changed := f, false
+		switch f.kind {
+		case KindString:
+			if v := p.redactValue(f.str, path).(string); v != f.str {
+				redacted, changed = String(f.Key, v), true
+			}
+		case KindRawJSON:
+			raw := f.any.(json.RawMessage)
+			if v := p.redactValue(raw, path).(json.RawMessage); string(v) != string(raw) {
+				redacted, changed = RawJSON(f.Key, v), true
+			}
+		case KindAny:
+			redacted, changed = Any(f.Key, p.redactValue(f.any, path)), true
+		}
+		if !changed {
+			continue
+		}
+
+		if out == nil {
+			out = make([]Field, len(attrs))
+			copy(out, attrs)
+		}
+		out[i] = redacted
+	}
+
+	if out == nil {
+		return attrs
+	}
+	return out
+}
+
+// redactValue redacts v at path, in place for maps and slices.
+func (p *RedactProcessor) redactValue(v any, path []string) any {
+	apply, descend := p.rulesAt(path)
+	if !apply && !descend {
+		return v
+	}
+
+	switch v := v.(type) {
+	case string:
+		if apply {
+			return p.redactText(v, path)
+		}
+	case json.RawMessage:
+		// The replacements are made of characters which don't need escaping, the JSON stays valid.
+		if apply {
+			if s := p.redactText(string(v), path); s != string(v) {
+				return json.RawMessage(s)
+			}
+		}
+	case map[string]any:
+		for k, vv := range v {
+			v[k] = p.redactValue(vv, append(path, k))
+		}
+	case map[string]string:
+		for k, vv := range v {
+			v[k] = p.redactValue(vv, append(path, k)).(string)
+		}
+	case []any:
+		for i, vv := range v {
+			v[i] = p.redactValue(vv, path)
+		}
+	case []string:
+		for i, vv := range v {
+			v[i] = p.redactValue(vv, path).(string)
+		}
+	}
+
+	return v
+}
+
+// rulesAt reports whether a rule applies at path, or may apply below it.
+func (p *RedactProcessor) rulesAt(path []string) (apply, descend bool) {
+	for _, r := range p.rules {
+		a, d := r.at(path)
+		apply = apply || a
+		descend = descend || d
+	}
+	return apply, descend
+}
+
+func (r *redactRule) at(path []string) (apply, descend bool) {
+	if r.paths == nil {
+		return true, false
+	}
+	for _, rp := range r.paths {
+		if pathPrefix(rp, path) {
+			return true, false
+		}
+		if len(path) < len(rp) && pathPrefix(path, rp[:len(path)]) {
+			descend = true
+		}
+	}
+	return false, descend
+}
+
+// pathPrefix reports whether prefix matches the first segments of path.
+func pathPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i, seg := range prefix {
+		if seg != "*" && path[i] != "*" && !strings.EqualFold(seg, path[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// redactText applies the rules applying at path to s.
+func (p *RedactProcessor) redactText(s string, path []string) string {
+	for _, r := range p.rules {
+		if apply, _ := r.at(path); !apply {
+			continue
+		}
+		for _, d := range r.detectors {
+			s = p.redactMatches(s, d, r.action)
+		}
+	}
+	return s
+}
+
+func (p *RedactProcessor) redactMatches(s string, d *detector, action RedactAction) string {
+	if d.hint != nil && !d.hint(s) {
+		return s
+	}
+	matches := d.re.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		// Redact the first group when there's one.
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		match := s[start:end]
+		if d.valid != nil && !d.valid(match) {
+			continue
+		}
+		if b.Len() == 0 {
+			b.Grow(len(s))
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(p.replacement(match, d, action))
+		last = end
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+func (p *RedactProcessor) replacement(match string, d *detector, action RedactAction) string {
+	switch action {
+	case RedactLast4:
+		kept := match
+		if d.name == DetectPAN || d.name == DetectPhone {
+			kept = digitsOnly(match)
+		}
+		if n := utf8.RuneCountInString(kept); n > 4 {
+			runes := []rune(kept)
+			kept = string(runes[n-4:])
+		} else {
+			// Too short to keep anything.
+			kept = ""
+		}
+		return "****" + kept
+	case RedactHash:
+		if d.normalize != nil {
+			match = d.normalize(match)
+		}
+		mac := hmac.New(sha256.New, p.secret)
+		mac.Write([]byte(match))
+		return "hmac:" + hex.EncodeToString(mac.Sum(nil)[:16])
+	default:
+		return "****"
+	}
+}
+
+func digitsOnly(s string) string {
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c >= '0' && c <= '9' {
+			b = append(b, c)
+		}
+	}
+	return string(b)
+}
+
+// hasDigits returns a hint for text with at least n digits.
+func hasDigits(n int) func(s string) bool {
+	return func(s string) bool {
+		count := 0
+		for i := 0; i < len(s); i++ {
+			if s[i] >= '0' && s[i] <= '9' {
+				if count++; count >= n {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
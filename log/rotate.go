@@ -0,0 +1,377 @@
+package log
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/retail-ai-inc/bean/v2/helpers"
+)
+
+// RotateConfig configures a `RotatingFile`. The zero value never rotates, the file is only
+// reopened on SIGHUP.
+type RotateConfig struct {
+	// MaxSize rotates the file before a write would make it bigger than this many bytes.
+	MaxSize int64
+	// Interval rotates the file when the current interval (a day for `24 * time.Hour`, in UTC) ends.
+	Interval time.Duration
+	// Compress gzips the rotated files.
+	Compress bool
+	// MaxFiles is the number of rotated files kept, all of them if 0.
+	MaxFiles int
+	// MaxAge removes the rotated files older than this, none if 0.
+	MaxAge time.Duration
+}
+
+const rotateTimeFormat = "20060102T150405.000"
+
+// RotatingFile is an `io.WriteCloser` appending to a file which it rotates by size and/or time.
+// Rotated files are renamed `<name>-<UTC time><ext>` next to it. The file is reopened on SIGHUP,
+// so an external logrotate can move it away. It's safe for concurrent use, including by the
+// writer goroutine of an async sink.
+type RotatingFile struct {
+	path string
+	cfg  RotateConfig
+
+	mu           sync.Mutex
+	file         *os.File
+	size         int64
+	nextRotation time.Time
+	closed       bool
+
+	// Compression and cleanup of the rotated files runs in the background, one at a time.
+	millCh chan struct{}
+	millWg sync.WaitGroup
+
+	now func() time.Time
+}
+
+// NewRotatingFile opens, or creates with its directory, the file at `path`.
+func NewRotatingFile(path string, cfg RotateConfig) (*RotatingFile, error) {
+	f := &RotatingFile{
+		path:   path,
+		cfg:    cfg,
+		millCh: make(chan struct{}, 1),
+		now:    time.Now,
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	f.millWg.Add(1)
+	go f.runMill()
+
+	watchSIGHUP(f)
+
+	return f, nil
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+
+	if f.shouldRotate(int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate rotates the file now.
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return os.ErrClosed
+	}
+	return f.rotate()
+}
+
+// Reopen closes and reopens the file at the same path, without rotating it. It's called on SIGHUP.
+func (f *RotatingFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return os.ErrClosed
+	}
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+// Close closes the file and waits for the rotated files to be compressed.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return nil
+	}
+	f.closed = true
+	err := f.file.Close()
+	f.mu.Unlock()
+
+	unwatchSIGHUP(f)
+	close(f.millCh)
+	f.millWg.Wait()
+
+	return err
+}
+
+func (f *RotatingFile) open() error {
+	file, err := helpers.OpenFile(f.path)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+	if f.cfg.Interval > 0 {
+		f.nextRotation = f.now().UTC().Truncate(f.cfg.Interval).Add(f.cfg.Interval)
+	}
+
+	return nil
+}
+
+func (f *RotatingFile) shouldRotate(n int64) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.cfg.MaxSize > 0 && f.size+n > f.cfg.MaxSize {
+		return true
+	}
+	return f.cfg.Interval > 0 && !f.now().Before(f.nextRotation)
+}
+
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(f.path, f.backupName(f.now())); err != nil && !errors.Is(err, os.ErrNotExist) {
+		// Keep writing to the current file rather than losing entries.
+		if openErr := f.open(); openErr != nil {
+			return errors.Join(err, openErr)
+		}
+		return err
+	}
+
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	select {
+	case f.millCh <- struct{}{}:
+	default:
+		// A run is pending already, it picks this file up too.
+	}
+
+	return nil
+}
+
+// backupName returns the name of a rotated file, moving the time on by a millisecond while
+// the name is taken, so a rotated file is never overwritten.
+func (f *RotatingFile) backupName(t time.Time) string {
+	dir, name := filepath.Split(f.path)
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	t = t.UTC().Truncate(time.Millisecond)
+	for {
+		backup := filepath.Join(dir, fmt.Sprintf("%s-%s%s", base, t.Format(rotateTimeFormat), ext))
+		if !fileExists(backup) && !fileExists(backup+".gz") {
+			return backup
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func (f *RotatingFile) runMill() {
+	defer f.millWg.Done()
+	for range f.millCh {
+		_ = f.mill()
+	}
+}
+
+type backupFile struct {
+	path string
+	time time.Time
+}
+
+// mill compresses the rotated files, if configured, then removes the ones over `MaxFiles` or `MaxAge`.
+func (f *RotatingFile) mill() error {
+	backups, err := f.backups()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if f.cfg.Compress {
+		for i, b := range backups {
+			if strings.HasSuffix(b.path, ".gz") {
+				continue
+			}
+			if err := compressFile(b.path); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			backups[i].path += ".gz"
+		}
+	}
+
+	cutoff := time.Time{}
+	if f.cfg.MaxAge > 0 {
+		cutoff = f.now().Add(-f.cfg.MaxAge)
+	}
+	for i, b := range backups {
+		if (f.cfg.MaxFiles > 0 && i >= f.cfg.MaxFiles) || b.time.Before(cutoff) {
+			if err := os.Remove(b.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// backups returns the rotated files of the file, newest first.
+func (f *RotatingFile) backups() ([]backupFile, error) {
+	dir, name := filepath.Split(f.path)
+	ext := filepath.Ext(name)
+	prefix := strings.TrimSuffix(name, ext) + "-"
+
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []backupFile
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), prefix) {
+			continue
+		}
+		ts := strings.TrimPrefix(e.Name(), prefix)
+		ts = strings.TrimSuffix(strings.TrimSuffix(ts, ".gz"), ext)
+		t, err := time.Parse(rotateTimeFormat, ts)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{path: filepath.Join(dir, e.Name()), time: t})
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].time.After(backups[j].time) })
+
+	return backups, nil
+}
+
+func compressFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o664)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(path + ".gz")
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// sighup reopens every open `RotatingFile` on SIGHUP.
+var sighup = struct {
+	sync.Mutex
+	files   map[*RotatingFile]struct{}
+	signals chan os.Signal
+}{files: map[*RotatingFile]struct{}{}}
+
+func watchSIGHUP(f *RotatingFile) {
+	sighup.Lock()
+	defer sighup.Unlock()
+
+	sighup.files[f] = struct{}{}
+	if sighup.signals != nil {
+		return
+	}
+
+	sighup.signals = make(chan os.Signal, 1)
+	signal.Notify(sighup.signals, syscall.SIGHUP)
+	go func(signals chan os.Signal) {
+		for range signals {
+			sighup.Lock()
+			files := make([]*RotatingFile, 0, len(sighup.files))
+			for f := range sighup.files {
+				files = append(files, f)
+			}
+			sighup.Unlock()
+
+			for _, f := range files {
+				_ = f.Reopen()
+			}
+		}
+	}(sighup.signals)
+}
+
+func unwatchSIGHUP(f *RotatingFile) {
+	sighup.Lock()
+	defer sighup.Unlock()
+
+	delete(sighup.files, f)
+	if len(sighup.files) == 0 && sighup.signals != nil {
+		// Give SIGHUP its default behaviour back.
+		signal.Stop(sighup.signals)
+		close(sighup.signals)
+		sighup.signals = nil
+	}
+}
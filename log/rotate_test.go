@@ -0,0 +1,134 @@
+package log
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatingFile_MaxSize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logs", "access.log")
+
+	f, err := NewRotatingFile(path, RotateConfig{MaxSize: 10, Compress: true, MaxFiles: 2})
+	require.NoError(t, err)
+
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	f.now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, f.Close())
+
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "fourth\n", string(current))
+
+	// The oldest rotated file is over `MaxFiles`, the others are compressed.
+	backups, err := filepath.Glob(filepath.Join(dir, "logs", "access-*"))
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+	for _, b := range backups {
+		assert.True(t, strings.HasSuffix(b, ".log.gz"), b)
+	}
+	assert.Equal(t, "third\n", readGzip(t, backups[1]))
+}
+
+func TestRotatingFile_Interval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+
+	now := time.Date(2026, 1, 1, 23, 59, 0, 0, time.UTC)
+	f, err := NewRotatingFile(path, RotateConfig{Interval: 24 * time.Hour})
+	require.NoError(t, err)
+	f.now = func() time.Time { return now }
+	f.nextRotation = now.Truncate(24 * time.Hour).Add(24 * time.Hour)
+
+	_, _ = f.Write([]byte("day one\n"))
+	now = now.Add(2 * time.Minute)
+	_, _ = f.Write([]byte("day two\n"))
+	require.NoError(t, f.Close())
+
+	rotated, err := os.ReadFile(filepath.Join(filepath.Dir(path), "access-20260102T000100.000.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "day one\n", string(rotated))
+}
+
+func TestRotatingFile_ReopenOnSIGHUP(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+
+	f, err := NewRotatingFile(path, RotateConfig{})
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	_, _ = f.Write([]byte("before\n"))
+
+	// An external logrotate moves the file away, then signals the process.
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	_, _ = f.Write([]byte("after\n"))
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "after\n", string(current))
+}
+
+func TestRotatingFile_AsyncSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+
+	f, err := NewRotatingFile(path, RotateConfig{MaxSize: 256})
+	require.NoError(t, err)
+
+	s, err := NewSink(f, "trace", SinkConfig{Async: true, QueueSize: 1024})
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		if i%10 == 0 {
+			require.NoError(t, f.Rotate())
+		}
+		require.NoError(t, s.Write(Entry{Timestamp: time.Now(), Severity: Info, Level: "ACCESS", Fields: map[string]any{"i": i}}))
+	}
+	require.NoError(t, s.Close(context.Background()))
+
+	files, err := filepath.Glob(filepath.Join(filepath.Dir(path), "access*"))
+	require.NoError(t, err)
+	lines := 0
+	for _, name := range files {
+		b, err := os.ReadFile(name)
+		require.NoError(t, err)
+		lines += strings.Count(string(b), "\n")
+	}
+	assert.Equal(t, 100, lines)
+}
+
+func readGzip(t *testing.T, path string) string {
+	t.Helper()
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() { _ = file.Close() }()
+
+	gz, err := gzip.NewReader(file)
+	require.NoError(t, err)
+	b, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	return string(b)
+}
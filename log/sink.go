@@ -5,7 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
 	"strings"
 	"sync"
@@ -32,6 +31,10 @@ var ErrSinkClosed = errors.New("log sink is closed")
 type SinkConfig struct {
 	Async     bool
 	QueueSize int
+	// Overflow configures what a write does when the queue of an async sink is full.
+	Overflow OverflowConfig
+	// Encoder writes the entries, one JSON object per line if nil.
+	Encoder Encoder
 }
 
 // NopWriteCloser wraps an io.Writer with a no-op Close so it satisfies io.WriteCloser.
@@ -43,27 +46,49 @@ type sink struct {
 	out          io.WriteCloser
 	payloadTrace string
 	async        bool
+	// encode writes the entries with their payload. Nil is the JSON encoder, which doesn't need it.
+	encode Encoder
 
 	queue    chan *bytes.Buffer
 	workerWg sync.WaitGroup
+	overflow OverflowConfig
+	spill    *spillFile
 
 	// Write holds RLock; Close holds Lock — guarantees no sender after close(queue).
-	mu      sync.RWMutex
-	closed  bool
-	dropped atomic.Uint64
+	mu       sync.RWMutex
+	closed   bool
+	dropped  atomic.Uint64
+	spilled  atomic.Uint64
+	replayed atomic.Uint64
 }
 
 func NewSink(out io.WriteCloser, payloadTrace string, cfg SinkConfig) (*sink, error) {
+	return newSink(out, payloadTrace, cfg, cfg.Encoder)
+}
+
+func newSink(out io.WriteCloser, payloadTrace string, cfg SinkConfig, encode Encoder) (*sink, error) {
 	gs := &sink{
 		out:          out,
 		payloadTrace: strings.TrimSpace(payloadTrace),
 		async:        cfg.Async,
+		encode:       encode,
 	}
 
 	if !cfg.Async {
 		return gs, nil
 	}
 
+	overflow, err := cfg.Overflow.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	gs.overflow = overflow
+	if overflow.Policy == OverflowSpill {
+		if gs.spill, err = openSpillFile(overflow.SpillPath, overflow.SpillMaxSize); err != nil {
+			return nil, err
+		}
+	}
+
 	qsize := cfg.QueueSize
 	if qsize <= 0 {
 		qsize = defaultAsyncQueueSize
@@ -83,54 +108,78 @@ func (g *sink) Write(e Entry) error {
 	}
 	defer g.mu.RUnlock()
 
-	payload := payloadPool.Get().(map[string]any)
-	payload["timestamp"] = e.Timestamp.Format(time.RFC3339Nano)
-	payload["severity"] = e.Severity
-	payload["level"] = e.Level
+	buf, err := g.encodeEntry(e)
+	if err != nil {
+		return err
+	}
 
-	for k, v := range e.Fields {
-		payload[k] = v
+	if !g.async {
+		_, err = g.out.Write(buf.Bytes())
+		bufPool.Put(buf)
+		return err
 	}
 
-	if e.Trace.TraceID != "" {
-		payload[g.payloadTrace] = e.Trace.TraceID
+	select {
+	case g.queue <- buf:
+		return nil
+	default:
+		g.overflowWrite(buf)
+		return nil
 	}
+}
 
+func (g *sink) encodeEntry(e Entry) (*bytes.Buffer, error) {
 	buf := bufPool.Get().(*bytes.Buffer)
 	buf.Reset()
 
-	err := json.NewEncoder(buf).Encode(payload)
+	if g.encode == nil {
+		buf.Write(appendEntryJSON(buf.AvailableBuffer(), e, g.payloadTrace))
+		return buf, nil
+	}
+
+	payload := payloadPool.Get().(map[string]any)
+	g.fillPayload(payload, e)
+
+	err := g.encode(buf, e, payload)
 
 	clear(payload)
 	payloadPool.Put(payload)
 
 	if err != nil {
 		bufPool.Put(buf)
-		return err
+		return nil, err
 	}
 
-	if !g.async {
-		_, err = g.out.Write(buf.Bytes())
-		bufPool.Put(buf)
-		return err
+	return buf, nil
+}
+
+// fillPayload sets the fields written for an entry, with the trace id, span id and sampling decision under
+// the keys of the runtime platform.
+func (g *sink) fillPayload(payload map[string]any, e Entry) {
+	payload["timestamp"] = e.Timestamp.Format(time.RFC3339Nano)
+	payload["severity"] = e.Severity
+	payload["level"] = e.Level
+
+	for k, v := range e.Fields {
+		payload[k] = v
+	}
+	for _, f := range e.Attrs {
+		payload[f.Key] = f.Value()
 	}
 
-	select {
-	case g.queue <- buf:
-		return nil
-	default:
-		g.dropped.Add(1)
-		bufPool.Put(buf)
-		return nil
+	if e.Trace.TraceID != "" {
+		payload[g.payloadTrace] = e.Trace.TraceID
+		if e.Trace.SpanID != "" {
+			spanKey, sampledKey := spanPayloadKeys(g.payloadTrace)
+			payload[spanKey] = e.Trace.SpanID
+			payload[sampledKey] = e.Trace.Sampled
+		}
 	}
 }
 
-func (g *sink) runWriter() {
-	defer g.workerWg.Done()
-	for buf := range g.queue {
-		_, _ = g.out.Write(buf.Bytes())
-		bufPool.Put(buf)
-	}
+// encodeJSONLine writes the payload, for the encoders adding to it.
+func encodeJSONLine(buf *bytes.Buffer, _ Entry, payload map[string]any) error {
+	return json.NewEncoder(buf).Encode(payload)
 }
 
 func (g *sink) Close(ctx context.Context) error {
@@ -144,6 +193,7 @@ func (g *sink) Close(ctx context.Context) error {
 
 	var drainErr error
 	if g.async {
+		// The writer drains the queue and the spill file, then reports what was dropped.
 		close(g.queue)
 
 		done := make(chan struct{})
@@ -159,13 +209,14 @@ func (g *sink) Close(ctx context.Context) error {
 		}
 	}
 
-	if dropped := g.dropped.Load(); dropped > 0 {
-		_, _ = fmt.Fprintf(g.out, "{\"severity\":\"WARNING\",\"message\":\"log entries dropped\",\"dropped_count\":%d}\n", dropped)
-	}
-
 	return errors.Join(drainErr, g.out.Close())
 }
 
 func (g *sink) DroppedCount() uint64 {
 	return g.dropped.Load()
 }
+
+// SpilledCount returns the number of entries written to the spill file.
+func (g *sink) SpilledCount() uint64 {
+	return g.spilled.Load()
+}
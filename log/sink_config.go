@@ -0,0 +1,81 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/retail-ai-inc/bean/v2/config"
+)
+
+// configSinkRoutes builds the routes of the sinks configured in `env.json`. The sinks without
+// an encoding of their own use `encoder`.
+func configSinkRoutes(elogger echo.Logger, payloadTrace string, encoder Encoder, sinks []config.LogSink) ([]SinkRoute, error) {
+	routes := make([]SinkRoute, 0, len(sinks))
+
+	for i, sc := range sinks {
+		s, err := configSink(elogger, payloadTrace, encoder, sc)
+		if err != nil {
+			closeRoutes(routes)
+			return nil, fmt.Errorf("log sink %d (%s): %w", i, sc.Type, err)
+		}
+
+		route := SinkRoute{
+			Sink:        s,
+			MinSeverity: Severity(strings.ToUpper(sc.MinSeverity)),
+			Levels:      sc.Levels,
+			Async:       sc.Async,
+			QueueSize:   sc.QueueSize,
+		}
+		if sc.SampleRate != nil {
+			route.Filter = SampleFilter(*sc.SampleRate)
+		}
+		routes = append(routes, route)
+	}
+
+	return routes, nil
+}
+
+// configSink builds a synchronous sink: the route is async itself, the sink writes from its goroutine.
+func configSink(elogger echo.Logger, payloadTrace string, encoder Encoder, sc config.LogSink) (Sink, error) {
+	if sc.Encoding.Format != "" {
+		var err error
+		if encoder, err = NewEncoder(EncoderConfigFrom(sc.Encoding)); err != nil {
+			return nil, err
+		}
+	}
+
+	netCfg := NetSinkConfig{Network: strings.ToLower(sc.Network), Address: sc.Address, Encoder: encoder}
+
+	switch strings.ToLower(sc.Type) {
+	case "", "stdout":
+		return NewSink(NopWriteCloser{Writer: elogger.Output()}, payloadTrace, SinkConfig{Encoder: encoder})
+	case "file":
+		if sc.Path == "" {
+			return nil, errors.New("path is empty")
+		}
+		file, err := NewRotatingFile(sc.Path, RotateConfigFrom(sc.Rotation))
+		if err != nil {
+			return nil, err
+		}
+		return NewSink(file, payloadTrace, SinkConfig{Encoder: encoder})
+	case "syslog":
+		return NewSyslogSink(payloadTrace, netCfg, SyslogConfig{Facility: sc.Facility, AppName: sc.Tag})
+	case "jsonlines":
+		return NewJSONLinesSink(payloadTrace, netCfg)
+	case "fluent":
+		return NewFluentSink(payloadTrace, netCfg, sc.Tag)
+	default:
+		return nil, fmt.Errorf("unknown sink type %q", sc.Type)
+	}
+}
+
+func closeRoutes(routes []SinkRoute) {
+	for _, r := range routes {
+		if c, ok := r.Sink.(interface{ Close(context.Context) error }); ok {
+			_ = c.Close(context.Background())
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// NewFluentSink sends every entry to Fluentd or Fluent Bit with the Forward protocol, in message mode:
+// `[tag, EventTime, record]` where the record is the payload of `NewSink`.
+func NewFluentSink(payloadTrace string, cfg NetSinkConfig, tag string) (*sink, error) {
+	if cfg.Network == "udp" {
+		return nil, errors.New("log sink: the forward protocol needs a stream network, tcp or tls")
+	}
+	if tag == "" {
+		tag = "bean"
+	}
+
+	w, err := newNetWriter(cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	encode := func(buf *bytes.Buffer, e Entry, payload map[string]any) error {
+		// The fields hold any type: take them through JSON, as the other sinks write them.
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		var record any
+		if err := dec.Decode(&record); err != nil {
+			return err
+		}
+
+		buf.WriteByte(0x93) // fixarray of 3.
+		msgpackString(buf, tag)
+		msgpackEventTime(buf, e.Timestamp)
+		msgpackValue(buf, record)
+		return nil
+	}
+
+	return newSink(w, payloadTrace, cfg.sinkConfig(), encode)
+}
+
+// msgpackEventTime writes the EventTime extension of the forward protocol: seconds and nanoseconds.
+func msgpackEventTime(buf *bytes.Buffer, t time.Time) {
+	var b [10]byte
+	b[0], b[1] = 0xd7, 0x00 // fixext 8, type 0.
+	binary.BigEndian.PutUint32(b[2:], uint32(t.Unix()))
+	binary.BigEndian.PutUint32(b[6:], uint32(t.Nanosecond()))
+	buf.Write(b[:])
+}
+
+// msgpackValue writes the values decoded from JSON with `UseNumber`.
+func msgpackValue(buf *bytes.Buffer, v any) {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			msgpackInt(buf, i)
+		} else if f, err := v.Float64(); err == nil {
+			msgpackFloat(buf, f)
+		} else {
+			msgpackString(buf, v.String())
+		}
+	case string:
+		msgpackString(buf, v)
+	case []any:
+		msgpackLen(buf, len(v), 0x90, 0xdc, 0xdd)
+		for _, vv := range v {
+			msgpackValue(buf, vv)
+		}
+	case map[string]any:
+		msgpackLen(buf, len(v), 0x80, 0xde, 0xdf)
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			msgpackString(buf, k)
+			msgpackValue(buf, v[k])
+		}
+	default:
+		buf.WriteByte(0xc0)
+	}
+}
+
+func msgpackString(buf *bytes.Buffer, s string) {
+	switch n := len(s); {
+	case n < 32:
+		buf.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(0xd9)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(0xda)
+		_ = binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(0xdb)
+		_ = binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+	buf.WriteString(s)
+}
+
+// msgpackLen writes the header of an array or a map: fix, 16 or 32 bits length.
+func msgpackLen(buf *bytes.Buffer, n int, fix, b16, b32 byte) {
+	switch {
+	case n < 16:
+		buf.WriteByte(fix | byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(b16)
+		_ = binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(b32)
+		_ = binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+func msgpackInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i < 128:
+		buf.WriteByte(byte(i))
+	case i < 0 && i >= -32:
+		buf.WriteByte(byte(i))
+	default:
+		buf.WriteByte(0xd3)
+		_ = binary.Write(buf, binary.BigEndian, i)
+	}
+}
+
+func msgpackFloat(buf *bytes.Buffer, f float64) {
+	buf.WriteByte(0xcb)
+	_ = binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+}
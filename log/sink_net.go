@@ -0,0 +1,187 @@
+package log
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// NetSinkConfig configures the connection of a network sink. The connection is opened on the first
+// write and reopened after a failure, waiting longer after each failed attempt. Entries written
+// while it's down are lost, so use `Async` to keep an unreachable endpoint off the request path.
+type NetSinkConfig struct {
+	// Network is `tcp`, `tls`, or `udp` for syslog. Default `tcp`.
+	Network string
+	// Address is the `host:port` of the endpoint.
+	Address string
+	// TLSConfig is used by the `tls` network. The system roots are trusted if nil.
+	TLSConfig *tls.Config
+	// DialTimeout and WriteTimeout default to 5s.
+	DialTimeout  time.Duration
+	WriteTimeout time.Duration
+	// MinBackoff and MaxBackoff bound the wait between two connection attempts. Default 100ms and 30s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	Async     bool
+	QueueSize int
+	Overflow  OverflowConfig
+	// Encoder writes the JSON lines and syslog messages, JSON if nil. The forward protocol is always msgpack.
+	Encoder Encoder
+}
+
+func (c NetSinkConfig) sinkConfig() SinkConfig {
+	return SinkConfig{Async: c.Async, QueueSize: c.QueueSize, Overflow: c.Overflow, Encoder: c.Encoder}
+}
+
+var errNetSinkBackoff = errors.New("log sink: waiting to reconnect")
+
+// netWriter writes each buffer to a connection, which it reopens with backoff after a failure.
+type netWriter struct {
+	cfg NetSinkConfig
+	// frame wraps a message for stream transports, e.g. with its length. Nil writes it as is.
+	frame func(p []byte) []byte
+
+	mu       sync.Mutex
+	conn     net.Conn
+	backoff  time.Duration
+	nextDial time.Time
+	closed   bool
+}
+
+func newNetWriter(cfg NetSinkConfig, frame func(p []byte) []byte) (*netWriter, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("log sink: address is empty")
+	}
+	if cfg.Network == "" {
+		cfg.Network = "tcp"
+	}
+	switch cfg.Network {
+	case "tcp", "tls", "udp":
+	default:
+		return nil, fmt.Errorf("log sink: unsupported network %q", cfg.Network)
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = 5 * time.Second
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = 5 * time.Second
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = 100 * time.Millisecond
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = max(30*time.Second, cfg.MinBackoff)
+	}
+
+	if cfg.Network == "udp" {
+		// Datagrams carry their own boundaries.
+		frame = nil
+	}
+
+	return &netWriter{cfg: cfg, frame: frame}, nil
+}
+
+func (w *netWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, ErrSinkClosed
+	}
+
+	msg := p
+	if w.frame != nil {
+		msg = w.frame(p)
+	}
+
+	// A connection closed by the peer usually only fails on the next write: retry once on a new one.
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if err = w.connect(); err != nil {
+			return 0, err
+		}
+
+		_ = w.conn.SetWriteDeadline(time.Now().Add(w.cfg.WriteTimeout))
+		if _, err = w.conn.Write(msg); err == nil {
+			w.backoff = 0
+			return len(p), nil
+		}
+
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+
+	w.wait()
+	return 0, err
+}
+
+func (w *netWriter) connect() error {
+	if w.conn != nil {
+		return nil
+	}
+	if time.Now().Before(w.nextDial) {
+		return errNetSinkBackoff
+	}
+
+	dialer := &net.Dialer{Timeout: w.cfg.DialTimeout}
+	var conn net.Conn
+	var err error
+	switch w.cfg.Network {
+	case "tls":
+		conn, err = tls.DialWithDialer(dialer, "tcp", w.cfg.Address, w.cfg.TLSConfig)
+	default:
+		conn, err = dialer.Dial(w.cfg.Network, w.cfg.Address)
+	}
+	if err != nil {
+		w.wait()
+		return err
+	}
+
+	w.conn = conn
+	return nil
+}
+
+// wait doubles the time until the next connection attempt.
+func (w *netWriter) wait() {
+	w.backoff = min(max(2*w.backoff, w.cfg.MinBackoff), w.cfg.MaxBackoff)
+	w.nextDial = time.Now().Add(w.backoff)
+}
+
+func (w *netWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// octetCounting frames a message as `<length> <message>`, as RFC 6587 and RFC 5425 do for syslog.
+func octetCounting(p []byte) []byte {
+	msg := make([]byte, 0, len(p)+8)
+	msg = strconv.AppendInt(msg, int64(len(p)), 10)
+	msg = append(msg, ' ')
+	return append(msg, p...)
+}
+
+// NewJSONLinesSink sends every entry as a line of JSON, the payload of `NewSink`, over TCP or TLS.
+// With `Encoder`, a line of its format.
+func NewJSONLinesSink(payloadTrace string, cfg NetSinkConfig) (*sink, error) {
+	if cfg.Network == "udp" {
+		return nil, errors.New("log sink: JSON lines need a stream network, tcp or tls")
+	}
+	w, err := newNetWriter(cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+	return NewSink(w, payloadTrace, cfg.sinkConfig())
+}
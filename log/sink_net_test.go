@@ -0,0 +1,294 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"net"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEntry(level string, fields map[string]any) Entry {
+	return Entry{
+		Timestamp: time.Date(2026, 1, 2, 3, 4, 5, 6000, time.UTC),
+		Severity:  Info,
+		Level:     level,
+		Fields:    fields,
+		Trace:     Trace{TraceID: "trace-1"},
+	}
+}
+
+// acceptLines accepts connections one after the other and sends every line read.
+func acceptLines(t *testing.T, ln net.Listener) <-chan string {
+	t.Helper()
+
+	lines := make(chan string, 16)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			r := bufio.NewReader(conn)
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					break
+				}
+				lines <- line
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	return lines
+}
+
+func TestJSONLinesSink(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+	lines := acceptLines(t, ln)
+
+	s, err := NewJSONLinesSink(tracePayloadKey("gcp"), NetSinkConfig{Address: ln.Addr().String()})
+	require.NoError(t, err)
+
+	require.NoError(t, s.Write(testEntry("ACCESS", map[string]any{"id": "req-1"})))
+
+	var payload map[string]any
+	require.NoError(t, json.Unmarshal([]byte(<-lines), &payload))
+	assert.Equal(t, "ACCESS", payload["level"])
+	assert.Equal(t, "req-1", payload["id"])
+	assert.Equal(t, "trace-1", payload["logging.googleapis.com/trace"])
+
+	require.NoError(t, s.Close(context.Background()))
+}
+
+func TestJSONLinesSink_Reconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+
+	s, err := NewJSONLinesSink("trace", NetSinkConfig{Address: addr, MinBackoff: 20 * time.Millisecond})
+	require.NoError(t, err)
+	defer func() { _ = s.Close(context.Background()) }()
+
+	// The endpoint is down: the sink waits before trying again.
+	require.NoError(t, ln.Close())
+	assert.Error(t, s.Write(testEntry("ACCESS", nil)))
+	assert.ErrorIs(t, s.Write(testEntry("ACCESS", nil)), errNetSinkBackoff)
+
+	ln, err = net.Listen("tcp", addr)
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+	lines := acceptLines(t, ln)
+
+	require.Eventually(t, func() bool {
+		return s.Write(testEntry("ACCESS", map[string]any{"id": "after"})) == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Contains(t, <-lines, `"id":"after"`)
+}
+
+func TestSyslogSink_UDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = pc.Close() }()
+
+	s, err := NewSyslogSink("trace", NetSinkConfig{Network: "udp", Address: pc.LocalAddr().String()},
+		SyslogConfig{Facility: 16, AppName: "my app", Hostname: "host-1"})
+	require.NoError(t, err)
+	defer func() { _ = s.Close(context.Background()) }()
+
+	entry := testEntry("ACCESS", map[string]any{"id": "req-1"})
+	entry.Severity = Error
+	require.NoError(t, s.Write(entry))
+
+	buf := make([]byte, 2048)
+	_ = pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	require.NoError(t, err)
+
+	// local0 (16) * 8 + error (3)
+	msg := string(buf[:n])
+	prefix := "<131>1 2026-01-02T03:04:05.000006Z host-1 myapp " + strconv.Itoa(os.Getpid()) + " ACCESS - "
+	require.True(t, strings.HasPrefix(msg, prefix), msg)
+
+	var payload map[string]any
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(msg, prefix)), &payload))
+	assert.Equal(t, "req-1", payload["id"])
+	assert.Equal(t, "trace-1", payload["trace"])
+}
+
+func TestSyslogSink_TLS(t *testing.T) {
+	srv := httptest.NewTLSServer(nil)
+	defer srv.Close()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: srv.TLS.Certificates})
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+
+	frames := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		r := bufio.NewReader(conn)
+		for {
+			// Octet counting: `<length> <message>`.
+			length, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(length))
+			msg := make([]byte, n)
+			if _, err := io.ReadFull(r, msg); err != nil {
+				return
+			}
+			frames <- string(msg)
+		}
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	s, err := NewSyslogSink("trace", NetSinkConfig{
+		Network:   "tls",
+		Address:   ln.Addr().String(),
+		TLSConfig: &tls.Config{RootCAs: roots, ServerName: "example.com"},
+	}, SyslogConfig{AppName: "app", Hostname: "host"})
+	require.NoError(t, err)
+	defer func() { _ = s.Close(context.Background()) }()
+
+	require.NoError(t, s.Write(testEntry("DUMP", map[string]any{"n": 1})))
+	require.NoError(t, s.Write(testEntry("DUMP", map[string]any{"n": 2})))
+
+	assert.True(t, strings.HasPrefix(<-frames, "<14>1 "))
+	assert.Contains(t, <-frames, `"n":2`)
+}
+
+func TestFluentSink(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		buf := make([]byte, 4096)
+		n, _ := conn.Read(buf)
+		received <- buf[:n]
+	}()
+
+	s, err := NewFluentSink("trace", NetSinkConfig{Address: ln.Addr().String()}, "bean.access")
+	require.NoError(t, err)
+	defer func() { _ = s.Close(context.Background()) }()
+
+	entry := testEntry("ACCESS", map[string]any{"status": 200, "latency": 1.5, "tags": []any{"a"}, "ok": true})
+	require.NoError(t, s.Write(entry))
+
+	msg := readMsgpack(t, bytes.NewReader(<-received))
+	arr, ok := msg.([]any)
+	require.True(t, ok)
+	require.Len(t, arr, 3)
+	assert.Equal(t, "bean.access", arr[0])
+	assert.Equal(t, entry.Timestamp, arr[1])
+
+	record := arr[2].(map[string]any)
+	assert.Equal(t, "ACCESS", record["level"])
+	assert.Equal(t, int64(200), record["status"])
+	assert.Equal(t, 1.5, record["latency"])
+	assert.Equal(t, []any{"a"}, record["tags"])
+	assert.Equal(t, true, record["ok"])
+	assert.Equal(t, "trace-1", record["trace"])
+}
+
+// readMsgpack decodes the subset of msgpack written by the fluent sink.
+func readMsgpack(t *testing.T, r *bytes.Reader) any {
+	t.Helper()
+
+	b, err := r.ReadByte()
+	require.NoError(t, err)
+
+	readN := func(n int) []byte {
+		p := make([]byte, n)
+		_, err := io.ReadFull(r, p)
+		require.NoError(t, err)
+		return p
+	}
+	readString := func(n int) string { return string(readN(n)) }
+	readArray := func(n int) []any {
+		arr := make([]any, n)
+		for i := range arr {
+			arr[i] = readMsgpack(t, r)
+		}
+		return arr
+	}
+	readMap := func(n int) map[string]any {
+		m := make(map[string]any, n)
+		for i := 0; i < n; i++ {
+			k := readMsgpack(t, r).(string)
+			m[k] = readMsgpack(t, r)
+		}
+		return m
+	}
+
+	switch {
+	case b < 0x80:
+		return int64(b)
+	case b >= 0xe0:
+		return int64(int8(b))
+	case b&0xf0 == 0x80:
+		return readMap(int(b & 0x0f))
+	case b&0xf0 == 0x90:
+		return readArray(int(b & 0x0f))
+	case b&0xe0 == 0xa0:
+		return readString(int(b & 0x1f))
+	}
+
+	switch b {
+	case 0xc0:
+		return nil
+	case 0xc2:
+		return false
+	case 0xc3:
+		return true
+	case 0xd9:
+		return readString(int(readN(1)[0]))
+	case 0xda:
+		return readString(int(binary.BigEndian.Uint16(readN(2))))
+	case 0xcb:
+		return math.Float64frombits(binary.BigEndian.Uint64(readN(8)))
+	case 0xd3:
+		return int64(binary.BigEndian.Uint64(readN(8)))
+	case 0xd7:
+		p := readN(9)
+		require.Equal(t, byte(0), p[0], "EventTime extension")
+		return time.Unix(int64(binary.BigEndian.Uint32(p[1:5])), int64(binary.BigEndian.Uint32(p[5:]))).UTC()
+	case 0xde:
+		return readMap(int(binary.BigEndian.Uint16(readN(2))))
+	case 0xdc:
+		return readArray(int(binary.BigEndian.Uint16(readN(2))))
+	}
+
+	t.Fatalf("unexpected msgpack type 0x%x", b)
+	return nil
+}
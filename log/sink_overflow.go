@@ -0,0 +1,329 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// OverflowPolicy is what a write does when the queue of an async sink is full.
+type OverflowPolicy string
+
+const (
+	// OverflowDropNewest drops the entry being written. It's the default.
+	OverflowDropNewest OverflowPolicy = "drop_newest"
+	// OverflowDropOldest drops the oldest queued entry to make room.
+	OverflowDropOldest OverflowPolicy = "drop_oldest"
+	// OverflowBlock waits up to `BlockTimeout` for room, then drops the entry.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowSpill appends the entry to a file on disk, replayed once the writer catches up.
+	// Replayed entries are written after the ones queued meanwhile.
+	OverflowSpill OverflowPolicy = "spill"
+)
+
+// OverflowConfig configures the overflow policy of an async sink and the reports of what it dropped.
+type OverflowConfig struct {
+	Policy OverflowPolicy
+	// BlockTimeout is the longest a write waits with `OverflowBlock`. Default 100ms.
+	BlockTimeout time.Duration
+	// SpillPath is the file `OverflowSpill` appends to. Entries left there by a previous run are replayed.
+	SpillPath string
+	// SpillMaxSize is the size in bytes over which entries are dropped instead of spilled. Default 256MB.
+	SpillMaxSize int64
+	// ReportInterval is how often a WARNING entry reports the entries dropped and spilled since the last one.
+	// Default 1 minute. There's a last report when the sink is closed.
+	ReportInterval time.Duration
+}
+
+func (o OverflowConfig) withDefaults() (OverflowConfig, error) {
+	o.Policy = OverflowPolicy(strings.ToLower(string(o.Policy)))
+	switch o.Policy {
+	case "":
+		o.Policy = OverflowDropNewest
+	case OverflowDropNewest, OverflowDropOldest:
+	case OverflowBlock:
+		if o.BlockTimeout <= 0 {
+			o.BlockTimeout = 100 * time.Millisecond
+		}
+	case OverflowSpill:
+		if o.SpillPath == "" {
+			return o, errors.New("log sink: spill path is empty")
+		}
+		if o.SpillMaxSize <= 0 {
+			o.SpillMaxSize = 256 << 20
+		}
+	default:
+		return o, fmt.Errorf("log sink: unknown overflow policy %q", o.Policy)
+	}
+
+	if o.ReportInterval <= 0 {
+		o.ReportInterval = time.Minute
+	}
+
+	return o, nil
+}
+
+var (
+	sinkMetricsOnce sync.Once
+	sinkEntries     = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "bean",
+		Subsystem: "log_sink",
+		Name:      "entries_total",
+		Help:      "Log entries which didn't fit in the queue of an async sink, by what happened to them.",
+	}, []string{"result"})
+)
+
+// registerSinkMetrics exposes the counters on the default registry, served by the `/metrics` endpoint.
+func registerSinkMetrics() {
+	sinkMetricsOnce.Do(func() {
+		_ = prometheus.DefaultRegisterer.Register(sinkEntries)
+	})
+}
+
+func (g *sink) overflowWrite(buf *bytes.Buffer) {
+	switch g.overflow.Policy {
+	case OverflowDropOldest:
+		select {
+		case old := <-g.queue:
+			bufPool.Put(old)
+			g.drop()
+		default:
+		}
+		select {
+		case g.queue <- buf:
+			return
+		default:
+		}
+
+	case OverflowBlock:
+		t := time.NewTimer(g.overflow.BlockTimeout)
+		defer t.Stop()
+		select {
+		case g.queue <- buf:
+			return
+		case <-t.C:
+		}
+
+	case OverflowSpill:
+		if err := g.spill.append(buf.Bytes()); err == nil {
+			g.spilled.Add(1)
+			sinkEntries.WithLabelValues("spilled").Inc()
+			bufPool.Put(buf)
+			return
+		}
+	}
+
+	g.drop()
+	bufPool.Put(buf)
+}
+
+func (g *sink) drop() {
+	g.dropped.Add(1)
+	sinkEntries.WithLabelValues("dropped").Inc()
+}
+
+func (g *sink) runWriter() {
+	defer g.workerWg.Done()
+
+	registerSinkMetrics()
+	t := time.NewTicker(g.overflow.ReportInterval)
+	defer t.Stop()
+
+	var reportedDropped, reportedSpilled uint64
+	report := func() {
+		dropped, spilled := g.dropped.Load(), g.spilled.Load()
+		g.report(dropped-reportedDropped, spilled-reportedSpilled)
+		reportedDropped, reportedSpilled = dropped, spilled
+	}
+	defer report()
+
+	write := func(buf *bytes.Buffer) {
+		_, _ = g.out.Write(buf.Bytes())
+		bufPool.Put(buf)
+	}
+
+	for {
+		// Spilled entries are replayed when the queue is empty.
+		if g.spill != nil && g.spill.pending() {
+			select {
+			case buf, ok := <-g.queue:
+				if !ok {
+					g.replayAll()
+					return
+				}
+				write(buf)
+			case <-t.C:
+				report()
+			default:
+				g.replayOne()
+			}
+			continue
+		}
+
+		select {
+		case buf, ok := <-g.queue:
+			if !ok {
+				g.replayAll()
+				return
+			}
+			write(buf)
+		case <-t.C:
+			report()
+		}
+	}
+}
+
+func (g *sink) replayOne() {
+	data, err := g.spill.next()
+	if err != nil {
+		// The rest of the file can't be read, give it up.
+		g.spill.reset()
+		return
+	}
+	_, _ = g.out.Write(data)
+	g.replayed.Add(1)
+	sinkEntries.WithLabelValues("replayed").Inc()
+}
+
+func (g *sink) replayAll() {
+	for g.spill != nil && g.spill.pending() {
+		g.replayOne()
+	}
+	if g.spill != nil {
+		_ = g.spill.close()
+	}
+}
+
+// report writes a WARNING entry with the counts of the entries dropped and spilled since the last report.
+func (g *sink) report(dropped, spilled uint64) {
+	if dropped == 0 && spilled == 0 {
+		return
+	}
+
+	message := "log entries dropped"
+	if dropped == 0 {
+		message = "log entries spilled to disk"
+	}
+
+	buf, err := g.encodeEntry(Entry{
+		Timestamp: time.Now(),
+		Severity:  Warning,
+		Level:     "LOG_SINK",
+		Fields: map[string]any{
+			"message":        message,
+			"policy":         string(g.overflow.Policy),
+			"dropped_count":  dropped,
+			"spilled_count":  spilled,
+			"dropped_total":  g.dropped.Load(),
+			"spilled_total":  g.spilled.Load(),
+			"replayed_total": g.replayed.Load(),
+		},
+	})
+	if err != nil {
+		return
+	}
+	_, _ = g.out.Write(buf.Bytes())
+	bufPool.Put(buf)
+}
+
+// spillFile is an append only file of length prefixed entries, read back from the start.
+// It's truncated once everything was read.
+type spillFile struct {
+	mu      sync.Mutex
+	file    *os.File
+	maxSize int64
+	readOff int64
+	size    int64
+}
+
+func openSpillFile(path string, maxSize int64) (*spillFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o764); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o664)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	// Entries left by a previous run are replayed first.
+	return &spillFile{file: file, maxSize: maxSize, size: info.Size()}, nil
+}
+
+var errSpillFull = errors.New("log sink: spill file is full")
+
+func (s *spillFile) append(p []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.size+int64(len(p))+4 > s.maxSize {
+		return errSpillFull
+	}
+
+	record := make([]byte, 4+len(p))
+	binary.BigEndian.PutUint32(record, uint32(len(p)))
+	copy(record[4:], p)
+	// A partial record is overwritten by the next one, the size only moves on success.
+	if _, err := s.file.WriteAt(record, s.size); err != nil {
+		return err
+	}
+	s.size += int64(len(record))
+
+	return nil
+}
+
+func (s *spillFile) pending() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readOff < s.size
+}
+
+func (s *spillFile) next() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var header [4]byte
+	if _, err := s.file.ReadAt(header[:], s.readOff); err != nil {
+		return nil, err
+	}
+	data := make([]byte, binary.BigEndian.Uint32(header[:]))
+	if n, err := s.file.ReadAt(data, s.readOff+4); n < len(data) {
+		return nil, err
+	}
+	s.readOff += 4 + int64(len(data))
+
+	if s.readOff >= s.size {
+		s.truncate()
+	}
+
+	return data, nil
+}
+
+func (s *spillFile) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.truncate()
+}
+
+func (s *spillFile) truncate() {
+	_ = s.file.Truncate(0)
+	s.readOff, s.size = 0, 0
+}
+
+func (s *spillFile) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
@@ -0,0 +1,152 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatedWriter blocks every write until the gate is opened.
+type gatedWriter struct {
+	gate chan struct{}
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{gate: make(chan struct{})}
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	<-w.gate
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gatedWriter) Close() error { return nil }
+
+func (w *gatedWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func writeN(t *testing.T, s *sink, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		require.NoError(t, s.Write(Entry{Timestamp: time.Now(), Severity: Info, Level: "ACCESS", Fields: map[string]any{"n": i}}))
+	}
+}
+
+func TestSinkOverflow_DropOldest(t *testing.T) {
+	w := newGatedWriter()
+	s, err := NewSink(w, "trace", SinkConfig{Async: true, QueueSize: 2, Overflow: OverflowConfig{Policy: OverflowDropOldest}})
+	require.NoError(t, err)
+
+	writeN(t, s, 10)
+	close(w.gate)
+	require.NoError(t, s.Close(context.Background()))
+
+	// The newest entries are kept.
+	out := w.String()
+	assert.Contains(t, out, `"n":9`)
+	assert.NotContains(t, out, `"n":5}`)
+	assert.Positive(t, s.DroppedCount())
+	assert.Contains(t, out, `"policy":"drop_oldest"`)
+}
+
+func TestSinkOverflow_Block(t *testing.T) {
+	w := newGatedWriter()
+	s, err := NewSink(w, "trace", SinkConfig{Async: true, QueueSize: 1, Overflow: OverflowConfig{Policy: OverflowBlock, BlockTimeout: time.Second}})
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(w.gate)
+	}()
+	writeN(t, s, 20)
+	require.NoError(t, s.Close(context.Background()))
+
+	assert.Zero(t, s.DroppedCount())
+	assert.Equal(t, 20, strings.Count(w.String(), `"level":"ACCESS"`))
+}
+
+func TestSinkOverflow_Spill(t *testing.T) {
+	spillPath := filepath.Join(t.TempDir(), "spill", "access.spill")
+
+	w := newGatedWriter()
+	s, err := NewSink(w, "trace", SinkConfig{
+		Async:     true,
+		QueueSize: 2,
+		Overflow:  OverflowConfig{Policy: OverflowSpill, SpillPath: spillPath, ReportInterval: 10 * time.Millisecond},
+	})
+	require.NoError(t, err)
+
+	writeN(t, s, 50)
+	assert.Positive(t, s.SpilledCount())
+	assert.Zero(t, s.DroppedCount())
+
+	// The writer catches up and replays what was spilled.
+	close(w.gate)
+	require.Eventually(t, func() bool {
+		return strings.Count(w.String(), `"level":"ACCESS"`) == 50
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// The periodic report doesn't wait for Close.
+	require.Eventually(t, func() bool {
+		return strings.Contains(w.String(), `"message":"log entries spilled to disk"`)
+	}, 2*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, s.Close(context.Background()))
+	for i := 0; i < 50; i++ {
+		assert.Contains(t, w.String(), fmt.Sprintf(`"n":%d}`, i))
+	}
+
+	info, err := os.Stat(spillPath)
+	require.NoError(t, err)
+	assert.Zero(t, info.Size())
+}
+
+func TestSinkOverflow_SpillReplayedOnStart(t *testing.T) {
+	spillPath := filepath.Join(t.TempDir(), "access.spill")
+
+	spill, err := openSpillFile(spillPath, 1<<20)
+	require.NoError(t, err)
+	require.NoError(t, spill.append([]byte("{\"left\":\"over\"}\n")))
+	require.NoError(t, spill.close())
+
+	var buf bytes.Buffer
+	s, err := NewSink(NopWriteCloser{Writer: &buf}, "trace", SinkConfig{
+		Async:    true,
+		Overflow: OverflowConfig{Policy: OverflowSpill, SpillPath: spillPath},
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.Close(context.Background()))
+
+	assert.Equal(t, "{\"left\":\"over\"}\n", buf.String())
+}
+
+func TestOverflowConfig_withDefaults(t *testing.T) {
+	_, err := OverflowConfig{Policy: OverflowSpill}.withDefaults()
+	assert.Error(t, err)
+
+	_, err = OverflowConfig{Policy: "unknown"}.withDefaults()
+	assert.Error(t, err)
+
+	o, err := OverflowConfig{Policy: "BLOCK"}.withDefaults()
+	require.NoError(t, err)
+	assert.Equal(t, OverflowBlock, o.Policy)
+	assert.Equal(t, 100*time.Millisecond, o.BlockTimeout)
+	assert.Equal(t, time.Minute, o.ReportInterval)
+}
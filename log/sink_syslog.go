@@ -0,0 +1,101 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// SyslogConfig configures the RFC 5424 header of the syslog messages.
+type SyslogConfig struct {
+	// Facility of the messages, 1 (user-level) by default. Use 16 to 23 for local0 to local7.
+	Facility int
+	// AppName defaults to the name of the executable.
+	AppName string
+	// Hostname defaults to the name of the host.
+	Hostname string
+}
+
+const syslogTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// NewSyslogSink sends every entry as an RFC 5424 syslog message, over UDP, TCP or TLS (RFC 5425),
+// with the JSON payload of `NewSink` (or the line of `Encoder`) as the message and the level of the entry as MSGID.
+func NewSyslogSink(payloadTrace string, cfg NetSinkConfig, sys SyslogConfig) (*sink, error) {
+	w, err := newNetWriter(cfg, octetCounting)
+	if err != nil {
+		return nil, err
+	}
+
+	if sys.Facility <= 0 || sys.Facility > 23 {
+		sys.Facility = 1
+	}
+	if sys.AppName == "" && len(os.Args) > 0 {
+		sys.AppName = os.Args[0][strings.LastIndexAny(os.Args[0], `/\`)+1:]
+	}
+	if sys.Hostname == "" {
+		sys.Hostname, _ = os.Hostname()
+	}
+
+	message := cfg.Encoder
+	if message == nil {
+		message = func(buf *bytes.Buffer, e Entry, _ map[string]any) error {
+			buf.Write(appendEntryJSON(buf.AvailableBuffer(), e, payloadTrace))
+			return nil
+		}
+	}
+
+	header := " " + syslogHeaderValue(sys.Hostname, 255) +
+		" " + syslogHeaderValue(sys.AppName, 48) +
+		" " + strconv.Itoa(os.Getpid()) + " "
+	encode := func(buf *bytes.Buffer, e Entry, payload map[string]any) error {
+		pri := sys.Facility*8 + syslogSeverity(e.Severity)
+		buf.WriteByte('<')
+		buf.WriteString(strconv.Itoa(pri))
+		buf.WriteString(">1 ")
+		buf.WriteString(e.Timestamp.Format(syslogTimeFormat))
+		buf.WriteString(header)
+		buf.WriteString(syslogHeaderValue(e.Level, 32))
+		// No structured data, the fields are in the message.
+		buf.WriteString(" - ")
+
+		if err := message(buf, e, payload); err != nil {
+			return err
+		}
+		buf.Truncate(buf.Len() - 1) // The newline of the encoder.
+		return nil
+	}
+
+	return newSink(w, payloadTrace, cfg.sinkConfig(), encode)
+}
+
+func syslogSeverity(s Severity) int {
+	switch s {
+	case Debug:
+		return 7
+	case Info:
+		return 6
+	case Warning:
+		return 4
+	case Error:
+		return 3
+	case Critical:
+		return 2
+	default:
+		return 5 // Notice.
+	}
+}
+
+// syslogHeaderValue returns a header field of printable ASCII without spaces, or the nil value `-`.
+func syslogHeaderValue(s string, maxLen int) string {
+	b := make([]byte, 0, min(len(s), maxLen))
+	for i := 0; i < len(s) && len(b) < maxLen; i++ {
+		if c := s[i]; c > ' ' && c < 127 {
+			b = append(b, c)
+		}
+	}
+	if len(b) == 0 {
+		return "-"
+	}
+	return string(b)
+}
@@ -0,0 +1,221 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+)
+
+// SlogHandler is an `slog.Handler` writing through the pipeline of the logger, like `AppLogger`:
+//
+//	slog.SetDefault(slog.New(log.NewSlogHandler(log.Logger())))
+//
+// The attributes of the groups are written as nested objects.
+type SlogHandler struct {
+	l     *logger
+	level string
+	// attrs are the attributes of `WithAttrs` outside any group.
+	attrs []Field
+	// fields hold the attributes of `WithAttrs` in groups.
+	fields map[string]any
+	groups []string
+}
+
+// NewSlogHandler returns a handler of the logger of `NewLogger` or `Init`, which drops the records of any other.
+func NewSlogHandler(l BeanLogger) *SlogHandler {
+	bl, _ := l.(*logger)
+	return &SlogHandler{l: bl, level: AppLevel}
+}
+
+// WithLevel returns a handler writing entries of another level than `APP`.
+func (h *SlogHandler) WithLevel(level string) *SlogHandler {
+	c := *h
+	c.level = level
+	return &c
+}
+
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.l != nil && h.l.appEnabled(ctx, h.level, slogSeverity(level))
+}
+
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.l == nil || !allowError(slogSeverity(r.Level), recordError(r)) {
+		return nil
+	}
+
+	attrs := make([]Field, 0, 3+len(h.attrs)+r.NumAttrs())
+	attrs = append(attrs, String("message", r.Message))
+	attrs = appendContextFields(attrs, ctx)
+	for _, f := range h.attrs {
+		if f.kind == KindAny {
+			f = Any(f.Key, cloneValue(f.any))
+		}
+		attrs = append(attrs, f)
+	}
+
+	fields := cloneFields(h.fields)
+	if len(h.groups) == 0 {
+		r.Attrs(func(a slog.Attr) bool {
+			attrs = appendSlogAttr(attrs, a)
+			return true
+		})
+	} else if r.NumAttrs() > 0 {
+		if fields == nil {
+			fields = map[string]any{}
+		}
+		group := groupMap(fields, h.groups)
+		r.Attrs(func(a slog.Attr) bool {
+			putSlogAttr(group, a)
+			return true
+		})
+	}
+
+	var pc uintptr
+	if h.l.addSource {
+		pc = r.PC
+	}
+	h.l.write(ctx, slogSeverity(r.Level), h.level, fields, attrs, pc)
+	return nil
+}
+
+func (h *SlogHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	if len(as) == 0 {
+		return h
+	}
+	c := *h
+	if len(h.groups) == 0 {
+		c.attrs = slices.Clip(h.attrs)
+		for _, a := range as {
+			c.attrs = appendSlogAttr(c.attrs, a)
+		}
+		return &c
+	}
+
+	c.fields = cloneFields(h.fields)
+	if c.fields == nil {
+		c.fields = map[string]any{}
+	}
+	group := groupMap(c.fields, h.groups)
+	for _, a := range as {
+		putSlogAttr(group, a)
+	}
+	return &c
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.groups = append(slices.Clip(h.groups), name)
+	return &c
+}
+
+// slogSeverity maps the slog levels to the severities, the levels above ERROR being CRITICAL.
+func slogSeverity(level slog.Level) Severity {
+	switch {
+	case level < slog.LevelInfo:
+		return Debug
+	case level < slog.LevelWarn:
+		return Info
+	case level < slog.LevelError:
+		return Warning
+	case level == slog.LevelError:
+		return Error
+	default:
+		return Critical
+	}
+}
+
+// appendSlogAttr appends an attribute as a typed field, a group as a nested object.
+func appendSlogAttr(attrs []Field, a slog.Attr) []Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return attrs
+	}
+
+	v := a.Value
+	switch v.Kind() {
+	case slog.KindString:
+		return append(attrs, String(a.Key, v.String()))
+	case slog.KindInt64:
+		return append(attrs, Int64(a.Key, v.Int64()))
+	case slog.KindUint64:
+		return append(attrs, Uint64(a.Key, v.Uint64()))
+	case slog.KindFloat64:
+		return append(attrs, Float64(a.Key, v.Float64()))
+	case slog.KindBool:
+		return append(attrs, Bool(a.Key, v.Bool()))
+	case slog.KindDuration:
+		return append(attrs, Duration(a.Key, v.Duration()))
+	case slog.KindTime:
+		return append(attrs, Time(a.Key, v.Time()))
+	case slog.KindGroup:
+		group := v.Group()
+		if len(group) == 0 {
+			return attrs
+		}
+		// The attributes of a group without a key are inlined.
+		if a.Key == "" {
+			for _, ga := range group {
+				attrs = appendSlogAttr(attrs, ga)
+			}
+			return attrs
+		}
+		m := make(map[string]any, len(group))
+		for _, ga := range group {
+			putSlogAttr(m, ga)
+		}
+		return append(attrs, Any(a.Key, m))
+	default:
+		return append(attrs, Any(a.Key, v.Any()))
+	}
+}
+
+// putSlogAttr puts an attribute in the map of a group.
+func putSlogAttr(m map[string]any, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		group := a.Value.Group()
+		if len(group) == 0 {
+			return
+		}
+		if a.Key != "" {
+			m = groupMap(m, []string{a.Key})
+		}
+		for _, ga := range group {
+			putSlogAttr(m, ga)
+		}
+		return
+	}
+	m[a.Key] = appendSlogAttr(nil, a)[0].Value()
+}
+
+// groupMap returns the map of the nested groups, created if needed.
+func groupMap(m map[string]any, groups []string) map[string]any {
+	for _, g := range groups {
+		child, ok := m[g].(map[string]any)
+		if !ok {
+			child = map[string]any{}
+			m[g] = child
+		}
+		m = child
+	}
+	return m
+}
+
+// recordError returns the first error of the attributes of the record.
+func recordError(r slog.Record) error {
+	var err error
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Value.Kind() == slog.KindAny {
+			err, _ = a.Value.Any().(error)
+		}
+		return err == nil
+	})
+	return err
+}
@@ -0,0 +1,107 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	bctx "github.com/retail-ai-inc/bean/v2/context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestSlogHandler(t *testing.T) {
+	l, buf := newBufferLogger(t, WithMaskFields([]string{"token"}))
+
+	logger := slog.New(NewSlogHandler(l)).With("service", "orders")
+	ctx := bctx.SetRequestID(context.Background(), "req-1")
+	logger.InfoContext(ctx, "created",
+		"id", 7,
+		"elapsed", 2*time.Millisecond,
+		slog.Group("auth", "user", "john", "token", "t"),
+	)
+
+	docs := decodeLines(t, buf)
+	require.Len(t, docs, 1)
+	doc := docs[0]
+	assert.Equal(t, "INFO", doc["severity"])
+	assert.Equal(t, AppLevel, doc["level"])
+	assert.Equal(t, "created", doc["message"])
+	assert.Equal(t, "req-1", doc["request_id"])
+	assert.Equal(t, "orders", doc["service"])
+	assert.Equal(t, float64(7), doc["id"])
+	assert.Equal(t, float64(2*time.Millisecond), doc["elapsed"])
+	assert.Equal(t, map[string]any{"user": "john", "token": "****"}, doc["auth"])
+}
+
+func TestSlogHandler_Span(t *testing.T) {
+	l, buf := newBufferLogger(t)
+
+	// A span the application started, with the default Sentry extractor.
+	ctx := oteltrace.ContextWithSpanContext(context.Background(), testSpanContext)
+	slog.New(NewSlogHandler(l)).InfoContext(ctx, "created")
+
+	docs := decodeLines(t, buf)
+	require.Len(t, docs, 1)
+	assert.Equal(t, testSpanContext.TraceID().String(), docs[0]["trace"])
+	assert.Equal(t, testSpanContext.SpanID().String(), docs[0]["span_id"])
+	assert.Equal(t, true, docs[0]["trace_sampled"])
+}
+
+func TestSlogHandler_ErrorDedup(t *testing.T) {
+	allowOnce(t)
+	l, buf := newBufferLogger(t)
+
+	logger := slog.New(NewSlogHandler(l))
+	err := errors.New("db down")
+	logger.Error("query failed", "err", err)
+	logger.Error("query failed", "err", err)
+	logger.Error("query failed")
+
+	docs := decodeLines(t, buf)
+	require.Len(t, docs, 2)
+	assert.Equal(t, "db down", docs[0]["err"])
+	assert.NotContains(t, docs[1], "err")
+}
+
+func TestSlogHandler_Groups(t *testing.T) {
+	l, buf := newBufferLogger(t)
+
+	logger := slog.New(NewSlogHandler(l).WithLevel("JOB")).
+		WithGroup("job").With("name", "sync").
+		WithGroup("result")
+	logger.Warn("slow", "count", 3, slog.Group("", "inlined", true))
+
+	docs := decodeLines(t, buf)
+	require.Len(t, docs, 1)
+	assert.Equal(t, "WARNING", docs[0]["severity"])
+	assert.Equal(t, "JOB", docs[0]["level"])
+	assert.Equal(t, map[string]any{
+		"name":   "sync",
+		"result": map[string]any{"count": float64(3), "inlined": true},
+	}, docs[0]["job"])
+}
+
+func TestSlogHandler_Enabled(t *testing.T) {
+	l, buf := newBufferLogger(t, WithAppMinSeverity(Info))
+	h := NewSlogHandler(l)
+
+	assert.False(t, h.Enabled(context.Background(), slog.LevelDebug))
+	assert.True(t, h.Enabled(context.Background(), slog.LevelInfo))
+
+	slog.New(h).Debug("dropped")
+	assert.Empty(t, buf.String())
+
+	assert.False(t, NewSlogHandler(nil).Enabled(context.Background(), slog.LevelError))
+}
+
+func TestSlogSeverity(t *testing.T) {
+	assert.Equal(t, Debug, slogSeverity(slog.LevelDebug))
+	assert.Equal(t, Info, slogSeverity(slog.LevelInfo))
+	assert.Equal(t, Warning, slogSeverity(slog.LevelWarn))
+	assert.Equal(t, Error, slogSeverity(slog.LevelError))
+	assert.Equal(t, Critical, slogSeverity(slog.LevelError+4))
+}
@@ -0,0 +1,151 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// LevelTailBuffer is the level of the entry counting the debug entries a full `TailBuffer` dropped.
+const LevelTailBuffer = "TAIL_BUFFER"
+
+// DefaultTailBufferSize is the number of debug entries a `TailBuffer` keeps when none is configured.
+const DefaultTailBufferSize = 1000
+
+type tailState int
+
+const (
+	tailBuffering tailState = iota
+	tailFlushed
+	tailDiscarded
+)
+
+// TailBuffer keeps the debug entries of a request until it is done, to write them only if it failed.
+// The entries logged through a context carrying it with `WithTailBuffer` are kept, whatever
+// `WithAppMinSeverity` is. Once flushed, the later debug entries, e.g. of the async tasks still
+// running, are written right away; once discarded, they are filtered as if there was no buffer.
+type TailBuffer struct {
+	mu      sync.Mutex
+	l       *logger
+	entries []Entry
+	max     int
+	dropped int
+	state   tailState
+	marked  atomic.Bool
+}
+
+// NewTailBuffer returns a buffer keeping up to max entries, `DefaultTailBufferSize` if max <= 0.
+// The entries logged after are dropped and counted.
+func NewTailBuffer(max int) *TailBuffer {
+	if max <= 0 {
+		max = DefaultTailBufferSize
+	}
+	return &TailBuffer{max: max}
+}
+
+type tailBufferKey struct{}
+
+// WithTailBuffer returns a context carrying the buffer, the context as is if the buffer is nil.
+// The tail buffer middleware sets it for every request; `async.ExecuteContext` passes it on to the tasks.
+func WithTailBuffer(ctx context.Context, b *TailBuffer) context.Context {
+	if b == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, tailBufferKey{}, b)
+}
+
+// TailBufferFromContext returns the buffer set by `WithTailBuffer`, or nil if none.
+func TailBufferFromContext(ctx context.Context) *TailBuffer {
+	if ctx == nil {
+		return nil
+	}
+	b, _ := ctx.Value(tailBufferKey{}).(*TailBuffer)
+	return b
+}
+
+// MarkForFlush marks the buffer of the context, if any, so its entries are written even if the
+// request succeeds. E.g. for a request worth a closer look which doesn't fail.
+func MarkForFlush(ctx context.Context) {
+	if b := TailBufferFromContext(ctx); b != nil {
+		b.Mark()
+	}
+}
+
+// Mark marks the buffer to be flushed.
+func (b *TailBuffer) Mark() {
+	b.marked.Store(true)
+}
+
+// Marked reports whether `Mark` was called.
+func (b *TailBuffer) Marked() bool {
+	return b.marked.Load()
+}
+
+// Flush writes the entries kept, with an entry counting the ones dropped if it was full.
+func (b *TailBuffer) Flush() {
+	b.mu.Lock()
+	if b.state != tailBuffering {
+		b.mu.Unlock()
+		return
+	}
+	b.state = tailFlushed
+	entries, dropped, l := b.entries, b.dropped, b.l
+	b.entries = nil
+	b.mu.Unlock()
+
+	for _, entry := range entries {
+		_ = l.pipeline.Process(entry)
+	}
+	if dropped > 0 {
+		l.writeEntry(context.Background(), Warning, LevelTailBuffer, map[string]any{
+			"message":     "debug entries dropped, the tail buffer was full",
+			"dropped":     dropped,
+			"max_entries": b.max,
+		}, nil, 0)
+	}
+}
+
+// Discard drops the entries kept.
+func (b *TailBuffer) Discard() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == tailBuffering {
+		b.state = tailDiscarded
+		b.entries = nil
+	}
+}
+
+// Len returns the number of entries kept.
+func (b *TailBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.entries)
+}
+
+// capturing reports whether the debug entries are kept or written, so they aren't dropped by `WithAppMinSeverity`.
+func (b *TailBuffer) capturing() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state != tailDiscarded
+}
+
+// add keeps the entry of the logger, it reports false if the entry is to be written now.
+func (b *TailBuffer) add(l *logger, entry Entry) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != tailBuffering {
+		return false
+	}
+
+	b.l = l
+	if len(b.entries) >= b.max {
+		b.dropped++
+		return true
+	}
+	b.entries = append(b.entries, entry)
+	return true
+}
@@ -0,0 +1,87 @@
+package log
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTailBuffer_Flush(t *testing.T) {
+	l, buf := newBufferLogger(t, WithAppMinSeverity(Info))
+	tb := NewTailBuffer(0)
+	ctx := WithTailBuffer(context.Background(), tb)
+
+	app := l.FromContext(ctx)
+	assert.True(t, app.Enabled(Debug), "kept whatever the min severity")
+	assert.False(t, l.FromContext(context.Background()).Enabled(Debug))
+
+	app.Debug("loaded cart", "items", 3)
+	l.Log(ctx, Debug, "DB", String("query", "SELECT 1"))
+	app.Info("checkout")
+	assert.Equal(t, 2, tb.Len())
+
+	docs := decodeLines(t, buf)
+	require.Len(t, docs, 1, "only the info entry is written")
+	assert.Equal(t, "checkout", docs[0]["message"])
+
+	tb.Flush()
+	docs = decodeLines(t, buf)
+	require.Len(t, docs, 3)
+	assert.Equal(t, "loaded cart", docs[1]["message"])
+	assert.Equal(t, "DEBUG", docs[1]["severity"])
+	assert.Equal(t, "SELECT 1", docs[2]["query"])
+	assert.Zero(t, tb.Len())
+
+	// Written right away once flushed, e.g. by the async tasks of the request.
+	app.Debug("async done")
+	docs = decodeLines(t, buf)
+	require.Len(t, docs, 4)
+	assert.Equal(t, "async done", docs[3]["message"])
+}
+
+func TestTailBuffer_Discard(t *testing.T) {
+	l, buf := newBufferLogger(t, WithAppMinSeverity(Info))
+	tb := NewTailBuffer(0)
+	ctx := WithTailBuffer(context.Background(), tb)
+
+	l.FromContext(ctx).Debug("loaded cart")
+	tb.Discard()
+	tb.Flush()
+	assert.Empty(t, buf.String())
+
+	// Filtered as without a buffer once discarded.
+	assert.False(t, l.FromContext(ctx).Enabled(Debug))
+	l.Log(ctx, Debug, "DB", String("query", "SELECT 1"))
+	assert.Len(t, decodeLines(t, buf), 1)
+}
+
+func TestTailBuffer_Full(t *testing.T) {
+	l, buf := newBufferLogger(t)
+	tb := NewTailBuffer(2)
+	ctx := WithTailBuffer(context.Background(), tb)
+
+	for range 5 {
+		l.FromContext(ctx).Debug("step")
+	}
+	assert.Equal(t, 2, tb.Len())
+
+	tb.Flush()
+	docs := decodeLines(t, buf)
+	require.Len(t, docs, 3)
+	assert.Equal(t, LevelTailBuffer, docs[2]["level"])
+	assert.Equal(t, float64(3), docs[2]["dropped"])
+	assert.Equal(t, float64(2), docs[2]["max_entries"])
+}
+
+func TestMarkForFlush(t *testing.T) {
+	MarkForFlush(context.Background())
+
+	tb := NewTailBuffer(0)
+	assert.False(t, tb.Marked())
+	MarkForFlush(WithTailBuffer(context.Background(), tb))
+	assert.True(t, tb.Marked())
+
+	assert.Nil(t, TailBufferFromContext(WithTailBuffer(context.Background(), nil)))
+}
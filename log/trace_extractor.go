@@ -4,6 +4,8 @@ import (
 	"context"
 
 	"github.com/getsentry/sentry-go"
+	bctx "github.com/retail-ai-inc/bean/v2/context"
+	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
 type TraceExtractor interface {
@@ -16,13 +18,64 @@ func NewSentryExtractor() *sentryExtractor {
 }
 
 func (e *sentryExtractor) Extract(ctx context.Context) Trace {
+	if t, ok := sentryTrace(ctx); ok {
+		return t
+	}
+	if t, ok := otelTrace(ctx); ok {
+		return t
+	}
+	return incomingTrace(ctx)
+}
+
+type otelExtractor struct{}
+
+// NewOtelExtractor returns an extractor which fills `TraceID` and `SpanID` from the OpenTelemetry span context.
+func NewOtelExtractor() *otelExtractor {
+	return &otelExtractor{}
+}
+
+func (e *otelExtractor) Extract(ctx context.Context) Trace {
+	if t, ok := otelTrace(ctx); ok {
+		return t
+	}
+	if t, ok := sentryTrace(ctx); ok {
+		return t
+	}
+	return incomingTrace(ctx)
+}
+
+// sentryTrace and otelTrace return the trace of the span of the context. Each extractor falls back to
+// the other tracer, so the entries logged inside a span the application started with it, e.g. by
+// `AppLogger` or `SlogHandler`, still get its span id.
+func sentryTrace(ctx context.Context) (Trace, bool) {
 	span := sentry.SpanFromContext(ctx)
 	if span == nil {
-		return Trace{}
+		return Trace{}, false
 	}
-
 	return Trace{
 		TraceID: span.TraceID.String(),
 		SpanID:  span.SpanID.String(),
+		Sampled: span.Sampled.Bool(),
+	}, true
+}
+
+func otelTrace(ctx context.Context) (Trace, bool) {
+	sc := oteltrace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return Trace{}, false
+	}
+	return Trace{
+		TraceID: sc.TraceID().String(),
+		SpanID:  sc.SpanID().String(),
+		Sampled: sc.IsSampled(),
+	}, true
+}
+
+// incomingTrace falls back to the trace id sent by the caller, so entries are still correlated
+// with the caller's trace when nothing is traced locally.
+func incomingTrace(ctx context.Context) Trace {
+	if traceID, ok := bctx.GetTraceID(ctx); ok {
+		return Trace{TraceID: traceID}
 	}
+	return Trace{}
 }
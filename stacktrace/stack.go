@@ -73,6 +73,11 @@ func (f Frame) name() string {
 	return fn.Name()
 }
 
+// Source returns the function name, the path of the source file and the line of the frame.
+func (f Frame) Source() (function, file string, line int) {
+	return f.name(), f.file(), f.line()
+}
+
 // Format formats the frame according to the fmt.Formatter interface.
 //
 //	%s    source file
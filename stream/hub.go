@@ -0,0 +1,223 @@
+package stream
+
+import (
+	"strconv"
+	"sync"
+)
+
+// HubConfig configures a `Hub`.
+type HubConfig struct {
+	// BacklogSize is the number of latest events kept, so reconnecting SSE clients get the events
+	// they missed since their `Last-Event-ID`. No event is kept if it's 0.
+	BacklogSize int
+}
+
+// Hub keeps track of open streams and broadcasts events to all of them or to the members of a room.
+// Streams leave the hub by themselves once closed, and slow consumers are closed instead of blocking a broadcast.
+type Hub struct {
+	mu      sync.RWMutex
+	clients map[Client]map[string]struct{}
+	rooms   map[string]map[Client]struct{}
+	backlog *Backlog
+}
+
+// AllRoom is the room every client joins; `Broadcast` sends to it.
+const AllRoom = ""
+
+func NewHub(cfg HubConfig) *Hub {
+	h := &Hub{
+		clients: map[Client]map[string]struct{}{},
+		rooms:   map[string]map[Client]struct{}{},
+	}
+	if cfg.BacklogSize > 0 {
+		h.backlog = NewBacklog(cfg.BacklogSize)
+	}
+	return h
+}
+
+// Join adds the client to the hub and to the given rooms. An SSE client reconnecting with a
+// `Last-Event-ID` first gets the events of these rooms it missed, as far as the backlog goes back.
+func (h *Hub) Join(c Client, rooms ...string) {
+	rooms = append([]string{AllRoom}, rooms...)
+
+	h.mu.Lock()
+	joined, ok := h.clients[c]
+	if !ok {
+		joined = map[string]struct{}{}
+		h.clients[c] = joined
+	}
+	newRooms := map[string]struct{}{}
+	for _, room := range rooms {
+		if _, ok := joined[room]; ok {
+			continue
+		}
+		joined[room] = struct{}{}
+		newRooms[room] = struct{}{}
+		if h.rooms[room] == nil {
+			h.rooms[room] = map[Client]struct{}{}
+		}
+		h.rooms[room][c] = struct{}{}
+	}
+
+	var missed []Event
+	if sse, ok := c.(*SSE); ok && sse.LastEventID() != "" && h.backlog != nil {
+		missed, _ = h.backlog.since(sse.LastEventID(), newRooms)
+	}
+	h.mu.Unlock()
+
+	for _, ev := range missed {
+		if err := c.Send(ev); err != nil {
+			break
+		}
+	}
+
+	if !ok {
+		go func() {
+			<-c.Context().Done()
+			h.Leave(c)
+		}()
+	}
+}
+
+// Leave removes the client from the given rooms, or from the hub if no room is given.
+func (h *Hub) Leave(c Client, rooms ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	joined, ok := h.clients[c]
+	if !ok {
+		return
+	}
+
+	if len(rooms) == 0 {
+		for room := range joined {
+			h.leaveRoom(c, room)
+		}
+		delete(h.clients, c)
+		return
+	}
+
+	for _, room := range rooms {
+		if room == AllRoom {
+			continue
+		}
+		h.leaveRoom(c, room)
+		delete(joined, room)
+	}
+}
+
+func (h *Hub) leaveRoom(c Client, room string) {
+	delete(h.rooms[room], c)
+	if len(h.rooms[room]) == 0 {
+		delete(h.rooms, room)
+	}
+}
+
+// Broadcast sends the event to every client and returns the number of clients it was queued for.
+func (h *Hub) Broadcast(ev Event) int {
+	return h.BroadcastTo(AllRoom, ev)
+}
+
+// BroadcastTo sends the event to the members of the room and returns the number of clients it was queued for.
+// When the hub keeps a backlog, an event without an id gets the next sequence number of the hub.
+func (h *Hub) BroadcastTo(room string, ev Event) int {
+	h.mu.Lock()
+	if h.backlog != nil {
+		ev = h.backlog.add(room, ev)
+	}
+	clients := make([]Client, 0, len(h.rooms[room]))
+	for c := range h.rooms[room] {
+		clients = append(clients, c)
+	}
+	h.mu.Unlock()
+
+	sent := 0
+	for _, c := range clients {
+		// A failing client is closed by `Send` and leaves the hub by itself.
+		if err := c.Send(ev); err == nil {
+			sent++
+		}
+	}
+
+	return sent
+}
+
+// Len returns the number of clients in the room.
+func (h *Hub) Len(room string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.rooms[room])
+}
+
+// Backlog is a fixed size buffer of the latest events, used to resume event streams.
+type Backlog struct {
+	mu      sync.Mutex
+	size    int
+	seq     uint64
+	entries []backlogEntry
+}
+
+type backlogEntry struct {
+	room string
+	ev   Event
+}
+
+func NewBacklog(size int) *Backlog {
+	return &Backlog{size: size, entries: make([]backlogEntry, 0, size)}
+}
+
+// Add keeps the event, dropping the oldest one when the backlog is full.
+// An event without an id gets the next sequence number.
+func (b *Backlog) Add(ev Event) Event {
+	return b.add(AllRoom, ev)
+}
+
+// Since returns the events added after the one with the given id. It returns false, and
+// every kept event, if the id is not in the backlog anymore.
+func (b *Backlog) Since(id string) ([]Event, bool) {
+	return b.since(id, nil)
+}
+
+func (b *Backlog) add(room string, ev Event) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	if ev.ID == "" {
+		ev.ID = strconv.FormatUint(b.seq, 10)
+	}
+
+	if len(b.entries) == b.size {
+		copy(b.entries, b.entries[1:])
+		b.entries = b.entries[:len(b.entries)-1]
+	}
+	b.entries = append(b.entries, backlogEntry{room: room, ev: ev})
+
+	return ev
+}
+
+// since returns the events after `id` sent to one of the rooms, or to any room if `rooms` is nil.
+func (b *Backlog) since(id string, rooms map[string]struct{}) ([]Event, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	start, found := 0, false
+	for i := len(b.entries) - 1; i >= 0; i-- {
+		if b.entries[i].ev.ID == id {
+			start, found = i+1, true
+			break
+		}
+	}
+
+	var events []Event
+	for _, e := range b.entries[start:] {
+		if rooms != nil {
+			if _, ok := rooms[e.room]; !ok {
+				continue
+			}
+		}
+		events = append(events, e.ev)
+	}
+
+	return events, found
+}
@@ -0,0 +1,206 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// LastEventIDHeader is sent by browsers reconnecting to an event stream.
+const LastEventIDHeader = "Last-Event-ID"
+
+// SSEConfig configures a Server-Sent Events stream.
+type SSEConfig struct {
+	// Heartbeat is the interval of the comment lines keeping the connection open through proxies. Default 15s.
+	Heartbeat time.Duration
+	// WriteTimeout bounds every write to the client. Default 10s.
+	WriteTimeout time.Duration
+	// QueueSize is the number of events queued before the client is treated as a slow consumer. Default 64.
+	QueueSize int
+	// Retry is sent once at the start, telling the browser how long to wait before reconnecting.
+	Retry time.Duration
+}
+
+// SSE is a Server-Sent Events stream. Events sent with `Send` are written by `Serve`,
+// which must be called by the handler and returns when the stream is closed.
+type SSE struct {
+	*conn
+	c           echo.Context
+	rc          *http.ResponseController
+	cfg         SSEConfig
+	lastEventID string
+}
+
+// NewSSE starts an event stream on the response. The last event id the client received
+// (the `Last-Event-ID` header, or the `lastEventId` query parameter) is available with `LastEventID`.
+func NewSSE(c echo.Context, cfg SSEConfig) (*SSE, error) {
+	if cfg.Heartbeat <= 0 {
+		cfg.Heartbeat = 15 * time.Second
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = 10 * time.Second
+	}
+
+	req := c.Request()
+	stats := StatsFromContext(req.Context())
+	stats.Kind = KindSSE
+
+	cn, err := newConn(req.Context(), cfg.QueueSize, stats, nil)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	lastEventID := req.Header.Get(LastEventIDHeader)
+	if lastEventID == "" {
+		lastEventID = c.QueryParam("lastEventId")
+	}
+
+	s := &SSE{
+		conn:        cn,
+		c:           c,
+		rc:          http.NewResponseController(c.Response().Writer),
+		cfg:         cfg,
+		lastEventID: lastEventID,
+	}
+
+	h := c.Response().Header()
+	h.Set(echo.HeaderContentType, "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no") // Disable the response buffering of nginx.
+	c.Response().WriteHeader(http.StatusOK)
+
+	if cfg.Retry > 0 {
+		if err := s.write([]byte("retry: " + strconv.FormatInt(cfg.Retry.Milliseconds(), 10) + "\n\n")); err != nil {
+			s.finish(CloseReasonWriteError)
+			return nil, err
+		}
+	} else if err := s.flush(); err != nil {
+		s.finish(CloseReasonWriteError)
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// LastEventID returns the id of the last event the client received before reconnecting, if any.
+func (s *SSE) LastEventID() string {
+	return s.lastEventID
+}
+
+// Send queues an event. It returns `ErrSlowConsumer` and closes the stream when the queue is full.
+func (s *SSE) Send(ev Event) error {
+	return s.send(ev)
+}
+
+// Context is done once the stream is closed by the client, the server or `Shutdown`.
+func (s *SSE) Context() context.Context {
+	return s.ctx
+}
+
+// Close closes the stream. Queued events which were not written yet are dropped.
+func (s *SSE) Close() error {
+	s.close(CloseReasonServer)
+	return nil
+}
+
+// Serve writes the queued events and the heartbeats until the stream is closed.
+// Return its result from the handler.
+func (s *SSE) Serve() error {
+	defer func() {
+		// Keep-alive connections are reused, don't leave the deadline behind.
+		_ = s.rc.SetWriteDeadline(time.Time{})
+		// The request context is done when the client goes away.
+		s.finish(CloseReasonClient)
+	}()
+
+	t := time.NewTicker(s.cfg.Heartbeat)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return nil
+		case ev := <-s.queue:
+			b, err := encodeSSE(ev)
+			if err != nil {
+				return err
+			}
+			if err := s.write(b); err != nil {
+				s.close(CloseReasonWriteError)
+				return nil
+			}
+			s.stats.MessagesOut.Add(1)
+		case <-t.C:
+			if err := s.write([]byte(": heartbeat\n\n")); err != nil {
+				s.close(CloseReasonWriteError)
+				return nil
+			}
+		}
+	}
+}
+
+func (s *SSE) write(b []byte) error {
+	_ = s.rc.SetWriteDeadline(time.Now().Add(s.cfg.WriteTimeout))
+	n, err := s.c.Response().Write(b)
+	s.stats.BytesOut.Add(int64(n))
+	if err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+func (s *SSE) flush() error {
+	if err := s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+func encodeSSE(ev Event) ([]byte, error) {
+	data, err := encodeData(ev.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if ev.ID != "" {
+		buf.WriteString("id: " + oneLine(ev.ID) + "\n")
+	}
+	if ev.Event != "" {
+		buf.WriteString("event: " + oneLine(ev.Event) + "\n")
+	}
+	if ev.Retry > 0 {
+		buf.WriteString("retry: " + strconv.FormatInt(ev.Retry.Milliseconds(), 10) + "\n")
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		buf.WriteString("data: " + strings.TrimSuffix(line, "\r") + "\n")
+	}
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
+
+func encodeData(data any) ([]byte, error) {
+	switch v := data.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+func oneLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
@@ -0,0 +1,254 @@
+// Package stream provides Server-Sent Events and WebSocket helpers which cooperate with bean's
+// default middleware stack: the routes marked with `Route` are exempt from `ContextTimeout` and the
+// body dump of the access log, get one summary log entry when they close, and are closed by `ShutdownAll`.
+package stream
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	KindSSE       = "sse"
+	KindWebSocket = "websocket"
+)
+
+// Reasons a stream was closed, reported in the summary log entry.
+const (
+	CloseReasonClient       = "client_closed"
+	CloseReasonServer       = "server_closed"
+	CloseReasonShutdown     = "server_shutdown"
+	CloseReasonSlowConsumer = "slow_consumer"
+	CloseReasonWriteError   = "write_error"
+)
+
+var (
+	// ErrClosed is returned when sending to a closed stream.
+	ErrClosed = errors.New("stream: closed")
+	// ErrSlowConsumer is returned when the send queue of a stream is full. The stream is closed.
+	ErrSlowConsumer = errors.New("stream: send queue is full")
+	// ErrShuttingDown is returned when a stream is opened while the server is shutting down.
+	ErrShuttingDown = errors.New("stream: server is shutting down")
+)
+
+// Event is a message sent to a stream. `ID`, `Event` and `Retry` are only used by SSE.
+type Event struct {
+	ID    string
+	Event string
+	// Data is written as is when it's a `string` or `[]byte` and as JSON otherwise.
+	Data  any
+	Retry time.Duration
+}
+
+// Client is an open stream, either `*SSE` or `*WebSocket`.
+type Client interface {
+	// Send queues the event without blocking.
+	Send(ev Event) error
+	// Context is done once the stream is closed.
+	Context() context.Context
+	// Close closes the stream from the server side.
+	Close() error
+}
+
+// routes is the set of routes marked as streaming, keyed by the method and the registered path.
+// The request headers are not trusted for it: any client could send `Accept: text/event-stream`
+// to escape the timeout and the body dump of a regular route.
+var routes = struct {
+	sync.RWMutex
+	keys map[string]struct{}
+}{keys: map[string]struct{}{}}
+
+// Route marks a registered route as a long-lived stream and returns it, e.g.
+// `stream.Route(e.GET("/events", handler))`.
+func Route(r *echo.Route) *echo.Route {
+	routes.Lock()
+	defer routes.Unlock()
+	routes.keys[r.Method+" "+r.Path] = struct{}{}
+	return r
+}
+
+// IsStreamingRoute reports whether the request was routed to a route marked with `Route`.
+func IsStreamingRoute(c echo.Context) bool {
+	routes.RLock()
+	defer routes.RUnlock()
+	_, ok := routes.keys[c.Request().Method+" "+c.Path()]
+	return ok
+}
+
+// Stats is the per-stream counters written to the summary log entry.
+type Stats struct {
+	Kind        string
+	MessagesIn  atomic.Int64
+	MessagesOut atomic.Int64
+	BytesIn     atomic.Int64
+	BytesOut    atomic.Int64
+
+	mu          sync.Mutex
+	closeReason string
+}
+
+// SetCloseReason keeps the first reason the stream was closed for.
+func (s *Stats) SetCloseReason(reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closeReason == "" {
+		s.closeReason = reason
+	}
+}
+
+// CloseReason returns why the stream was closed.
+func (s *Stats) CloseReason() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeReason
+}
+
+type statsKey struct{}
+
+// WithStats returns a context carrying the stats the stream helpers update.
+// The access log middleware sets it for streaming routes.
+func WithStats(ctx context.Context, stats *Stats) context.Context {
+	return context.WithValue(ctx, statsKey{}, stats)
+}
+
+// StatsFromContext returns the stats set by `WithStats`, or a detached value if none.
+func StatsFromContext(ctx context.Context) *Stats {
+	if stats, ok := ctx.Value(statsKey{}).(*Stats); ok {
+		return stats
+	}
+	return &Stats{}
+}
+
+// registry keeps track of the open streams, so they are closed before the HTTP server shuts down:
+// `http.Server.Shutdown` neither waits for hijacked WebSocket connections nor interrupts SSE handlers.
+var registry = struct {
+	sync.Mutex
+	closing bool
+	streams map[*conn]struct{}
+	wg      sync.WaitGroup
+}{streams: map[*conn]struct{}{}}
+
+func register(c *conn) error {
+	registry.Lock()
+	defer registry.Unlock()
+	if registry.closing {
+		return ErrShuttingDown
+	}
+	registry.streams[c] = struct{}{}
+	registry.wg.Add(1)
+	return nil
+}
+
+func isClosing() bool {
+	registry.Lock()
+	defer registry.Unlock()
+	return registry.closing
+}
+
+func unregister(c *conn) {
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.streams[c]; ok {
+		delete(registry.streams, c)
+		registry.wg.Done()
+	}
+}
+
+// Shutdown closes every open stream with `CloseReasonShutdown` and waits for them to finish,
+// or for `ctx` to be done. New streams are refused afterward. `ShutdownAll` calls it.
+func Shutdown(ctx context.Context) error {
+	registry.Lock()
+	registry.closing = true
+	streams := make([]*conn, 0, len(registry.streams))
+	for c := range registry.streams {
+		streams = append(streams, c)
+	}
+	registry.Unlock()
+
+	for _, c := range streams {
+		c.close(CloseReasonShutdown)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		registry.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// conn is the transport independent part of a stream: the send queue, the close state and the stats.
+type conn struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	queue  chan Event
+	stats  *Stats
+
+	closeOnce sync.Once
+	onClose   func(reason string)
+}
+
+func newConn(parent context.Context, queueSize int, stats *Stats, onClose func(reason string)) (*conn, error) {
+	if queueSize <= 0 {
+		queueSize = 64
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	c := &conn{
+		ctx:     ctx,
+		cancel:  cancel,
+		queue:   make(chan Event, queueSize),
+		stats:   stats,
+		onClose: onClose,
+	}
+	if err := register(c); err != nil {
+		cancel()
+		return nil, err
+	}
+	// The request context is done once the handler returned, so a stream which is never served,
+	// or closed without being served, doesn't hold `Shutdown` back.
+	context.AfterFunc(parent, func() { c.finish(CloseReasonClient) })
+
+	return c, nil
+}
+
+func (c *conn) send(ev Event) error {
+	if c.ctx.Err() != nil {
+		return ErrClosed
+	}
+
+	select {
+	case c.queue <- ev:
+		return nil
+	default:
+		c.close(CloseReasonSlowConsumer)
+		return ErrSlowConsumer
+	}
+}
+
+func (c *conn) close(reason string) {
+	c.closeOnce.Do(func() {
+		c.stats.SetCloseReason(reason)
+		if c.onClose != nil {
+			c.onClose(reason)
+		}
+		c.cancel()
+	})
+}
+
+// finish marks the stream as gone once its handler is done with it.
+func (c *conn) finish(reason string) {
+	c.close(reason)
+	unregister(c)
+}
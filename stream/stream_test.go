@@ -0,0 +1,237 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func resetRegistry(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		registry.Lock()
+		registry.closing = false
+		registry.Unlock()
+	})
+}
+
+func TestEncodeSSE(t *testing.T) {
+	b, err := encodeSSE(Event{ID: "7", Event: "update", Data: "line1\nline2"})
+	require.NoError(t, err)
+	assert.Equal(t, "id: 7\nevent: update\ndata: line1\ndata: line2\n\n", string(b))
+
+	b, err = encodeSSE(Event{Data: map[string]int{"n": 1}})
+	require.NoError(t, err)
+	assert.Equal(t, "data: {\"n\":1}\n\n", string(b))
+}
+
+func TestIsStreamingRoute(t *testing.T) {
+	e := echo.New()
+	Route(e.GET("/events/:shop", func(c echo.Context) error { return nil }))
+	Route(e.Group("/v1").GET("/ws", func(c echo.Context) error { return nil }))
+	e.GET("/orders", func(c echo.Context) error { return nil })
+
+	isStreaming := func(method, target string, header http.Header) bool {
+		req := httptest.NewRequest(method, target, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		c := e.NewContext(req, httptest.NewRecorder())
+		e.Router().Find(method, req.URL.Path, c)
+		return IsStreamingRoute(c)
+	}
+
+	assert.True(t, isStreaming(http.MethodGet, "/events/1", nil))
+	assert.True(t, isStreaming(http.MethodGet, "/v1/ws", nil))
+	assert.False(t, isStreaming(http.MethodPost, "/events/1", nil))
+
+	// The headers of a regular route don't make it a stream.
+	assert.False(t, isStreaming(http.MethodGet, "/orders", http.Header{
+		"Accept":  {"text/event-stream"},
+		"Upgrade": {"websocket"},
+	}))
+}
+
+func TestSSEHubResume(t *testing.T) {
+	resetRegistry(t)
+
+	hub := NewHub(HubConfig{BacklogSize: 10})
+	stats := make(chan *Stats, 2)
+
+	e := echo.New()
+	e.GET("/events", func(c echo.Context) error {
+		st := &Stats{}
+		c.SetRequest(c.Request().WithContext(WithStats(c.Request().Context(), st)))
+		defer func() { stats <- st }()
+
+		sse, err := NewSSE(c, SSEConfig{Heartbeat: time.Hour})
+		if err != nil {
+			return err
+		}
+		hub.Join(sse, c.QueryParam("room"))
+		return sse.Serve()
+	})
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	// Events broadcast before the client reconnects.
+	hub.BroadcastTo("a", Event{Data: "first"})
+	hub.BroadcastTo("b", Event{Data: "other room"})
+	hub.BroadcastTo("a", Event{Event: "update", Data: "second"})
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/events?room=a", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set(LastEventIDHeader, "1")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	assert.Equal(t, "text/event-stream", resp.Header.Get(echo.HeaderContentType))
+
+	r := bufio.NewReader(resp.Body)
+	assert.Equal(t, "id: 3\nevent: update\ndata: second\n\n", readEvent(t, r))
+
+	require.Eventually(t, func() bool { return hub.Len("a") == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, hub.Broadcast(Event{Data: "to all"}))
+	assert.Equal(t, "id: 4\ndata: to all\n\n", readEvent(t, r))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, Shutdown(ctx))
+
+	st := <-stats
+	assert.Equal(t, KindSSE, st.Kind)
+	assert.Equal(t, CloseReasonShutdown, st.CloseReason())
+	assert.EqualValues(t, 2, st.MessagesOut.Load())
+	assert.Eventually(t, func() bool { return hub.Len(AllRoom) == 0 }, time.Second, 10*time.Millisecond)
+
+	// New streams are refused once shutting down.
+	resp2, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = resp2.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp2.StatusCode)
+}
+
+func TestWebSocket(t *testing.T) {
+	resetRegistry(t)
+
+	hub := NewHub(HubConfig{})
+	stats := make(chan *Stats, 1)
+
+	e := echo.New()
+	e.GET("/ws", func(c echo.Context) error {
+		st := &Stats{}
+		c.SetRequest(c.Request().WithContext(WithStats(c.Request().Context(), st)))
+		defer func() { stats <- st }()
+
+		ws, err := UpgradeWebSocket(c, WebSocketConfig{Heartbeat: time.Hour})
+		if err != nil {
+			return err
+		}
+		hub.Join(ws, "chat")
+		return ws.Serve(func(data []byte) error {
+			hub.BroadcastTo("chat", Event{Data: "echo: " + string(data)})
+			return nil
+		})
+	})
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("hello")))
+	_, msg, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "echo: hello", string(msg))
+
+	// Reading answers the close handshake, which `Shutdown` waits for.
+	readErr := make(chan error, 1)
+	go func() {
+		_, _, err := conn.ReadMessage()
+		readErr <- err
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, Shutdown(ctx))
+
+	err = <-readErr
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), "got %v", err)
+
+	st := <-stats
+	assert.Equal(t, KindWebSocket, st.Kind)
+	assert.Equal(t, CloseReasonShutdown, st.CloseReason())
+	assert.EqualValues(t, 1, st.MessagesIn.Load())
+	assert.EqualValues(t, 1, st.MessagesOut.Load())
+}
+
+func TestShutdown_UnservedStreams(t *testing.T) {
+	resetRegistry(t)
+
+	e := echo.New()
+	// The handlers return without serving their stream, closed or not.
+	e.GET("/early", func(c echo.Context) error {
+		_, err := NewSSE(c, SSEConfig{Heartbeat: time.Hour})
+		return err
+	})
+	e.GET("/closed", func(c echo.Context) error {
+		sse, err := NewSSE(c, SSEConfig{Heartbeat: time.Hour})
+		if err != nil {
+			return err
+		}
+		return sse.Close()
+	})
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	for _, path := range []string{"/early", "/closed"} {
+		resp, err := http.Get(srv.URL + path)
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	require.NoError(t, Shutdown(ctx))
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestSlowConsumer(t *testing.T) {
+	resetRegistry(t)
+
+	c, err := newConn(context.Background(), 1, &Stats{}, nil)
+	require.NoError(t, err)
+	defer c.finish(CloseReasonServer)
+
+	require.NoError(t, c.send(Event{Data: "1"}))
+	assert.ErrorIs(t, c.send(Event{Data: "2"}), ErrSlowConsumer)
+	assert.ErrorIs(t, c.send(Event{Data: "3"}), ErrClosed)
+	assert.Equal(t, CloseReasonSlowConsumer, c.stats.CloseReason())
+}
+
+func readEvent(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+
+	var b strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		b.WriteString(line)
+		if line == "\n" {
+			return b.String()
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+)
+
+// WebSocketConfig configures a WebSocket connection.
+type WebSocketConfig struct {
+	// Heartbeat is the ping interval. The connection is closed if no pong arrives within two intervals. Default 30s.
+	Heartbeat time.Duration
+	// WriteTimeout bounds every write to the client. Default 10s.
+	WriteTimeout time.Duration
+	// QueueSize is the number of events queued before the client is treated as a slow consumer. Default 64.
+	QueueSize int
+	// ReadLimit is the maximum size in bytes of a message read from the client. Default 1MB.
+	ReadLimit int64
+	// CheckOrigin returns true if the request Origin header is acceptable. Nil only allows same origin requests.
+	CheckOrigin func(r *http.Request) bool
+	// Subprotocols lists the supported protocols in order of preference.
+	Subprotocols []string
+}
+
+// WebSocket is a WebSocket connection. Events sent with `Send` are written, as text messages
+// (binary for `[]byte` data), by `Serve`, which must be called by the handler.
+type WebSocket struct {
+	*conn
+	ws  *websocket.Conn
+	cfg WebSocketConfig
+}
+
+// UpgradeWebSocket upgrades the request to a WebSocket connection. If the upgrade fails
+// the error response is already written.
+func UpgradeWebSocket(c echo.Context, cfg WebSocketConfig) (*WebSocket, error) {
+	if cfg.Heartbeat <= 0 {
+		cfg.Heartbeat = 30 * time.Second
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = 10 * time.Second
+	}
+	if cfg.ReadLimit <= 0 {
+		cfg.ReadLimit = 1 << 20
+	}
+
+	req := c.Request()
+	stats := StatsFromContext(req.Context())
+	stats.Kind = KindWebSocket
+
+	if isClosing() {
+		return nil, echo.NewHTTPError(http.StatusServiceUnavailable, ErrShuttingDown.Error())
+	}
+
+	upgrader := websocket.Upgrader{
+		CheckOrigin:  cfg.CheckOrigin,
+		Subprotocols: cfg.Subprotocols,
+	}
+	ws, err := upgrader.Upgrade(c.Response(), req, nil)
+	if err != nil {
+		return nil, err
+	}
+	ws.SetReadLimit(cfg.ReadLimit)
+
+	w := &WebSocket{ws: ws, cfg: cfg}
+	cn, err := newConn(req.Context(), cfg.QueueSize, stats, w.onClose)
+	if err != nil {
+		// Shutdown started during the upgrade.
+		_ = ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, CloseReasonShutdown), time.Now().Add(time.Second))
+		_ = ws.Close()
+		return nil, err
+	}
+	w.conn = cn
+
+	return w, nil
+}
+
+// Send queues an event. It returns `ErrSlowConsumer` and closes the connection when the queue is full.
+func (w *WebSocket) Send(ev Event) error {
+	return w.send(ev)
+}
+
+// Context is done once the connection is closed by the client, the server or `Shutdown`.
+func (w *WebSocket) Context() context.Context {
+	return w.ctx
+}
+
+// Close sends a close frame and closes the connection.
+func (w *WebSocket) Close() error {
+	w.close(CloseReasonServer)
+	return nil
+}
+
+// Serve writes the queued events and the pings, and calls `onMessage` for every message received,
+// until the connection is closed or `onMessage` returns an error. `onMessage` may be nil for
+// send only connections. Return its result from the handler.
+func (w *WebSocket) Serve(onMessage func(data []byte) error) error {
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		w.writeLoop()
+	}()
+
+	err := w.readLoop(onMessage)
+
+	w.close(CloseReasonClient)
+	<-writerDone
+	_ = w.ws.Close()
+	w.finish(CloseReasonClient)
+
+	return err
+}
+
+func (w *WebSocket) readLoop(onMessage func(data []byte) error) error {
+	pongWait := 2 * w.cfg.Heartbeat
+	_ = w.ws.SetReadDeadline(time.Now().Add(pongWait))
+	w.ws.SetPongHandler(func(string) error {
+		return w.ws.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := w.ws.ReadMessage()
+		if err != nil {
+			// Closing is the normal end of a connection, not an error of the handler.
+			return nil
+		}
+		if w.ctx.Err() != nil {
+			// Closed by the server, only the close handshake is left.
+			return nil
+		}
+		_ = w.ws.SetReadDeadline(time.Now().Add(pongWait))
+		w.stats.MessagesIn.Add(1)
+		w.stats.BytesIn.Add(int64(len(data)))
+
+		if onMessage != nil {
+			if err := onMessage(data); err != nil {
+				w.close(CloseReasonServer)
+				return err
+			}
+		}
+	}
+}
+
+func (w *WebSocket) writeLoop() {
+	t := time.NewTicker(w.cfg.Heartbeat)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case ev := <-w.queue:
+			data, err := encodeData(ev.Data)
+			if err != nil {
+				continue
+			}
+			msgType := websocket.TextMessage
+			if _, ok := ev.Data.([]byte); ok {
+				msgType = websocket.BinaryMessage
+			}
+
+			_ = w.ws.SetWriteDeadline(time.Now().Add(w.cfg.WriteTimeout))
+			if err := w.ws.WriteMessage(msgType, data); err != nil {
+				w.close(CloseReasonWriteError)
+				return
+			}
+			w.stats.MessagesOut.Add(1)
+			w.stats.BytesOut.Add(int64(len(data)))
+		case <-t.C:
+			if err := w.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(w.cfg.WriteTimeout)); err != nil {
+				w.close(CloseReasonWriteError)
+				return
+			}
+		}
+	}
+}
+
+// onClose sends the close frame and lets the read loop end shortly after, whether the client
+// answers the close handshake or not.
+func (w *WebSocket) onClose(reason string) {
+	code := websocket.CloseNormalClosure
+	switch reason {
+	case CloseReasonShutdown:
+		code = websocket.CloseGoingAway
+	case CloseReasonSlowConsumer:
+		code = websocket.CloseTryAgainLater
+	}
+
+	deadline := time.Now().Add(time.Second)
+	err := w.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline)
+	if err != nil && !errors.Is(err, websocket.ErrCloseSent) {
+		deadline = time.Now()
+	}
+	_ = w.ws.SetReadDeadline(deadline)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/getsentry/sentry-go"
 	"github.com/retail-ai-inc/bean/v2/config"
 	"github.com/retail-ai-inc/bean/v2/internal/regex"
+	"github.com/retail-ai-inc/bean/v2/trace"
 	"github.com/sourcegraph/conc/panics"
 	"github.com/sourcegraph/conc/pool"
 )
@@ -20,8 +21,9 @@ import (
 // Pool provides a way to execute multiple tasks concurrently and synchronously wait for all of them to finish.
 // It also recovers from panics within the tasks and support sentry tracing.
 type Pool struct {
-	pool *pool.ContextPool
-	span *sentry.Span
+	pool       *pool.ContextPool
+	span       *sentry.Span
+	finishOtel func()
 }
 
 // PoolOption provides options to configure the pool.
@@ -76,6 +78,7 @@ func NewPool(ctx context.Context, opts ...PoolOption) *Pool {
 	if span != nil {
 		ctx = span.Context()
 	}
+	ctx, finishOtel := setOtelSpan(ctx)
 
 	var pl *pool.ContextPool
 	if plOpts.cancelOnFirstErr {
@@ -88,8 +91,9 @@ func NewPool(ctx context.Context, opts ...PoolOption) *Pool {
 	}
 
 	return &Pool{
-		pool: pl,
-		span: span,
+		pool:       pl,
+		span:       span,
+		finishOtel: finishOtel,
 	}
 }
 
@@ -125,6 +129,9 @@ func (p *Pool) Wait() error {
 		if p.span != nil {
 			p.span.Finish()
 		}
+		if p.finishOtel != nil {
+			p.finishOtel()
+		}
 	}()
 
 	return p.pool.Wait()
@@ -143,7 +150,7 @@ func setSpan(ctx context.Context, req *http.Request) *sentry.Span {
 		clone.Scope().SetRequest(req)
 		ctx = sentry.SetHubOnContext(ctx, clone)
 
-		if config.Bean.Sentry.TracesSampleRate > 0.0 {
+		if config.Bean.Sentry.TracesSampleRate > 0.0 && !trace.IsOtel() {
 			urlPath := req.URL.Path
 
 			functionName := "unknown function"
@@ -167,6 +174,25 @@ func setSpan(ctx context.Context, req *http.Request) *sentry.Span {
 	return span
 }
 
+// setOtelSpan starts a `sync` span when OpenTelemetry is the tracing provider.
+func setOtelSpan(ctx context.Context) (context.Context, func()) {
+	if !trace.IsOtel() {
+		return ctx, func() {}
+	}
+
+	functionName := "unknown function"
+	if pc, _, _, ok := runtime.Caller(2); ok {
+		functionName = runtime.FuncForPC(pc).Name()
+	}
+
+	tracer := trace.CurrentTracer()
+	if !tracer.Enabled() {
+		return ctx, func() {}
+	}
+
+	return tracer.Start(ctx, "sync", trace.SpanOptions{Description: functionName})
+}
+
 func capturePanic(ctx context.Context, err error) {
 	if config.Bean.Sentry.On {
 		var localHub *sentry.Hub
@@ -13,8 +13,9 @@ import (
 )
 
 type ResultPool[T any] struct {
-	pool *pool.ResultContextPool[T]
-	span *sentry.Span
+	pool       *pool.ResultContextPool[T]
+	span       *sentry.Span
+	finishOtel func()
 }
 
 type ResultPoolOption func(*resultPoolOptions)
@@ -72,6 +73,7 @@ func NewResultPool[T any](ctx context.Context, opts ...ResultPoolOption) ResultP
 	if span != nil {
 		ctx = span.Context()
 	}
+	ctx, finishOtel := setOtelSpan(ctx)
 
 	var pl *pool.ResultContextPool[T]
 	if plOpts.pl.cancelOnFirstErr {
@@ -87,8 +89,9 @@ func NewResultPool[T any](ctx context.Context, opts ...ResultPoolOption) ResultP
 	}
 
 	return ResultPool[T]{
-		pool: pl,
-		span: span,
+		pool:       pl,
+		span:       span,
+		finishOtel: finishOtel,
 	}
 }
 
@@ -121,6 +124,9 @@ func (p *ResultPool[T]) Wait() ([]T, error) {
 		if p.span != nil {
 			p.span.Finish()
 		}
+		if p.finishOtel != nil {
+			p.finishOtel()
+		}
 	}()
 
 	return p.pool.Wait()
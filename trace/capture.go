@@ -8,6 +8,7 @@ import (
 	sentryecho "github.com/getsentry/sentry-go/echo"
 	"github.com/labstack/echo/v4"
 	"github.com/retail-ai-inc/bean/v2/config"
+	berror "github.com/retail-ai-inc/bean/v2/error"
 	"github.com/retail-ai-inc/bean/v2/log"
 )
 
@@ -64,7 +65,7 @@ func sentryCaptureException(err error, logging bool, getHub func() (hub *sentry.
 	}
 
 	// Log the error if logging is on, whether sentry is on or off.
-	if logging {
+	if logging && berror.LogDedup.Allow(err) {
 		log.Logger().Error(err)
 	}
 
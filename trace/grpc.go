@@ -0,0 +1,43 @@
+package trace
+
+import (
+	"net/http"
+	"net/textproto"
+
+	"go.opentelemetry.io/otel/propagation"
+	"google.golang.org/grpc/metadata"
+)
+
+// MetadataCarrier adapts gRPC metadata to the OpenTelemetry `TextMapCarrier` interface.
+type MetadataCarrier metadata.MD
+
+var _ propagation.TextMapCarrier = MetadataCarrier{}
+
+func (c MetadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c MetadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c MetadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// MetadataToHeader converts incoming gRPC metadata into an HTTP header, so the HTTP helpers like
+// `SentryTraceFromHeader` and `IncomingSpanContext` can be used for gRPC calls as well.
+func MetadataToHeader(md metadata.MD) http.Header {
+	header := make(http.Header, len(md))
+	for k, v := range md {
+		header[textproto.CanonicalMIMEHeaderKey(k)] = v
+	}
+	return header
+}
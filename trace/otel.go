@@ -0,0 +1,82 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/retail-ai-inc/bean/v2/config"
+	"github.com/retail-ai-inc/bean/v2/helpers"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// InitOtel installs a global OpenTelemetry tracer provider which exports spans to an OTLP/HTTP collector.
+// The returned function flushes the pending spans and shuts the provider down; call it on server shutdown.
+func InitOtel(ctx context.Context, cfg config.Otel, serviceName, environment string) (func(ctx context.Context) error, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("otel endpoint is empty")
+	}
+
+	exporterOpts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(cfg.Endpoint),
+	}
+	if cfg.URLPath != "" {
+		exporterOpts = append(exporterOpts, otlptracehttp.WithURLPath(cfg.URLPath))
+	}
+	if cfg.Insecure {
+		exporterOpts = append(exporterOpts, otlptracehttp.WithInsecure())
+	}
+	if len(cfg.Headers) > 0 {
+		exporterOpts = append(exporterOpts, otlptracehttp.WithHeaders(cfg.Headers))
+	}
+	if cfg.Timeout > 0 {
+		exporterOpts = append(exporterOpts, otlptracehttp.WithTimeout(cfg.Timeout))
+	}
+
+	exporter, err := otlptracehttp.New(ctx, exporterOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.ServiceName != "" {
+		serviceName = cfg.ServiceName
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", serviceName),
+		attribute.String("service.version", helpers.CurrVersion()),
+		attribute.String("deployment.environment", environment),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	rate := helpers.FloatInRange(cfg.TracesSampleRate, 0.0, 1.0)
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter, sdktrace.WithBatchTimeout(5*time.Second)),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(rate))),
+	)
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(Propagator())
+
+	return tp.Shutdown, nil
+}
+
+// ContextWithOtelSpan copies the OpenTelemetry span carried by `from` onto `to`. Work detached from the
+// request context, like async tasks, is then still recorded as a child of the request span.
+func ContextWithOtelSpan(to, from context.Context) context.Context {
+	sc := oteltrace.SpanContextFromContext(from)
+	if !sc.IsValid() {
+		return to
+	}
+
+	return oteltrace.ContextWithSpanContext(to, sc)
+}
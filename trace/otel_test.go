@@ -0,0 +1,127 @@
+package trace_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/retail-ai-inc/bean/v2/config"
+	"github.com/retail-ai-inc/bean/v2/log"
+	"github.com/retail-ai-inc/bean/v2/trace"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// collectorStandIn accepts OTLP/HTTP protobuf exports and records the received span names.
+type collectorStandIn struct {
+	mu    sync.Mutex
+	spans []string
+}
+
+func (c *collectorStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/traces" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var req coltracepb.ExportTraceServiceRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	for _, rs := range req.GetResourceSpans() {
+		for _, ss := range rs.GetScopeSpans() {
+			for _, s := range ss.GetSpans() {
+				c.spans = append(c.spans, s.GetName())
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *collectorStandIn) names() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.spans...)
+}
+
+func TestOtelExportToCollector(t *testing.T) {
+	collector := &collectorStandIn{}
+	srv := httptest.NewServer(collector)
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	reset := setOtelConfig()
+	defer reset()
+
+	prevProvider := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(prevProvider)
+
+	shutdown, err := trace.InitOtel(context.Background(), config.Otel{
+		Endpoint:         u.Host,
+		Insecure:         true,
+		TracesSampleRate: 1.0,
+		Timeout:          5 * time.Second,
+	}, "bean-test", "test")
+	require.NoError(t, err)
+
+	ctx, finish := trace.StartSpan(context.Background(), "parent")
+	childCtx, finishChild := trace.StartSpan(ctx, "child")
+
+	// The log extractor must see the same trace as the span.
+	parentTrace := log.NewOtelExtractor().Extract(ctx)
+	childTrace := log.NewOtelExtractor().Extract(childCtx)
+	assert.NotEmpty(t, parentTrace.TraceID)
+	assert.Equal(t, parentTrace.TraceID, childTrace.TraceID)
+	assert.NotEqual(t, parentTrace.SpanID, childTrace.SpanID)
+
+	// A detached context keeps the trace.
+	detached := trace.ContextWithOtelSpan(context.Background(), childCtx)
+	assert.Equal(t, childTrace, log.NewOtelExtractor().Extract(detached))
+
+	finishChild()
+	finish()
+
+	require.NoError(t, shutdown(context.Background()))
+	assert.ElementsMatch(t, []string{"parent", "child"}, collector.names())
+}
+
+func TestInitOtelWithoutEndpoint(t *testing.T) {
+	_, err := trace.InitOtel(context.Background(), config.Otel{}, "bean-test", "test")
+	assert.Error(t, err)
+}
+
+func setOtelConfig() func() {
+	originalProvider := viper.GetString("tracing.provider")
+	originalSampleRate := viper.GetFloat64("tracing.otel.tracesSampleRate")
+
+	viper.Set("tracing.provider", trace.ProviderOtel)
+	viper.Set("tracing.otel.tracesSampleRate", 1.0)
+
+	return func() {
+		viper.Set("tracing.provider", originalProvider)
+		viper.Set("tracing.otel.tracesSampleRate", originalSampleRate)
+	}
+}
@@ -0,0 +1,189 @@
+package trace
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+	bctx "github.com/retail-ai-inc/bean/v2/context"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/propagation"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// Propagation formats which can be listed in `tracing.propagation`.
+const (
+	PropagationSentry  = "sentry"  // `sentry-trace` and Sentry `baggage` headers.
+	PropagationW3C     = "w3c"     // W3C `traceparent` and `tracestate` headers.
+	PropagationBaggage = "baggage" // W3C `baggage` header.
+)
+
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// PropagationFormats returns the formats configured in `tracing.propagation`.
+// If nothing is configured, Sentry keeps propagating `sentry-trace`/`baggage` only and
+// OpenTelemetry propagates W3C `traceparent`/`tracestate` plus `baggage`.
+func PropagationFormats() []string {
+	formats := make([]string, 0, 3)
+	for _, f := range viper.GetStringSlice("tracing.propagation") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if f != "" && !slices.Contains(formats, f) {
+			formats = append(formats, f)
+		}
+	}
+
+	if len(formats) > 0 {
+		return formats
+	}
+
+	if IsOtel() {
+		return []string{PropagationW3C, PropagationBaggage}
+	}
+
+	return []string{PropagationSentry}
+}
+
+// IsPropagationEnabled reports whether `format` is one of the configured propagation formats.
+func IsPropagationEnabled(format string) bool {
+	return slices.Contains(PropagationFormats(), format)
+}
+
+// Propagator returns an OpenTelemetry propagator made of the configured formats.
+// W3C is extracted last, so it wins over `sentry-trace` when a request carries both.
+func Propagator() propagation.TextMapPropagator {
+	formats := PropagationFormats()
+
+	propagators := make([]propagation.TextMapPropagator, 0, len(formats))
+	if slices.Contains(formats, PropagationSentry) {
+		propagators = append(propagators, sentryPropagator{})
+	}
+	if slices.Contains(formats, PropagationW3C) {
+		propagators = append(propagators, propagation.TraceContext{})
+	}
+	if slices.Contains(formats, PropagationBaggage) {
+		propagators = append(propagators, propagation.Baggage{})
+	}
+
+	return propagation.NewCompositeTextMapPropagator(propagators...)
+}
+
+// inject writes the tracing information of the span carried by `ctx` into `carrier`, in every configured format.
+func inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	if IsOtel() {
+		Propagator().Inject(ctx, carrier)
+		return
+	}
+
+	span := sentry.SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+
+	formats := PropagationFormats()
+
+	if slices.Contains(formats, PropagationSentry) {
+		carrier.Set(sentry.SentryTraceHeader, span.ToSentryTrace())
+		carrier.Set(sentry.SentryBaggageHeader, span.ToBaggage())
+	} else if slices.Contains(formats, PropagationBaggage) {
+		carrier.Set(sentry.SentryBaggageHeader, span.ToBaggage())
+	}
+
+	if slices.Contains(formats, PropagationW3C) {
+		flags := "00"
+		if span.Sampled.Bool() {
+			flags = "01"
+		}
+		carrier.Set(TraceparentHeader, "00-"+span.TraceID.String()+"-"+span.SpanID.String()+"-"+flags)
+
+		// Sentry has no tracestate of its own, the one of the caller is passed on.
+		if state, ok := bctx.GetTraceState(ctx); ok {
+			carrier.Set(TracestateHeader, state)
+		}
+	}
+}
+
+// IncomingSpanContext returns the remote span context of an incoming request, read from the configured formats.
+func IncomingSpanContext(header http.Header) oteltrace.SpanContext {
+	ctx := Propagator().Extract(context.Background(), propagation.HeaderCarrier(header))
+	return oteltrace.SpanContextFromContext(ctx)
+}
+
+// SentryTraceFromHeader returns the `sentry-trace` value to continue an incoming trace with Sentry.
+// If the caller only sent W3C `traceparent` and W3C propagation is on, the value is converted from it;
+// both formats share the same 32 hex trace id and 16 hex span id.
+func SentryTraceFromHeader(header http.Header) string {
+	if v := header.Get(sentry.SentryTraceHeader); v != "" {
+		return v
+	}
+
+	if header.Get(TraceparentHeader) == "" || !IsPropagationEnabled(PropagationW3C) {
+		return ""
+	}
+
+	ctx := propagation.TraceContext{}.Extract(context.Background(), propagation.HeaderCarrier(header))
+	sc := oteltrace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return ""
+	}
+
+	return toSentryTrace(sc)
+}
+
+func toSentryTrace(sc oteltrace.SpanContext) string {
+	sampled := "0"
+	if sc.IsSampled() {
+		sampled = "1"
+	}
+	return sc.TraceID().String() + "-" + sc.SpanID().String() + "-" + sampled
+}
+
+// sentryPropagator lets OpenTelemetry read and write `sentry-trace` headers, for fleets still on Sentry.
+type sentryPropagator struct{}
+
+func (sentryPropagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	sc := oteltrace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	carrier.Set(sentry.SentryTraceHeader, toSentryTrace(sc))
+}
+
+func (sentryPropagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	// sentry-trace: <32 hex trace id>-<16 hex span id>[-<sampled>]
+	parts := strings.Split(strings.TrimSpace(carrier.Get(sentry.SentryTraceHeader)), "-")
+	if len(parts) < 2 {
+		return ctx
+	}
+
+	traceID, err := oteltrace.TraceIDFromHex(parts[0])
+	if err != nil {
+		return ctx
+	}
+	spanID, err := oteltrace.SpanIDFromHex(parts[1])
+	if err != nil {
+		return ctx
+	}
+
+	var flags oteltrace.TraceFlags
+	if len(parts) > 2 && parts[2] == "1" {
+		flags = oteltrace.FlagsSampled
+	}
+
+	sc := oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: flags,
+		Remote:     true,
+	})
+
+	return oteltrace.ContextWithRemoteSpanContext(ctx, sc)
+}
+
+func (sentryPropagator) Fields() []string {
+	return []string{sentry.SentryTraceHeader}
+}
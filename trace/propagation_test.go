@@ -0,0 +1,157 @@
+package trace_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	bctx "github.com/retail-ai-inc/bean/v2/context"
+	"github.com/retail-ai-inc/bean/v2/trace"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	w3cTraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	w3cParentID    = "00f067aa0ba902b7"
+	w3cTraceparent = "00-" + w3cTraceID + "-" + w3cParentID + "-01"
+)
+
+func Test_Propagate_W3C_Tracing_To_HTTP_Request(t *testing.T) {
+	reset := setSentryConfig(true)
+	defer reset()
+	resetFormats := setPropagation(trace.PropagationSentry, trace.PropagationW3C)
+	defer resetFormats()
+
+	ctx, finish := trace.StartSpan(context.Background(), "test")
+	defer finish()
+	span := sentry.SpanFromContext(ctx)
+	require.NotNil(t, span)
+
+	got := trace.PropagateToHTTP(ctx, http.Header{})
+
+	assert.NotEmpty(t, got.Get(sentry.SentryTraceHeader))
+	traceparent := got.Get(trace.TraceparentHeader)
+	parts := strings.Split(traceparent, "-")
+	require.Len(t, parts, 4, "traceparent is %q", traceparent)
+	assert.Equal(t, "00", parts[0])
+	assert.Equal(t, span.TraceID.String(), parts[1])
+	assert.Equal(t, span.SpanID.String(), parts[2])
+}
+
+func Test_Propagate_W3C_Tracestate_To_HTTP_Request(t *testing.T) {
+	reset := setSentryConfig(true)
+	defer reset()
+	resetFormats := setPropagation(trace.PropagationSentry, trace.PropagationW3C)
+	defer resetFormats()
+
+	ctx := bctx.SetTraceState(context.Background(), "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7")
+	ctx, finish := trace.StartSpan(ctx, "test")
+	defer finish()
+
+	got := trace.PropagateToHTTP(ctx, http.Header{})
+	assert.NotEmpty(t, got.Get(trace.TraceparentHeader))
+	assert.Equal(t, "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7", got.Get(trace.TracestateHeader))
+
+	// Nothing to forward without an incoming one.
+	ctx, finish2 := trace.StartSpan(context.Background(), "test")
+	defer finish2()
+	assert.Empty(t, trace.PropagateToHTTP(ctx, http.Header{}).Get(trace.TracestateHeader))
+}
+
+func Test_Propagate_W3C_Only_Tracing_To_gRPC_Request(t *testing.T) {
+	reset := setSentryConfig(true)
+	defer reset()
+	resetFormats := setPropagation(trace.PropagationW3C)
+	defer resetFormats()
+
+	ctx, finish := trace.StartSpan(context.Background(), "test")
+	defer finish()
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-tenant-id", "1")
+
+	got := trace.PropagateToGRPC(ctx)
+
+	md, ok := metadata.FromOutgoingContext(got)
+	require.True(t, ok)
+	assert.Len(t, md.Get(trace.TraceparentHeader), 1)
+	assert.Empty(t, md.Get(sentry.SentryTraceHeader), "sentry format is not configured")
+	assert.Equal(t, []string{"1"}, md.Get("x-tenant-id"), "existing metadata should be kept")
+}
+
+func Test_Sentry_Trace_From_W3C_Header(t *testing.T) {
+	tests := []struct {
+		name    string
+		formats []string
+		header  http.Header
+		want    string
+	}{
+		{
+			name:    "sentry_trace_header_wins",
+			formats: []string{trace.PropagationSentry, trace.PropagationW3C},
+			header: http.Header{
+				"Sentry-Trace": []string{"sentry-value"},
+				"Traceparent":  []string{w3cTraceparent},
+			},
+			want: "sentry-value",
+		},
+		{
+			name:    "converted_from_traceparent",
+			formats: []string{trace.PropagationSentry, trace.PropagationW3C},
+			header:  http.Header{"Traceparent": []string{w3cTraceparent}},
+			want:    w3cTraceID + "-" + w3cParentID + "-1",
+		},
+		{
+			name:    "w3c_not_configured",
+			formats: []string{trace.PropagationSentry},
+			header:  http.Header{"Traceparent": []string{w3cTraceparent}},
+			want:    "",
+		},
+		{
+			name:    "invalid_traceparent",
+			formats: []string{trace.PropagationW3C},
+			header:  http.Header{"Traceparent": []string{"00-invalid-01"}},
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reset := setPropagation(tt.formats...)
+			defer reset()
+
+			assert.Equal(t, tt.want, trace.SentryTraceFromHeader(tt.header))
+		})
+	}
+}
+
+func Test_Incoming_Span_Context(t *testing.T) {
+	reset := setPropagation(trace.PropagationSentry, trace.PropagationW3C)
+	defer reset()
+
+	sc := trace.IncomingSpanContext(http.Header{
+		"Sentry-Trace": []string{"0123456789abcdef0123456789abcdef-0123456789abcdef-1"},
+		"Traceparent":  []string{w3cTraceparent},
+	})
+	require.True(t, sc.IsValid())
+	assert.Equal(t, w3cTraceID, sc.TraceID().String(), "W3C should win over sentry-trace")
+
+	sc = trace.IncomingSpanContext(http.Header{
+		"Sentry-Trace": []string{"0123456789abcdef0123456789abcdef-0123456789abcdef-1"},
+	})
+	require.True(t, sc.IsValid())
+	assert.Equal(t, "0123456789abcdef0123456789abcdef", sc.TraceID().String())
+	assert.True(t, sc.IsSampled())
+}
+
+func setPropagation(formats ...string) func() {
+	original := viper.GetStringSlice("tracing.propagation")
+	viper.Set("tracing.propagation", formats)
+
+	return func() {
+		viper.Set("tracing.propagation", original)
+	}
+}
@@ -26,6 +26,8 @@ import (
 	"context"
 	"net/http"
 	"runtime"
+	"strconv"
+	"strings"
 
 	sentryecho "github.com/getsentry/sentry-go/echo"
 
@@ -33,7 +35,7 @@ import (
 	"github.com/labstack/echo/v4"
 	berror "github.com/retail-ai-inc/bean/v2/error"
 	"github.com/retail-ai-inc/bean/v2/internal/validator"
-	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/propagation"
 	"google.golang.org/grpc/metadata"
 )
 
@@ -61,79 +63,68 @@ func StartSpan(c context.Context, operation string, spanOpts ...sentry.SpanOptio
 
 // startSpan starts a span and returns context containing the span and a function to finish the corresponding span.
 func startSpan(c context.Context, operation string, skip int, spanOpts ...sentry.SpanOption) (context.Context, func()) {
-	// If trace sample rate is 0.0 or 0 or Sentry is off, use the provided context as-is.
-	if viper.GetFloat64("sentry.tracesSampleRate") == 0 || !viper.GetBool("sentry.on") {
+	tracer := CurrentTracer()
+	if !tracer.Enabled() {
 		return c, func() {}
 	}
 
+	opts := SpanOptions{Sentry: spanOpts}
 	if len(spanOpts) == 0 {
-		// Add default options if none provided.
-		spanOpts = append(spanOpts, defaultDescription(skip+1))
+		// Add default description if no options provided.
+		opts.Description = callerName(skip + 1)
 	}
 
-	span := sentry.StartSpan(c, operation, spanOpts...)
-	newCtx := span.Context()
-
-	return newCtx, func() {
-		span.Finish()
-	}
+	return tracer.Start(c, operation, opts)
 }
 
-func defaultDescription(skip int) sentry.SpanOption {
+func callerName(skip int) string {
 
 	functionName := "unknown function"
 	if pc, _, _, ok := runtime.Caller(skip + 1); ok {
 		functionName = runtime.FuncForPC(pc).Name()
 	}
 
-	return sentry.WithDescription(functionName)
+	return functionName
 }
 
-// PropagateToHTTP propagates the Sentry tracing information to the outgoing HTTP/1.X request header.
+// PropagateToHTTP propagates the tracing information to the outgoing HTTP/1.X request header.
+// The headers written depend on `tracing.propagation`: `sentry-trace`/`baggage` for Sentry,
+// `traceparent`/`tracestate` for W3C and `baggage` for W3C baggage.
 // Refers to the following link for more information.
 // https://docs.sentry.io/platforms/go/tracing/trace-propagation/custom-instrumentation/#step-2-inject-tracing-information-to-outgoing-requests
 func PropagateToHTTP(ctx context.Context, header http.Header) http.Header {
 
-	sentryTrace, baggage := extractTracing(ctx)
-	if sentryTrace == "" {
-		return header
-	}
-
-	header.Add(sentry.SentryTraceHeader, sentryTrace)
-	header.Add(sentry.SentryBaggageHeader, baggage)
+	inject(ctx, propagation.HeaderCarrier(header))
 
 	return header
 }
 
-// PropagateToGRPC propagates the Sentry tracing information to the outgoing gRPC request metadata.
+// PropagateToGRPC propagates the tracing information to the outgoing gRPC request metadata.
+// Existing outgoing metadata is kept. The keys written depend on `tracing.propagation`, same as `PropagateToHTTP`.
 // Refers to the following link for more information.
 // https://docs.sentry.io/platforms/go/tracing/trace-propagation/custom-instrumentation/#step-2-inject-tracing-information-to-outgoing-requests
 func PropagateToGRPC(ctx context.Context) context.Context {
 
-	sentryTrace, baggage := extractTracing(ctx)
-	if sentryTrace == "" {
+	carrier := propagation.MapCarrier{}
+	inject(ctx, carrier)
+	if len(carrier) == 0 {
 		return ctx
 	}
 
-	md := metadata.Pairs(
-		sentry.SentryTraceHeader, sentryTrace,
-		sentry.SentryBaggageHeader, baggage,
-	)
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	for k, v := range carrier {
+		md.Set(k, v)
+	}
 	ctx = metadata.NewOutgoingContext(ctx, md)
 
 	return ctx
 }
 
-func extractTracing(ctx context.Context) (sentryTrace, baggage string) {
-
-	span := sentry.SpanFromContext(ctx)
-	if span == nil {
-		return "", ""
-	}
-
-	return span.ToSentryTrace(), span.ToBaggage()
-}
-
 // Modify event through beforeSend function.
 func DefaultBeforeSend(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
 	// Example: enriching the event by adding aditional data.
@@ -141,9 +132,14 @@ func DefaultBeforeSend(event *sentry.Event, hint *sentry.EventHint) *sentry.Even
 	case *validator.ValidationError:
 		return event
 	case *berror.APIError:
-		if err.Ignorable {
+		// The registered codes set the ignorability and the level of their errors.
+		def, registered := berror.LookupCode(err.GlobalErrCode)
+		if err.Ignorable || def.Ignorable {
 			return nil
 		}
+		if registered {
+			event.Level = sentryLevel(def.Severity)
+		}
 		event.Contexts["Error"] = map[string]interface{}{
 			"HTTPStatusCode": err.HTTPStatusCode,
 			"GlobalErrCode":  err.GlobalErrCode,
@@ -157,6 +153,83 @@ func DefaultBeforeSend(event *sentry.Event, hint *sentry.EventHint) *sentry.Even
 	}
 }
 
+// sentryLevel returns the Sentry level of the severity of a registered error code.
+func sentryLevel(severity string) sentry.Level {
+	switch severity {
+	case "DEBUG":
+		return sentry.LevelDebug
+	case "INFO":
+		return sentry.LevelInfo
+	case "WARNING":
+		return sentry.LevelWarning
+	case "CRITICAL":
+		return sentry.LevelFatal
+	default:
+		return sentry.LevelError
+	}
+}
+
+// suppressedFingerprint is the first part of the Sentry fingerprint of the summaries of `CaptureSuppressed`,
+// the second being the fingerprint of the error.
+const suppressedFingerprint = "bean-error-dedup"
+
+// CaptureSuppressed sends the summary of the suppressed occurrences of an error to Sentry, as a warning.
+// `DedupBeforeSend` throttles the summaries with the fingerprint of the error.
+func CaptureSuppressed(s berror.Suppressed) {
+	event := sentry.NewEvent()
+	event.Level = sentry.LevelWarning
+	event.Message = s.String()
+	event.Fingerprint = []string{suppressedFingerprint, s.Fingerprint}
+	sentry.CaptureEvent(event)
+}
+
+// DedupBeforeSend returns a beforeSend function running next, if any, then dropping the exceptions the
+// deduplicator suppresses. The exceptions of the panics are fingerprinted with their type, message and
+// top stack frames, and the summaries of `CaptureSuppressed` with the fingerprint of their error; the
+// other messages are always sent.
+func DedupBeforeSend(d *berror.Dedup, next func(*sentry.Event, *sentry.EventHint) *sentry.Event) func(*sentry.Event, *sentry.EventHint) *sentry.Event {
+	return func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+		if next != nil {
+			if event = next(event, hint); event == nil {
+				return nil
+			}
+		}
+
+		if hint != nil && hint.OriginalException != nil {
+			if !d.Allow(hint.OriginalException) {
+				return nil
+			}
+			return event
+		}
+
+		if len(event.Exception) == 0 {
+			if len(event.Fingerprint) == 2 && event.Fingerprint[0] == suppressedFingerprint &&
+				!d.AllowFingerprint(suppressedFingerprint+"|"+event.Fingerprint[1], event.Message) {
+				return nil
+			}
+			return event
+		}
+		ex := event.Exception[len(event.Exception)-1]
+		if !d.AllowFingerprint(exceptionFingerprint(ex, d.Frames()), ex.Value) {
+			return nil
+		}
+		return event
+	}
+}
+
+func exceptionFingerprint(ex sentry.Exception, frames int) string {
+	var b strings.Builder
+	b.WriteString(ex.Type + "|" + ex.Value)
+	if ex.Stacktrace != nil {
+		// The frames are from the oldest to the newest.
+		st := ex.Stacktrace.Frames
+		for i := len(st) - 1; i >= 0 && i >= len(st)-frames; i-- {
+			b.WriteString("|" + st[i].Function + ":" + strconv.Itoa(st[i].Lineno))
+		}
+	}
+	return b.String()
+}
+
 // Modify breadcrumbs through beforeBreadcrumb function.
 func DefaultBeforeBreadcrumb(breadcrumb *sentry.Breadcrumb, hint *sentry.BreadcrumbHint) *sentry.Breadcrumb {
 	// Example: discard the breadcrumb by return nil.
@@ -2,6 +2,7 @@ package trace_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/getsentry/sentry-go"
+	berror "github.com/retail-ai-inc/bean/v2/error"
 	"github.com/retail-ai-inc/bean/v2/trace"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
@@ -210,3 +212,66 @@ func Test_Propagate_Sentry_Tracing_To_gRPC_Request(t *testing.T) {
 		})
 	}
 }
+
+func TestDedupBeforeSend(t *testing.T) {
+	d := berror.NewDedup(berror.DedupConfig{Window: time.Hour}, nil)
+	defer d.Close()
+	beforeSend := trace.DedupBeforeSend(d, trace.DefaultBeforeSend)
+
+	err := errors.New("db down")
+	event := func() *sentry.Event { return &sentry.Event{Contexts: map[string]sentry.Context{}} }
+	assert.NotNil(t, beforeSend(event(), &sentry.EventHint{OriginalException: err}))
+	assert.Nil(t, beforeSend(event(), &sentry.EventHint{OriginalException: err}))
+
+	// The ignorable errors are dropped by next, without being counted.
+	ignorable := berror.NewIgnorableAPIError(http.StatusNotFound, berror.RESOURCE_NOT_FOUND, errors.New("not found"))
+	assert.Nil(t, beforeSend(event(), &sentry.EventHint{OriginalException: ignorable}))
+
+	panicEvent := func() *sentry.Event {
+		e := event()
+		e.Exception = []sentry.Exception{{
+			Type:       "*errors.errorString",
+			Value:      "nil map",
+			Stacktrace: &sentry.Stacktrace{Frames: []sentry.Frame{{Function: "main"}, {Function: "handler", Lineno: 12}}},
+		}}
+		return e
+	}
+	assert.NotNil(t, beforeSend(panicEvent(), &sentry.EventHint{RecoveredException: "nil map"}))
+	assert.Nil(t, beforeSend(panicEvent(), &sentry.EventHint{RecoveredException: "nil map"}))
+
+	// Messages are always sent.
+	assert.NotNil(t, beforeSend(&sentry.Event{Message: "hello"}, &sentry.EventHint{}))
+	assert.NotNil(t, beforeSend(&sentry.Event{Message: "hello"}, &sentry.EventHint{}))
+}
+
+func TestCaptureSuppressed(t *testing.T) {
+	d := berror.NewDedup(berror.DedupConfig{Window: time.Hour}, nil)
+	defer d.Close()
+
+	// The events let through are recorded instead of being sent.
+	var events []*sentry.Event
+	beforeSend := trace.DedupBeforeSend(d, nil)
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		BeforeSend: func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+			if event = beforeSend(event, hint); event != nil {
+				events = append(events, event)
+			}
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	sentry.CurrentHub().BindClient(client)
+	defer sentry.CurrentHub().BindClient(nil)
+
+	// The summaries are throttled per error, like the errors.
+	summary := berror.Suppressed{Fingerprint: "fp-1", Error: "db down", Count: 3, Since: time.Now()}
+	trace.CaptureSuppressed(summary)
+	trace.CaptureSuppressed(summary)
+	trace.CaptureSuppressed(berror.Suppressed{Fingerprint: "fp-2", Error: "cache down", Count: 2, Since: time.Now()})
+
+	require.Len(t, events, 2)
+	assert.Equal(t, sentry.LevelWarning, events[0].Level)
+	assert.Contains(t, events[0].Message, "3 similar errors suppressed")
+	assert.Equal(t, []string{"bean-error-dedup", "fp-1"}, events[0].Fingerprint)
+	assert.Contains(t, events[1].Message, "cache down")
+}
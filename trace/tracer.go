@@ -0,0 +1,98 @@
+package trace
+
+import (
+	"context"
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ProviderSentry = "sentry"
+	ProviderOtel   = "otel"
+)
+
+// instrumentationName is the OpenTelemetry instrumentation scope of every span started by bean.
+const instrumentationName = "github.com/retail-ai-inc/bean/v2"
+
+// Tracer is a tracing backend. `StartSpan` and `StartSpanWithEcho` delegate to the tracer selected
+// by `tracing.provider` in env.json.
+type Tracer interface {
+	// Enabled reports whether the backend records spans at all.
+	Enabled() bool
+	// Start starts a child span of the span carried by `ctx` and returns the context holding the new span
+	// and a function to finish it.
+	Start(ctx context.Context, operation string, opts SpanOptions) (context.Context, func())
+}
+
+// SpanOptions carries backend independent span settings plus the backend specific ones.
+type SpanOptions struct {
+	Description string
+	// Sentry options are only used by the Sentry tracer.
+	Sentry []sentry.SpanOption
+}
+
+// Provider returns the configured tracing provider. An empty or unknown value falls back to Sentry.
+func Provider() string {
+	if strings.EqualFold(strings.TrimSpace(viper.GetString("tracing.provider")), ProviderOtel) {
+		return ProviderOtel
+	}
+	return ProviderSentry
+}
+
+// IsOtel reports whether OpenTelemetry is the configured tracing provider.
+func IsOtel() bool {
+	return Provider() == ProviderOtel
+}
+
+// CurrentTracer returns the tracer selected by `tracing.provider`.
+func CurrentTracer() Tracer {
+	if IsOtel() {
+		return otelTracer{}
+	}
+	return sentryTracer{}
+}
+
+type sentryTracer struct{}
+
+func (sentryTracer) Enabled() bool {
+	// If trace sample rate is 0.0 or 0 or Sentry is off, nothing will be recorded.
+	return viper.GetFloat64("sentry.tracesSampleRate") != 0 && viper.GetBool("sentry.on")
+}
+
+func (sentryTracer) Start(ctx context.Context, operation string, opts SpanOptions) (context.Context, func()) {
+	spanOpts := opts.Sentry
+	if len(spanOpts) == 0 && opts.Description != "" {
+		spanOpts = []sentry.SpanOption{sentry.WithDescription(opts.Description)}
+	}
+
+	span := sentry.StartSpan(ctx, operation, spanOpts...)
+
+	return span.Context(), func() {
+		span.Finish()
+	}
+}
+
+type otelTracer struct{}
+
+func (otelTracer) Enabled() bool {
+	// Same as Sentry, a sample rate of 0 turns the tracing off.
+	return viper.GetFloat64("tracing.otel.tracesSampleRate") != 0
+}
+
+func (otelTracer) Start(ctx context.Context, operation string, opts SpanOptions) (context.Context, func()) {
+	var startOpts []oteltrace.SpanStartOption
+	if opts.Description != "" {
+		startOpts = append(startOpts, oteltrace.WithAttributes(attribute.String("code.function", opts.Description)))
+	}
+
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, operation, startOpts...)
+
+	return ctx, func() {
+		span.End()
+	}
+}
@@ -0,0 +1,290 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/retail-ai-inc/bean/v2/config"
+	bctx "github.com/retail-ai-inc/bean/v2/context"
+	blog "github.com/retail-ai-inc/bean/v2/log"
+	"github.com/retail-ai-inc/bean/v2/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+var requestIDMetadataKey = strings.ToLower(echo.HeaderXRequestID)
+
+type loggingInterceptor struct {
+	logger blog.AccessLogger
+	opt    LoggingOptions
+}
+
+func newLoggingInterceptor(logger blog.AccessLogger, opt LoggingOptions) *loggingInterceptor {
+	if opt.MaxBodySize == 0 {
+		opt.MaxBodySize = 64 * 1024
+	}
+
+	if len(opt.AllowedReqHeaders) == 0 {
+		opt.AllowedReqHeaders = config.Bean.AccessLog.ReqHeaderParam
+	}
+
+	if len(opt.AllowedRespHeaders) == 0 {
+		opt.AllowedRespHeaders = config.Bean.AccessLog.ResHeaderParam
+	}
+
+	return &loggingInterceptor{
+		logger: logger,
+		opt:    opt,
+	}
+}
+
+// NewLoggingUnaryInterceptor returns a unary client interceptor which logs every call as `OUTBOUND_API`,
+// like `transport/http.LoggingTransport` does for HTTP. It also starts a child span of the caller's span
+// and sends the request id and the tracing information in the outgoing metadata.
+func NewLoggingUnaryInterceptor(logger blog.AccessLogger, opt LoggingOptions) grpc.UnaryClientInterceptor {
+	i := newLoggingInterceptor(logger, opt)
+
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, finish := startSpan(ctx, method)
+		defer finish()
+		ctx = outgoingContext(ctx)
+
+		fields := i.requestFields(ctx, method, cc)
+		if i.opt.DumpBody {
+			fields["request_body"] = i.marshal(req)
+		}
+
+		var header metadata.MD
+		opts = append(opts, grpc.Header(&header))
+
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		fields["latency_ms"] = time.Since(start).Milliseconds()
+
+		i.responseFields(fields, header, err)
+		if i.opt.DumpBody && err == nil {
+			fields["response_body"] = i.marshal(reply)
+		}
+
+		i.log(ctx, fields, err)
+
+		return err
+	}
+}
+
+// NewLoggingStreamInterceptor returns the stream version of `NewLoggingUnaryInterceptor`.
+// A stream is logged once, when it ends; the messages are counted instead of dumped.
+func NewLoggingStreamInterceptor(logger blog.AccessLogger, opt LoggingOptions) grpc.StreamClientInterceptor {
+	i := newLoggingInterceptor(logger, opt)
+
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, finish := startSpan(ctx, method)
+		ctx = outgoingContext(ctx)
+
+		fields := i.requestFields(ctx, method, cc)
+		start := time.Now()
+
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			fields["latency_ms"] = time.Since(start).Milliseconds()
+			i.responseFields(fields, nil, err)
+			i.log(ctx, fields, err)
+			finish()
+			return nil, err
+		}
+
+		s := &loggingStream{
+			ClientStream:  cs,
+			serverStreams: desc.ServerStreams,
+			done: func(s *loggingStream, err error) {
+				fields["latency_ms"] = time.Since(start).Milliseconds()
+				fields["messages_out"] = s.sent
+				fields["messages_in"] = s.received
+
+				header, _ := s.ClientStream.Header()
+				i.responseFields(fields, header, err)
+				i.log(ctx, fields, err)
+				finish()
+			},
+		}
+
+		// The context of the stream is done when the call ends, even if the caller doesn't read it to the end.
+		// Once the stream is logged, it's a no-op.
+		context.AfterFunc(cs.Context(), func() {
+			s.finish(status.FromContextError(cs.Context().Err()).Err())
+		})
+
+		return s, nil
+	}
+}
+
+func (i *loggingInterceptor) requestFields(ctx context.Context, method string, cc *grpc.ClientConn) map[string]any {
+	fields := map[string]any{
+		"protocol": "grpc",
+		"method":   method,
+	}
+
+	if cc != nil {
+		fields["target"] = cc.Target()
+	}
+
+	if i.opt.LogType != "" {
+		fields["type"] = i.opt.LogType
+	}
+
+	reqHeader := make(map[string]any)
+	if requestID, ok := bctx.GetRequestID(ctx); ok {
+		fields["id"] = requestID
+		reqHeader[echo.HeaderXRequestID] = requestID
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	for _, h := range i.opt.AllowedReqHeaders {
+		if v := md.Get(h); len(v) > 0 {
+			reqHeader[h] = v[0]
+		}
+	}
+
+	if len(reqHeader) > 0 {
+		fields["request_header"] = reqHeader
+	}
+
+	return fields
+}
+
+func (i *loggingInterceptor) responseFields(fields map[string]any, header metadata.MD, err error) {
+	code := status.Code(err)
+	fields["status"] = code.String()
+	fields["grpc_code"] = int(code)
+
+	respHeader := make(map[string]any)
+	for _, h := range i.opt.AllowedRespHeaders {
+		if v := header.Get(h); len(v) > 0 {
+			respHeader[h] = v[0]
+		}
+	}
+
+	if len(respHeader) > 0 {
+		fields["response_header"] = respHeader
+	}
+
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+}
+
+func (i *loggingInterceptor) log(ctx context.Context, fields map[string]any, err error) {
+	if err != nil {
+		i.logger.TraceError(ctx, "OUTBOUND_API", fields)
+		return
+	}
+
+	i.logger.TraceInfo(ctx, "OUTBOUND_API", fields)
+}
+
+// marshal encodes a message as protojson, cut to `MaxBodySize` bytes followed by a truncation marker.
+func (i *loggingInterceptor) marshal(m any) string {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return ""
+	}
+
+	b, err := protojson.Marshal(msg)
+	if err != nil {
+		return ""
+	}
+
+	if int64(len(b)) > i.opt.MaxBodySize {
+		return fmt.Sprintf("%s…(truncated %d bytes)", b[:i.opt.MaxBodySize], int64(len(b))-i.opt.MaxBodySize)
+	}
+
+	return string(b)
+}
+
+func startSpan(ctx context.Context, method string) (context.Context, func()) {
+	tracer := trace.CurrentTracer()
+	if !tracer.Enabled() {
+		return ctx, func() {}
+	}
+
+	return tracer.Start(ctx, "grpc.client", trace.SpanOptions{Description: strings.TrimPrefix(method, "/")})
+}
+
+// outgoingContext adds the request id and the tracing information to the outgoing metadata.
+func outgoingContext(ctx context.Context) context.Context {
+	if requestID, ok := bctx.GetRequestID(ctx); ok {
+		if md, _ := metadata.FromOutgoingContext(ctx); len(md.Get(requestIDMetadataKey)) == 0 {
+			ctx = metadata.AppendToOutgoingContext(ctx, requestIDMetadataKey, requestID)
+		}
+	}
+
+	return trace.PropagateToGRPC(ctx)
+}
+
+// loggingStream reports the end of a client stream: an error (including `io.EOF`) from `RecvMsg`,
+// the single response of a client streaming call, a failed `SendMsg`, or its context being done, e.g.
+// when the caller cancels a stream it doesn't read to the end.
+type loggingStream struct {
+	grpc.ClientStream
+	serverStreams bool
+	once          sync.Once
+	mu            sync.Mutex
+	sent          int
+	received      int
+	done          func(s *loggingStream, err error)
+}
+
+func (s *loggingStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	if err != nil {
+		// On `io.EOF` the real status is returned by `RecvMsg`, which the caller is expected to call.
+		if !errors.Is(err, io.EOF) {
+			s.finish(err)
+		}
+		return err
+	}
+
+	s.mu.Lock()
+	s.sent++
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *loggingStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			s.finish(nil)
+		} else {
+			s.finish(err)
+		}
+		return err
+	}
+
+	s.mu.Lock()
+	s.received++
+	s.mu.Unlock()
+
+	if !s.serverStreams {
+		s.finish(nil)
+	}
+
+	return nil
+}
+
+func (s *loggingStream) finish(err error) {
+	s.once.Do(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.done(s, err)
+	})
+}
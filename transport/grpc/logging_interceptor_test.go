@@ -0,0 +1,142 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/retail-ai-inc/bean/v2/config"
+	bctx "github.com/retail-ai-inc/bean/v2/context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+type recordLogger struct {
+	mu     sync.Mutex
+	infos  []map[string]any
+	errors []map[string]any
+}
+
+func (l *recordLogger) TraceInfo(_ context.Context, _ string, fields map[string]any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.infos = append(l.infos, fields)
+}
+
+func (l *recordLogger) TraceError(_ context.Context, _ string, fields map[string]any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errors = append(l.errors, fields)
+}
+
+func TestLoggingInterceptors(t *testing.T) {
+	config.Bean = &config.Config{}
+
+	var gotRequestID []string
+	lis := bufconn.Listen(1 << 20)
+	hs := health.NewServer()
+	hs.SetServingStatus("known", healthpb.HealthCheckResponse_SERVING)
+	s := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		gotRequestID = md.Get("x-request-id")
+		_ = grpc.SetHeader(ctx, metadata.Pairs("x-served-by", "test"))
+		return handler(ctx, req)
+	}))
+	healthpb.RegisterHealthServer(s, hs)
+	go func() { _ = s.Serve(lis) }()
+	defer s.Stop()
+
+	logger := &recordLogger{}
+	opt := LoggingOptions{
+		DumpBody:           true,
+		LogType:            "health",
+		AllowedReqHeaders:  []string{"x-tenant-id"},
+		AllowedRespHeaders: []string{"x-served-by"},
+	}
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(NewLoggingUnaryInterceptor(logger, opt)),
+		grpc.WithStreamInterceptor(NewLoggingStreamInterceptor(logger, opt)),
+	)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	client := healthpb.NewHealthClient(conn)
+
+	ctx := bctx.SetRequestID(context.Background(), "req-1")
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-tenant-id", "42")
+
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{Service: "known"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"req-1"}, gotRequestID)
+
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{Service: "unknown"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// Abandoned: canceled without reading it to the end.
+	abandonCtx, abandon := context.WithCancel(ctx)
+	abandoned, err := client.Watch(abandonCtx, &healthpb.HealthCheckRequest{Service: "known"})
+	require.NoError(t, err)
+	_, err = abandoned.Recv()
+	require.NoError(t, err)
+	abandon()
+	assert.Eventually(t, func() bool {
+		logger.mu.Lock()
+		defer logger.mu.Unlock()
+		return len(logger.errors) == 2
+	}, time.Second, 5*time.Millisecond)
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	stream, err := client.Watch(watchCtx, &healthpb.HealthCheckRequest{Service: "known"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+	cancel()
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Canceled, status.Code(err))
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+
+	require.Len(t, logger.infos, 1)
+	ok := logger.infos[0]
+	assert.Equal(t, "/grpc.health.v1.Health/Check", ok["method"])
+	assert.Equal(t, "OK", ok["status"])
+	assert.Equal(t, "health", ok["type"])
+	assert.Equal(t, "req-1", ok["id"])
+	assert.Equal(t, map[string]any{"X-Request-Id": "req-1", "x-tenant-id": "42"}, ok["request_header"])
+	assert.Equal(t, map[string]any{"x-served-by": "test"}, ok["response_header"])
+	assert.Equal(t, `{"service":"known"}`, ok["request_body"])
+	assert.Equal(t, `{"status":"SERVING"}`, ok["response_body"])
+
+	require.Len(t, logger.errors, 3)
+	assert.Equal(t, "NotFound", logger.errors[0]["status"])
+	for _, watch := range logger.errors[1:] {
+		assert.Equal(t, "/grpc.health.v1.Health/Watch", watch["method"])
+		assert.Equal(t, "Canceled", watch["status"])
+		assert.Equal(t, 1, watch["messages_in"])
+	}
+}
+
+func TestLoggingInterceptor_Marshal(t *testing.T) {
+	i := newLoggingInterceptor(&recordLogger{}, LoggingOptions{MaxBodySize: 10})
+
+	msg := &healthpb.HealthCheckRequest{Service: "a-long-service-name"}
+	b, err := protojson.Marshal(msg)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%s…(truncated %d bytes)", b[:10], len(b)-10), i.marshal(msg))
+
+	assert.Equal(t, `{}`, i.marshal(&healthpb.HealthCheckRequest{}))
+}
@@ -0,0 +1,9 @@
+package grpc
+
+type LoggingOptions struct {
+	DumpBody           bool
+	MaxBodySize        int64
+	LogType            string
+	AllowedReqHeaders  []string // Outgoing metadata keys to log.
+	AllowedRespHeaders []string // Header metadata keys of the response to log.
+}